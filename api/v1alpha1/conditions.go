@@ -14,6 +14,14 @@ const (
 	// ReconciliationSucceededReason represents the fact that
 	// the reconciliation succeeded.
 	ReconciliationSucceededReason string = "ReconciliationSucceeded"
+
+	// DeletionsBlockedReason represents the fact that the deletion of
+	// generated resources was blocked because it exceeded the MaxDeletions.
+	DeletionsBlockedReason string = "DeletionsBlocked"
+
+	// DryRunReason represents the fact that the GitOpsSet was reconciled in
+	// dry-run mode.
+	DryRunReason string = "DryRun"
 )
 
 // SetGitOpsSetReadiness sets the ready condition with the given status, reason and message.
@@ -40,3 +48,25 @@ func SetGitOpsSetReadiness(set *GitOpsSet, inventory *ResourceInventory, status
 func GetGitOpsSetReadiness(set *GitOpsSet) metav1.ConditionStatus {
 	return apimeta.FindStatusCondition(set.Status.Conditions, meta.ReadyCondition).Status
 }
+
+// SetGitOpsSetHealthiness sets the healthy condition with the given status, reason and message.
+func SetGitOpsSetHealthiness(set *GitOpsSet, status metav1.ConditionStatus, reason, message string) {
+	newCondition := metav1.Condition{
+		Type:    meta.HealthyCondition,
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	}
+	apimeta.SetStatusCondition(&set.Status.Conditions, newCondition)
+}
+
+// SetGitOpsSetStalled sets the stalled condition with the given reason and message.
+func SetGitOpsSetStalled(set *GitOpsSet, reason, message string) {
+	newCondition := metav1.Condition{
+		Type:    meta.StalledCondition,
+		Status:  metav1.ConditionTrue,
+		Reason:  reason,
+		Message: message,
+	}
+	apimeta.SetStatusCondition(&set.Status.Conditions, newCondition)
+}
@@ -1,16 +1,53 @@
 package v1alpha1
 
 import (
+	"encoding/json"
+	"fmt"
+
 	"github.com/fluxcd/pkg/apis/meta"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // GitOpsSetFinalizer is the finalizer added to GitOpsSets to allow us to clean
 // up resources.
 const GitOpsSetFinalizer = "finalizers.sets.gitops.pro"
 
+// PruneAnnotation can be set on generated resources to prevent them from
+// being deleted when they are removed from the GitOpsSet, or the GitOpsSet is
+// deleted.
+const PruneAnnotation = "sets.gitops.pro/prune"
+
+// PruneDisabledValue is the value of the PruneAnnotation that disables pruning.
+const PruneDisabledValue = "disabled"
+
+// ApproveDeletionsAnnotation can be set on a GitOpsSet to approve deletions
+// that exceed the MaxDeletions, each new value approves a single
+// reconciliation.
+const ApproveDeletionsAnnotation = "sets.gitops.pro/approve-deletions"
+
+const (
+	// DeletionPolicyDelete deletes the generated resources when the GitOpsSet
+	// is deleted.
+	DeletionPolicyDelete = "Delete"
+
+	// DeletionPolicyOrphan leaves the generated resources in place when the
+	// GitOpsSet is deleted.
+	DeletionPolicyOrphan = "Orphan"
+)
+
+const (
+	// ErrorPolicyFailFast stops reconciling the GitOpsSet when any generator
+	// or template fails.
+	ErrorPolicyFailFast = "failFast"
+
+	// ErrorPolicyContinue applies the resources for the elements that are
+	// generated and rendered, when other generators or templates fail.
+	ErrorPolicyContinue = "continue"
+)
+
 // LocalObjectReference contains enough information to locate the referenced Kubernetes resource object.
 type LocalObjectReference struct {
 	// Name of the referent.
@@ -24,8 +61,75 @@ type GitOpsSetTemplate struct {
 	// repeated for each of the matching elements in the JSONPath expression.
 	// https://kubernetes.io/docs/reference/kubectl/jsonpath/
 	Repeat string `json:"repeat,omitempty"`
+
+	// When is a CEL expression evaluated against each element, the template
+	// is only rendered for the elements where the expression evaluates to
+	// true e.g. `element.public == true`.
+	// +optional
+	When string `json:"when,omitempty"`
+
 	// Content is the YAML to be templated and generated.
-	Content runtime.RawExtension `json:"content"`
+	//
+	// Either Content or TemplateRef must be provided.
+	// +optional
+	Content runtime.RawExtension `json:"content,omitempty"`
+
+	// TemplateRef references YAML to be templated and generated that is
+	// stored in a ConfigMap, or in a file in a GitRepository or
+	// OCIRepository.
+	//
+	// If the referenced YAML contains multiple documents, each document is
+	// a separate template.
+	// +optional
+	TemplateRef *TemplateRef `json:"templateRef,omitempty"`
+}
+
+// TemplatePartial is a named template that can be included in the templates.
+type TemplatePartial struct {
+	// Name is the name used to include the partial.
+	Name string `json:"name"`
+
+	// Template is the template text of the partial, rendered with the
+	// parameters it's included with.
+	Template string `json:"template"`
+}
+
+// ValuesReference references values stored in a ConfigMap or Secret.
+type ValuesReference struct {
+	// Kind of the referenced resource.
+	// +kubebuilder:validation:Enum=ConfigMap;Secret
+	Kind string `json:"kind"`
+
+	// Name of the referenced resource in the same namespace as the
+	// GitOpsSet.
+	Name string `json:"name"`
+
+	// Key is the key in the referenced resource that contains the values as
+	// YAML.
+	//
+	// If this is not set, each key in the data is a value.
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+// TemplateRef references a template stored outside of the GitOpsSet.
+type TemplateRef struct {
+	// Kind of the referenced resource.
+	// +kubebuilder:validation:Enum=ConfigMap;GitRepository;OCIRepository
+	Kind string `json:"kind"`
+
+	// Name of the referenced resource in the same namespace as the
+	// GitOpsSet.
+	Name string `json:"name"`
+
+	// Key is the key in the ConfigMap data that contains the template.
+	// +optional
+	Key string `json:"key,omitempty"`
+
+	// Path is the path to the file in the repository artifact that contains
+	// the template.
+	// +optional
+	Path string `json:"path,omitempty"`
 }
 
 // ClusterGenerator defines a generator that queries the cluster API for
@@ -38,6 +142,46 @@ type ClusterGenerator struct {
 	Selector metav1.LabelSelector `json:"selector,omitempty"`
 }
 
+// KubernetesResourcesGenerator defines a generator that queries the cluster
+// for arbitrary Kubernetes resources.
+type KubernetesResourcesGenerator struct {
+	// APIVersion of the resources to query e.g. v1 or
+	// kustomize.toolkit.fluxcd.io/v1.
+	// +required
+	APIVersion string `json:"apiVersion"`
+
+	// Kind of the resources to query e.g. Namespace.
+	// +required
+	Kind string `json:"kind"`
+
+	// Selector is used to filter the resources by their labels.
+	//
+	// If no selector is provided, all resources of the Kind will be matched.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// FieldSelector is used to filter the resources by their fields e.g.
+	// metadata.name=my-resource.
+	// +optional
+	FieldSelector string `json:"fieldSelector,omitempty"`
+
+	// AllNamespaces queries the resources in all namespaces rather than the
+	// namespace of the GitOpsSet.
+	//
+	// This, and querying cluster-scoped resources, must be enabled in the
+	// controller.
+	// +optional
+	AllNamespaces bool `json:"allNamespaces,omitempty"`
+
+	// JSONPath is a string that is used to project each resource before it's
+	// generated as an element.
+	//
+	// The expression must result in a single object for each resource.
+	// https://kubernetes.io/docs/reference/kubectl/jsonpath/
+	// +optional
+	JSONPath string `json:"jsonPath,omitempty"`
+}
+
 // ConfigGenerator loads a referenced ConfigMap or
 // Secret from the Cluster and makes it available as a resource.
 type ConfigGenerator struct {
@@ -90,6 +234,126 @@ type PullRequestGenerator struct {
 	// or to include forks if  true
 	// +optional
 	Forks bool `json:"forks,omitempty"`
+
+	// TargetBranches is used to filter the PRs by the branch they target, only
+	// PRs targeting one of the branches are included.
+	// +optional
+	TargetBranches []string `json:"targetBranches,omitempty"`
+
+	// TitlePattern is a regular expression that the titles of the PRs must
+	// match e.g. `^\[preview\]`.
+	// +optional
+	TitlePattern string `json:"titlePattern,omitempty"`
+
+	// Authors is used to filter the PRs by the login of the author, only PRs
+	// opened by one of the authors are included.
+	// +optional
+	Authors []string `json:"authors,omitempty"`
+
+	// Draft is used to filter the PRs by their draft state, if true only draft
+	// PRs are included, and if false draft PRs are excluded.
+	// +optional
+	Draft *bool `json:"draft,omitempty"`
+
+	// MaxPullRequests is the maximum number of open PRs to query, if the
+	// repository has more open PRs the generator fails rather than generating
+	// from a partial list. Defaults to 500.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxPullRequests int `json:"maxPullRequests,omitempty"`
+
+	// UpdatedTimestamps adds the time that each PR was last updated to the
+	// generated elements, this changes whenever the PR is updated.
+	// +optional
+	UpdatedTimestamps bool `json:"updatedTimestamps,omitempty"`
+}
+
+// GitRefsGenerator generates from the branches or tags in a repository.
+type GitRefsGenerator struct {
+	// The interval at which to check for repository updates.
+	// +required
+	Interval metav1.Duration `json:"interval"`
+
+	// Determines which git-api protocol to use.
+	// +kubebuilder:validation:Enum=github;gitlab;bitbucketserver
+	Driver string `json:"driver"`
+	// This is the API endpoint to use.
+	// +kubebuilder:validation:Pattern="^https://"
+	// +optional
+	ServerURL string `json:"serverURL,omitempty"`
+	// This should be the Repo you want to query.
+	// e.g. my-org/my-repo
+	// +required
+	Repo string `json:"repo"`
+
+	// Reference to Secret in same namespace with a field "password" which is an
+	// auth token that can query the Git Provider API.
+	SecretRef *LocalObjectReference `json:"secretRef,omitempty"`
+
+	// Type is the kind of refs to generate from, either branches or tags.
+	// +kubebuilder:validation:Enum=branch;tag
+	// +kubebuilder:default=branch
+	// +optional
+	Type string `json:"type,omitempty"`
+
+	// Pattern is a regular expression that the names of the refs must match
+	// e.g. `^release-.*`.
+	// +optional
+	Pattern string `json:"pattern,omitempty"`
+
+	// SemVer is a semantic version constraint that the names of the refs must
+	// satisfy e.g. `>= 1.2.0`, a "v" prefix on the ref names is ignored.
+	// Refs that aren't semantic versions are excluded.
+	// +optional
+	SemVer string `json:"semver,omitempty"`
+
+	// CommitTimestamps adds the time of the commit that each ref points at
+	// to the generated elements, this queries the commit for each ref.
+	// +optional
+	CommitTimestamps bool `json:"commitTimestamps,omitempty"`
+}
+
+// SCMRepositoriesGenerator generates from the repositories in an organization
+// or group.
+type SCMRepositoriesGenerator struct {
+	// The interval at which to check for repository updates.
+	// +required
+	Interval metav1.Duration `json:"interval"`
+
+	// Determines which git-api protocol to use.
+	// +kubebuilder:validation:Enum=github;gitlab;bitbucketserver
+	Driver string `json:"driver"`
+	// This is the API endpoint to use.
+	// +kubebuilder:validation:Pattern="^https://"
+	// +optional
+	ServerURL string `json:"serverURL,omitempty"`
+	// This should be the organization, group or project you want to query.
+	// e.g. my-org
+	// +required
+	Organization string `json:"organization"`
+
+	// Reference to Secret in same namespace with a field "password" which is an
+	// auth token that can query the Git Provider API.
+	SecretRef *LocalObjectReference `json:"secretRef,omitempty"`
+
+	// Topics is used to filter the repositories, only repositories with all
+	// the topics are included.
+	// +optional
+	Topics []string `json:"topics,omitempty"`
+
+	// Pattern is a regular expression that the names of the repositories must
+	// match e.g. `^service-.*`.
+	// +optional
+	Pattern string `json:"pattern,omitempty"`
+
+	// Archived is used to filter the repositories by their archived state,
+	// archived repositories are excluded by default, `include` includes them
+	// with the other repositories, and `only` includes only the archived
+	// repositories.
+	// +kubebuilder:validation:Enum=exclude;include;only
+	// +kubebuilder:default=exclude
+	// +optional
+	Archived string `json:"archived,omitempty"`
 }
 
 // APIClientGenerator defines a generator that queries an API endpoint and uses
@@ -195,6 +459,19 @@ type OCIRepositoryGenerator struct {
 	Directories []RepositoryGeneratorDirectoryItem `json:"directories,omitempty"`
 }
 
+// BucketGenerator generates from files in a Flux Bucket resource.
+type BucketGenerator struct {
+	// BucketRef is the name of a Bucket resource to be generated from.
+	BucketRef string `json:"bucketRef,omitempty"`
+
+	// Files is a set of rules for identifying files to be parsed.
+	Files []RepositoryGeneratorFileItem `json:"files,omitempty"`
+
+	// Directories is a set of rules for identifying directories to be
+	// generated.
+	Directories []RepositoryGeneratorDirectoryItem `json:"directories,omitempty"`
+}
+
 // MatrixGenerator defines a matrix that combines generators.
 // The matrix is a cartesian product of the generators.
 type MatrixGenerator struct {
@@ -212,8 +489,53 @@ type MatrixGenerator struct {
 	SingleElement bool `json:"singleElement,omitempty"`
 }
 
-// GitOpsSetNestedGenerator describes the generators usable by the MatrixGenerator.
-// This is a subset of the generators allowed by the GitOpsSetGenerator because the CRD format doesn't support recursive declarations.
+// MergeGenerator merges the elements of generators that have the same values
+// for the merge keys.
+//
+// The elements generated by the first generator are the base, elements from
+// the following generators that match a base element by the merge keys
+// override the values in the base element, elements that don't match a base
+// element are dropped.
+type MergeGenerator struct {
+	// MergeKeys are the names of the fields used to match elements.
+	// +kubebuilder:validation:MinItems=1
+	MergeKeys []string `json:"mergeKeys"`
+
+	// Generators is a list of generators to be merged.
+	Generators []GitOpsSetNestedGenerator `json:"generators,omitempty"`
+}
+
+// NestedMatrixGenerator is a MatrixGenerator that is nested in a
+// MatrixGenerator or MergeGenerator.
+type NestedMatrixGenerator struct {
+	// Generators is a list of generators to be combined.
+	//
+	// These are parsed by the controller as GitOpsSetNestedGenerators because
+	// the CRD format doesn't support recursive declarations.
+	Generators []apiextensionsv1.JSON `json:"generators,omitempty"`
+
+	// SingleElement means generate a single element with the result of the
+	// merged generator elements.
+	// +optional
+	SingleElement bool `json:"singleElement,omitempty"`
+}
+
+// NestedMergeGenerator is a MergeGenerator that is nested in a
+// MatrixGenerator or MergeGenerator.
+type NestedMergeGenerator struct {
+	// MergeKeys are the names of the fields used to match elements.
+	// +kubebuilder:validation:MinItems=1
+	MergeKeys []string `json:"mergeKeys"`
+
+	// Generators is a list of generators to be merged.
+	//
+	// These are parsed by the controller as GitOpsSetNestedGenerators because
+	// the CRD format doesn't support recursive declarations.
+	Generators []apiextensionsv1.JSON `json:"generators,omitempty"`
+}
+
+// GitOpsSetNestedGenerator describes the generators usable by the MatrixGenerator and MergeGenerator.
+// The Matrix and Merge generators can be nested, up to 3 levels deep including the top-level generator.
 type GitOpsSetNestedGenerator struct {
 	// Name is an optional field that will be used to prefix the values generated
 	// by the nested generators, this allows multiple generators of the same
@@ -221,14 +543,97 @@ type GitOpsSetNestedGenerator struct {
 	// +optional
 	Name string `json:"name,omitempty"`
 
-	List          *ListGenerator          `json:"list,omitempty"`
-	GitRepository *GitRepositoryGenerator `json:"gitRepository,omitempty"`
-	OCIRepository *OCIRepositoryGenerator `json:"ociRepository,omitempty"`
-	PullRequests  *PullRequestGenerator   `json:"pullRequests,omitempty"`
-	Cluster       *ClusterGenerator       `json:"cluster,omitempty"`
-	APIClient     *APIClientGenerator     `json:"apiClient,omitempty"`
-	ImagePolicy   *ImagePolicyGenerator   `json:"imagePolicy,omitempty"`
-	Config        *ConfigGenerator        `json:"config,omitempty"`
+	List                *ListGenerator                `json:"list,omitempty"`
+	GitRepository       *GitRepositoryGenerator       `json:"gitRepository,omitempty"`
+	OCIRepository       *OCIRepositoryGenerator       `json:"ociRepository,omitempty"`
+	Bucket              *BucketGenerator              `json:"bucket,omitempty"`
+	PullRequests        *PullRequestGenerator         `json:"pullRequests,omitempty"`
+	GitRefs             *GitRefsGenerator             `json:"gitRefs,omitempty"`
+	SCMRepositories     *SCMRepositoriesGenerator     `json:"scmRepositories,omitempty"`
+	Cluster             *ClusterGenerator             `json:"cluster,omitempty"`
+	APIClient           *APIClientGenerator           `json:"apiClient,omitempty"`
+	ImagePolicy         *ImagePolicyGenerator         `json:"imagePolicy,omitempty"`
+	Config              *ConfigGenerator              `json:"config,omitempty"`
+	KubernetesResources *KubernetesResourcesGenerator `json:"kubernetesResources,omitempty"`
+	GitOpsSet           *GitOpsSetRefGenerator        `json:"gitOpsSet,omitempty"`
+	Matrix              *NestedMatrixGenerator        `json:"matrix,omitempty"`
+	Merge               *NestedMergeGenerator         `json:"merge,omitempty"`
+
+	// Filter is a CEL expression evaluated against each generated element,
+	// only the elements where the expression evaluates to true are kept
+	// e.g. `element.Fork == false && element.Branch.startsWith("feature/")`.
+	// +optional
+	Filter string `json:"filter,omitempty"`
+
+	// PostProcess is a list of steps applied in order to the generated
+	// elements, after they are filtered.
+	// +optional
+	PostProcess []PostProcessStep `json:"postProcess,omitempty"`
+}
+
+// PostProcessStep is a single step in processing the generated elements,
+// exactly one of the fields must be set.
+type PostProcessStep struct {
+	// SortBy is a CEL expression evaluated against each element, the elements
+	// are sorted by the result e.g. `element.Number`.
+	// +optional
+	SortBy string `json:"sortBy,omitempty"`
+
+	// DedupeBy is a CEL expression evaluated against each element, only the
+	// first element for each result is kept e.g. `element.Branch`.
+	// +optional
+	DedupeBy string `json:"dedupeBy,omitempty"`
+
+	// Limit is the maximum number of elements to keep.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Limit *int `json:"limit,omitempty"`
+
+	// Transform adds computed fields to each element.
+	// +optional
+	Transform []ElementTransform `json:"transform,omitempty"`
+}
+
+// ElementTransform computes a field for each element from either a CEL
+// expression or a template.
+type ElementTransform struct {
+	// Field is the name of the field to set on the element.
+	Field string `json:"field"`
+
+	// Expression is a CEL expression evaluated against the element.
+	// +optional
+	Expression string `json:"expression,omitempty"`
+
+	// Template is a Go template rendered with the element as `.Element`.
+	// +optional
+	Template string `json:"template,omitempty"`
+}
+
+// MaxNestingDepth is the maximum number of levels of Matrix and Merge
+// generators, including the top-level generator.
+const MaxNestingDepth = 3
+
+// NestedGenerators parses the generators nested in the Matrix and Merge
+// generators.
+func (in GitOpsSetNestedGenerator) NestedGenerators() ([]GitOpsSetNestedGenerator, error) {
+	var raw []apiextensionsv1.JSON
+	if in.Matrix != nil {
+		raw = append(raw, in.Matrix.Generators...)
+	}
+	if in.Merge != nil {
+		raw = append(raw, in.Merge.Generators...)
+	}
+
+	nested := []GitOpsSetNestedGenerator{}
+	for _, r := range raw {
+		var generator GitOpsSetNestedGenerator
+		if err := json.Unmarshal(r.Raw, &generator); err != nil {
+			return nil, fmt.Errorf("failed to parse nested generator: %w", err)
+		}
+		nested = append(nested, generator)
+	}
+
+	return nested, nil
 }
 
 // ImagePolicyGenerator generates from the ImagePolicy.
@@ -237,17 +642,42 @@ type ImagePolicyGenerator struct {
 	PolicyRef string `json:"policyRef,omitempty"`
 }
 
+// GitOpsSetRefGenerator generates from the elements published by another
+// GitOpsSet.
+type GitOpsSetRefGenerator struct {
+	// GitOpsSetRef is the name of a GitOpsSet in the same namespace that
+	// publishes its elements.
+	GitOpsSetRef string `json:"gitOpsSetRef"`
+}
+
 // GitOpsSetGenerator is the top-level set of generators for this GitOpsSet.
 type GitOpsSetGenerator struct {
-	List          *ListGenerator          `json:"list,omitempty"`
-	PullRequests  *PullRequestGenerator   `json:"pullRequests,omitempty"`
-	GitRepository *GitRepositoryGenerator `json:"gitRepository,omitempty"`
-	OCIRepository *OCIRepositoryGenerator `json:"ociRepository,omitempty"`
-	Matrix        *MatrixGenerator        `json:"matrix,omitempty"`
-	Cluster       *ClusterGenerator       `json:"cluster,omitempty"`
-	APIClient     *APIClientGenerator     `json:"apiClient,omitempty"`
-	ImagePolicy   *ImagePolicyGenerator   `json:"imagePolicy,omitempty"`
-	Config        *ConfigGenerator        `json:"config,omitempty"`
+	List                *ListGenerator                `json:"list,omitempty"`
+	PullRequests        *PullRequestGenerator         `json:"pullRequests,omitempty"`
+	GitRefs             *GitRefsGenerator             `json:"gitRefs,omitempty"`
+	SCMRepositories     *SCMRepositoriesGenerator     `json:"scmRepositories,omitempty"`
+	GitRepository       *GitRepositoryGenerator       `json:"gitRepository,omitempty"`
+	OCIRepository       *OCIRepositoryGenerator       `json:"ociRepository,omitempty"`
+	Bucket              *BucketGenerator              `json:"bucket,omitempty"`
+	Matrix              *MatrixGenerator              `json:"matrix,omitempty"`
+	Merge               *MergeGenerator               `json:"merge,omitempty"`
+	Cluster             *ClusterGenerator             `json:"cluster,omitempty"`
+	APIClient           *APIClientGenerator           `json:"apiClient,omitempty"`
+	ImagePolicy         *ImagePolicyGenerator         `json:"imagePolicy,omitempty"`
+	Config              *ConfigGenerator              `json:"config,omitempty"`
+	KubernetesResources *KubernetesResourcesGenerator `json:"kubernetesResources,omitempty"`
+	GitOpsSet           *GitOpsSetRefGenerator        `json:"gitOpsSet,omitempty"`
+
+	// Filter is a CEL expression evaluated against each generated element,
+	// only the elements where the expression evaluates to true are kept
+	// e.g. `element.Fork == false && element.Branch.startsWith("feature/")`.
+	// +optional
+	Filter string `json:"filter,omitempty"`
+
+	// PostProcess is a list of steps applied in order to the generated
+	// elements, after they are filtered.
+	// +optional
+	PostProcess []PostProcessStep `json:"postProcess,omitempty"`
 }
 
 // GitOpsSetSpec defines the desired state of GitOpsSet
@@ -264,10 +694,171 @@ type GitOpsSetSpec struct {
 	// from the data supplied by the generators.
 	Templates []GitOpsSetTemplate `json:"templates,omitempty"`
 
+	// Partials are named templates that can be included in any of the
+	// templates with `{{ include "name" . }}`.
+	// +optional
+	Partials []TemplatePartial `json:"partials,omitempty"`
+
+	// Values are made available to all the templates as `.Values`.
+	//
+	// The values take precedence over the values loaded with ValuesFrom.
+	// +optional
+	Values *apiextensionsv1.JSON `json:"values,omitempty"`
+
+	// ValuesFrom loads values from ConfigMaps and Secrets in the same
+	// namespace, these are merged in order, with later values taking
+	// precedence.
+	// +optional
+	ValuesFrom []ValuesReference `json:"valuesFrom,omitempty"`
+
 	// The name of the Kubernetes service account to impersonate
 	// when reconciling this Kustomization.
 	// +optional
 	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	// Prune enables deleting generated resources that are no longer generated.
+	//
+	// Defaults to true.
+	// +optional
+	Prune *bool `json:"prune,omitempty"`
+
+	// DeletionPolicy controls what happens to the generated resources when
+	// the GitOpsSet is deleted.
+	//
+	// Defaults to Delete.
+	// +kubebuilder:validation:Enum=Delete;Orphan
+	// +optional
+	DeletionPolicy string `json:"deletionPolicy,omitempty"`
+
+	// DryRun renders the templates and compares the resources with the
+	// cluster without creating, updating or deleting any resources.
+	//
+	// The changes that would be made are recorded in the status.
+	// +optional
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// Adoption configures taking ownership of existing resources that are not
+	// in the inventory.
+	// +optional
+	Adoption *Adoption `json:"adoption,omitempty"`
+
+	// MaxDeletions is the maximum number of generated resources that can be
+	// deleted in a single reconciliation, either as a number, or a percentage
+	// of the generated resources e.g. "50%".
+	//
+	// If more resources would be deleted, the deletions are blocked until they
+	// are approved with the sets.gitops.pro/approve-deletions annotation.
+	// +kubebuilder:validation:XIntOrString
+	// +optional
+	MaxDeletions *intstr.IntOrString `json:"maxDeletions,omitempty"`
+
+	// Interval is the interval at which the generated resources are
+	// reconciled, correcting any drift from the templates.
+	//
+	// If this is not set, the resources are only reconciled when the GitOpsSet
+	// or the sources of its generators change.
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	// ServerSideApply configures applying the generated resources with
+	// server-side apply.
+	// +optional
+	ServerSideApply *ServerSideApply `json:"serverSideApply,omitempty"`
+
+	// Wait enables assessing the health of the generated resources, the
+	// result is reported in the Healthy condition.
+	// +optional
+	Wait bool `json:"wait,omitempty"`
+
+	// Timeout is the time to wait for the generated resources to become
+	// healthy before the Healthy condition is set to False.
+	//
+	// Defaults to 5m.
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// Rollout applies changes to the generated elements in waves, rather
+	// than all at once.
+	// +optional
+	Rollout *Rollout `json:"rollout,omitempty"`
+
+	// ErrorPolicy controls what happens when a generator fails, or a
+	// template fails to render for an element.
+	//
+	// With failFast, no resources are applied. With continue, the resources
+	// for the other elements are applied, and the failed elements keep their
+	// previous resources, this requires an ElementKey.
+	//
+	// Defaults to failFast.
+	// +kubebuilder:validation:Enum=failFast;continue
+	// +optional
+	ErrorPolicy string `json:"errorPolicy,omitempty"`
+
+	// ElementKey is a JSONPath expression evaluated against each generated
+	// element to identify it in the status e.g. "{ .ClusterName }", the key
+	// must be unique for each element.
+	//
+	// If this is not set, elements are identified by a hash of their fields,
+	// which changes whenever the element changes, this is only suitable for
+	// displaying the elements, and an ElementKey is required for rollouts and
+	// the continue ErrorPolicy.
+	// +optional
+	ElementKey string `json:"elementKey,omitempty"`
+
+	// PublishElements records the generated elements in the status, so that
+	// they can be used by other GitOpsSets with the GitOpsSet generator.
+	// +optional
+	PublishElements bool `json:"publishElements,omitempty"`
+}
+
+// Rollout configures the progressive rollout of changes to the generated
+// elements.
+type Rollout struct {
+	// MaxConcurrent is the number of elements updated in each wave, either as
+	// a number, or a percentage of the generated elements e.g. "25%".
+	// +kubebuilder:validation:XIntOrString
+	MaxConcurrent intstr.IntOrString `json:"maxConcurrent"`
+
+	// OrderBy is a JSONPath expression evaluated against each element to
+	// order the rollout e.g. "{ .ClusterLabels.tier }".
+	//
+	// If this is not set, the elements are rolled out in the order they are
+	// generated.
+	// +optional
+	OrderBy string `json:"orderBy,omitempty"`
+
+	// WaitForHealth waits for the resources generated for each wave to
+	// become healthy before the next wave is started.
+	// +optional
+	WaitForHealth bool `json:"waitForHealth,omitempty"`
+}
+
+// Adoption configures taking ownership of existing resources.
+type Adoption struct {
+	// Enabled adopts existing resources that match the generated resources
+	// and the Selector.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// Selector restricts adoption to existing resources with matching labels,
+	// it is required for resources to be adopted.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+// ServerSideApply configures how generated resources are applied with
+// server-side apply.
+type ServerSideApply struct {
+	// Enabled applies the generated resources with server-side apply.
+	//
+	// If this is not set, the controller default is used.
+	// +optional
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// Force takes ownership of fields that conflict with other field
+	// managers.
+	// +optional
+	Force bool `json:"force,omitempty"`
 }
 
 // GitOpsSetStatus defines the observed state of GitOpsSet
@@ -287,6 +878,127 @@ type GitOpsSetStatus struct {
 	// have been successfully applied
 	// +optional
 	Inventory *ResourceInventory `json:"inventory,omitempty"`
+
+	// Plan lists the changes that would be made to the generated resources
+	// when the GitOpsSet is in dry-run mode.
+	// +optional
+	Plan []PlannedChange `json:"plan,omitempty"`
+
+	// LastHandledDeletionApproval holds the value of the most recent
+	// sets.gitops.pro/approve-deletions annotation handled by the controller.
+	// +optional
+	LastHandledDeletionApproval string `json:"lastHandledDeletionApproval,omitempty"`
+
+	// Rollout records the progress of the most recent rollout when the
+	// GitOpsSet is configured to roll out changes in waves.
+	// +optional
+	Rollout *RolloutStatus `json:"rollout,omitempty"`
+
+	// Elements records the result of applying the resources generated for
+	// each element.
+	// +optional
+	Elements []ElementStatus `json:"elements,omitempty"`
+
+	// PublishedElements are the generated elements when the GitOpsSet
+	// publishes its elements.
+	// +optional
+	PublishedElements *PublishedElements `json:"publishedElements,omitempty"`
+}
+
+// PublishedElements are the elements generated by a GitOpsSet, for use by
+// other GitOpsSets.
+type PublishedElements struct {
+	// Elements are the generated elements.
+	// +optional
+	Elements []apiextensionsv1.JSON `json:"elements,omitempty"`
+}
+
+// ElementStatus records the result of applying the resources generated for an
+// element.
+type ElementStatus struct {
+	// Key identifies the element.
+	Key string `json:"key"`
+
+	// Ready is true if all the resources generated for the element were
+	// applied.
+	Ready bool `json:"ready"`
+
+	// Resources are the resources generated for the element.
+	// +optional
+	Resources []ResourceRef `json:"resources,omitempty"`
+
+	// Revision identifies the resources that were most recently applied for
+	// the element, it's used to skip elements that have already been rolled
+	// out.
+	// +optional
+	Revision string `json:"revision,omitempty"`
+
+	// LastError is the error from the most recent attempt to apply the
+	// resources generated for the element.
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+}
+
+// RolloutStatus records the progress of a rollout of the generated elements.
+type RolloutStatus struct {
+	// Revision identifies the elements, in rollout order, and their
+	// resources that are being rolled out.
+	Revision string `json:"revision"`
+
+	// CurrentWave is the most recent wave that was applied.
+	CurrentWave int `json:"currentWave"`
+
+	// TotalWaves is the number of waves needed to update all the elements.
+	TotalWaves int `json:"totalWaves"`
+
+	// UpdatedElements is the number of elements that have been updated.
+	UpdatedElements int `json:"updatedElements"`
+
+	// TotalElements is the number of generated elements.
+	TotalElements int `json:"totalElements"`
+}
+
+// Complete returns true if all the elements have been updated.
+func (in RolloutStatus) Complete() bool {
+	return in.UpdatedElements >= in.TotalElements
+}
+
+const (
+	// PlanActionCreate is the action for resources that would be created.
+	PlanActionCreate = "Create"
+
+	// PlanActionUpdate is the action for resources that would be updated.
+	PlanActionUpdate = "Update"
+
+	// PlanActionAdopt is the action for existing resources that are not in
+	// the inventory that would be adopted.
+	PlanActionAdopt = "Adopt"
+
+	// PlanActionConflict is the action for resources that can't be created
+	// because they already exist and would not be adopted.
+	PlanActionConflict = "Conflict"
+
+	// PlanActionPrune is the action for resources that would be deleted.
+	PlanActionPrune = "Prune"
+)
+
+// PlannedChange is a change to a generated resource that would be made if the
+// GitOpsSet was not in dry-run mode.
+type PlannedChange struct {
+	ResourceRef `json:",inline"`
+
+	// Action is the change that would be made to the resource.
+	// +kubebuilder:validation:Enum=Create;Update;Adopt;Conflict;Prune
+	Action string `json:"action"`
+
+	// Diff is a summary of the changes that would be made to an existing
+	// resource.
+	// +optional
+	Diff string `json:"diff,omitempty"`
+
+	// Message explains why a resource is in conflict.
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
 //+genclient
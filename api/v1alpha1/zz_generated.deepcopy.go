@@ -9,9 +9,10 @@ Copyright 2025.
 package v1alpha1
 
 import (
-	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -25,7 +26,7 @@ func (in *APIClientGenerator) DeepCopyInto(out *APIClientGenerator) {
 	}
 	if in.Body != nil {
 		in, out := &in.Body, &out.Body
-		*out = new(v1.JSON)
+		*out = new(apiextensionsv1.JSON)
 		(*in).DeepCopyInto(*out)
 	}
 	if in.SecretRef != nil {
@@ -45,6 +46,51 @@ func (in *APIClientGenerator) DeepCopy() *APIClientGenerator {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Adoption) DeepCopyInto(out *Adoption) {
+	*out = *in
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Adoption.
+func (in *Adoption) DeepCopy() *Adoption {
+	if in == nil {
+		return nil
+	}
+	out := new(Adoption)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BucketGenerator) DeepCopyInto(out *BucketGenerator) {
+	*out = *in
+	if in.Files != nil {
+		in, out := &in.Files, &out.Files
+		*out = make([]RepositoryGeneratorFileItem, len(*in))
+		copy(*out, *in)
+	}
+	if in.Directories != nil {
+		in, out := &in.Directories, &out.Directories
+		*out = make([]RepositoryGeneratorDirectoryItem, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BucketGenerator.
+func (in *BucketGenerator) DeepCopy() *BucketGenerator {
+	if in == nil {
+		return nil
+	}
+	out := new(BucketGenerator)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterGenerator) DeepCopyInto(out *ClusterGenerator) {
 	*out = *in
@@ -76,6 +122,41 @@ func (in *ConfigGenerator) DeepCopy() *ConfigGenerator {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ElementStatus) DeepCopyInto(out *ElementStatus) {
+	*out = *in
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]ResourceRef, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ElementStatus.
+func (in *ElementStatus) DeepCopy() *ElementStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ElementStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ElementTransform) DeepCopyInto(out *ElementTransform) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ElementTransform.
+func (in *ElementTransform) DeepCopy() *ElementTransform {
+	if in == nil {
+		return nil
+	}
+	out := new(ElementTransform)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GitOpsSet) DeepCopyInto(out *GitOpsSet) {
 	*out = *in
@@ -116,6 +197,16 @@ func (in *GitOpsSetGenerator) DeepCopyInto(out *GitOpsSetGenerator) {
 		*out = new(PullRequestGenerator)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.GitRefs != nil {
+		in, out := &in.GitRefs, &out.GitRefs
+		*out = new(GitRefsGenerator)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SCMRepositories != nil {
+		in, out := &in.SCMRepositories, &out.SCMRepositories
+		*out = new(SCMRepositoriesGenerator)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.GitRepository != nil {
 		in, out := &in.GitRepository, &out.GitRepository
 		*out = new(GitRepositoryGenerator)
@@ -126,11 +217,21 @@ func (in *GitOpsSetGenerator) DeepCopyInto(out *GitOpsSetGenerator) {
 		*out = new(OCIRepositoryGenerator)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Bucket != nil {
+		in, out := &in.Bucket, &out.Bucket
+		*out = new(BucketGenerator)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Matrix != nil {
 		in, out := &in.Matrix, &out.Matrix
 		*out = new(MatrixGenerator)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Merge != nil {
+		in, out := &in.Merge, &out.Merge
+		*out = new(MergeGenerator)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Cluster != nil {
 		in, out := &in.Cluster, &out.Cluster
 		*out = new(ClusterGenerator)
@@ -151,6 +252,23 @@ func (in *GitOpsSetGenerator) DeepCopyInto(out *GitOpsSetGenerator) {
 		*out = new(ConfigGenerator)
 		**out = **in
 	}
+	if in.KubernetesResources != nil {
+		in, out := &in.KubernetesResources, &out.KubernetesResources
+		*out = new(KubernetesResourcesGenerator)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GitOpsSet != nil {
+		in, out := &in.GitOpsSet, &out.GitOpsSet
+		*out = new(GitOpsSetRefGenerator)
+		**out = **in
+	}
+	if in.PostProcess != nil {
+		in, out := &in.PostProcess, &out.PostProcess
+		*out = make([]PostProcessStep, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitOpsSetGenerator.
@@ -213,11 +331,26 @@ func (in *GitOpsSetNestedGenerator) DeepCopyInto(out *GitOpsSetNestedGenerator)
 		*out = new(OCIRepositoryGenerator)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Bucket != nil {
+		in, out := &in.Bucket, &out.Bucket
+		*out = new(BucketGenerator)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.PullRequests != nil {
 		in, out := &in.PullRequests, &out.PullRequests
 		*out = new(PullRequestGenerator)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.GitRefs != nil {
+		in, out := &in.GitRefs, &out.GitRefs
+		*out = new(GitRefsGenerator)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SCMRepositories != nil {
+		in, out := &in.SCMRepositories, &out.SCMRepositories
+		*out = new(SCMRepositoriesGenerator)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Cluster != nil {
 		in, out := &in.Cluster, &out.Cluster
 		*out = new(ClusterGenerator)
@@ -238,6 +371,33 @@ func (in *GitOpsSetNestedGenerator) DeepCopyInto(out *GitOpsSetNestedGenerator)
 		*out = new(ConfigGenerator)
 		**out = **in
 	}
+	if in.KubernetesResources != nil {
+		in, out := &in.KubernetesResources, &out.KubernetesResources
+		*out = new(KubernetesResourcesGenerator)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GitOpsSet != nil {
+		in, out := &in.GitOpsSet, &out.GitOpsSet
+		*out = new(GitOpsSetRefGenerator)
+		**out = **in
+	}
+	if in.Matrix != nil {
+		in, out := &in.Matrix, &out.Matrix
+		*out = new(NestedMatrixGenerator)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Merge != nil {
+		in, out := &in.Merge, &out.Merge
+		*out = new(NestedMergeGenerator)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PostProcess != nil {
+		in, out := &in.PostProcess, &out.PostProcess
+		*out = make([]PostProcessStep, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitOpsSetNestedGenerator.
@@ -250,6 +410,21 @@ func (in *GitOpsSetNestedGenerator) DeepCopy() *GitOpsSetNestedGenerator {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitOpsSetRefGenerator) DeepCopyInto(out *GitOpsSetRefGenerator) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitOpsSetRefGenerator.
+func (in *GitOpsSetRefGenerator) DeepCopy() *GitOpsSetRefGenerator {
+	if in == nil {
+		return nil
+	}
+	out := new(GitOpsSetRefGenerator)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GitOpsSetSpec) DeepCopyInto(out *GitOpsSetSpec) {
 	*out = *in
@@ -267,6 +442,56 @@ func (in *GitOpsSetSpec) DeepCopyInto(out *GitOpsSetSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Partials != nil {
+		in, out := &in.Partials, &out.Partials
+		*out = make([]TemplatePartial, len(*in))
+		copy(*out, *in)
+	}
+	if in.Values != nil {
+		in, out := &in.Values, &out.Values
+		*out = new(apiextensionsv1.JSON)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ValuesFrom != nil {
+		in, out := &in.ValuesFrom, &out.ValuesFrom
+		*out = make([]ValuesReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.Prune != nil {
+		in, out := &in.Prune, &out.Prune
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Adoption != nil {
+		in, out := &in.Adoption, &out.Adoption
+		*out = new(Adoption)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MaxDeletions != nil {
+		in, out := &in.MaxDeletions, &out.MaxDeletions
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.ServerSideApply != nil {
+		in, out := &in.ServerSideApply, &out.ServerSideApply
+		*out = new(ServerSideApply)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Rollout != nil {
+		in, out := &in.Rollout, &out.Rollout
+		*out = new(Rollout)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitOpsSetSpec.
@@ -285,7 +510,7 @@ func (in *GitOpsSetStatus) DeepCopyInto(out *GitOpsSetStatus) {
 	out.ReconcileRequestStatus = in.ReconcileRequestStatus
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
-		*out = make([]metav1.Condition, len(*in))
+		*out = make([]v1.Condition, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -295,6 +520,28 @@ func (in *GitOpsSetStatus) DeepCopyInto(out *GitOpsSetStatus) {
 		*out = new(ResourceInventory)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Plan != nil {
+		in, out := &in.Plan, &out.Plan
+		*out = make([]PlannedChange, len(*in))
+		copy(*out, *in)
+	}
+	if in.Rollout != nil {
+		in, out := &in.Rollout, &out.Rollout
+		*out = new(RolloutStatus)
+		**out = **in
+	}
+	if in.Elements != nil {
+		in, out := &in.Elements, &out.Elements
+		*out = make([]ElementStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PublishedElements != nil {
+		in, out := &in.PublishedElements, &out.PublishedElements
+		*out = new(PublishedElements)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitOpsSetStatus.
@@ -311,6 +558,11 @@ func (in *GitOpsSetStatus) DeepCopy() *GitOpsSetStatus {
 func (in *GitOpsSetTemplate) DeepCopyInto(out *GitOpsSetTemplate) {
 	*out = *in
 	in.Content.DeepCopyInto(&out.Content)
+	if in.TemplateRef != nil {
+		in, out := &in.TemplateRef, &out.TemplateRef
+		*out = new(TemplateRef)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitOpsSetTemplate.
@@ -323,6 +575,27 @@ func (in *GitOpsSetTemplate) DeepCopy() *GitOpsSetTemplate {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitRefsGenerator) DeepCopyInto(out *GitRefsGenerator) {
+	*out = *in
+	out.Interval = in.Interval
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(LocalObjectReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitRefsGenerator.
+func (in *GitRefsGenerator) DeepCopy() *GitRefsGenerator {
+	if in == nil {
+		return nil
+	}
+	out := new(GitRefsGenerator)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GitRepositoryGenerator) DeepCopyInto(out *GitRepositoryGenerator) {
 	*out = *in
@@ -378,12 +651,32 @@ func (in *ImagePolicyGenerator) DeepCopy() *ImagePolicyGenerator {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubernetesResourcesGenerator) DeepCopyInto(out *KubernetesResourcesGenerator) {
+	*out = *in
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubernetesResourcesGenerator.
+func (in *KubernetesResourcesGenerator) DeepCopy() *KubernetesResourcesGenerator {
+	if in == nil {
+		return nil
+	}
+	out := new(KubernetesResourcesGenerator)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ListGenerator) DeepCopyInto(out *ListGenerator) {
 	*out = *in
 	if in.Elements != nil {
 		in, out := &in.Elements, &out.Elements
-		*out = make([]v1.JSON, len(*in))
+		*out = make([]apiextensionsv1.JSON, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -437,6 +730,82 @@ func (in *MatrixGenerator) DeepCopy() *MatrixGenerator {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MergeGenerator) DeepCopyInto(out *MergeGenerator) {
+	*out = *in
+	if in.MergeKeys != nil {
+		in, out := &in.MergeKeys, &out.MergeKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Generators != nil {
+		in, out := &in.Generators, &out.Generators
+		*out = make([]GitOpsSetNestedGenerator, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MergeGenerator.
+func (in *MergeGenerator) DeepCopy() *MergeGenerator {
+	if in == nil {
+		return nil
+	}
+	out := new(MergeGenerator)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NestedMatrixGenerator) DeepCopyInto(out *NestedMatrixGenerator) {
+	*out = *in
+	if in.Generators != nil {
+		in, out := &in.Generators, &out.Generators
+		*out = make([]apiextensionsv1.JSON, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NestedMatrixGenerator.
+func (in *NestedMatrixGenerator) DeepCopy() *NestedMatrixGenerator {
+	if in == nil {
+		return nil
+	}
+	out := new(NestedMatrixGenerator)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NestedMergeGenerator) DeepCopyInto(out *NestedMergeGenerator) {
+	*out = *in
+	if in.MergeKeys != nil {
+		in, out := &in.MergeKeys, &out.MergeKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Generators != nil {
+		in, out := &in.Generators, &out.Generators
+		*out = make([]apiextensionsv1.JSON, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NestedMergeGenerator.
+func (in *NestedMergeGenerator) DeepCopy() *NestedMergeGenerator {
+	if in == nil {
+		return nil
+	}
+	out := new(NestedMergeGenerator)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OCIRepositoryGenerator) DeepCopyInto(out *OCIRepositoryGenerator) {
 	*out = *in
@@ -462,6 +831,69 @@ func (in *OCIRepositoryGenerator) DeepCopy() *OCIRepositoryGenerator {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlannedChange) DeepCopyInto(out *PlannedChange) {
+	*out = *in
+	out.ResourceRef = in.ResourceRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlannedChange.
+func (in *PlannedChange) DeepCopy() *PlannedChange {
+	if in == nil {
+		return nil
+	}
+	out := new(PlannedChange)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostProcessStep) DeepCopyInto(out *PostProcessStep) {
+	*out = *in
+	if in.Limit != nil {
+		in, out := &in.Limit, &out.Limit
+		*out = new(int)
+		**out = **in
+	}
+	if in.Transform != nil {
+		in, out := &in.Transform, &out.Transform
+		*out = make([]ElementTransform, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostProcessStep.
+func (in *PostProcessStep) DeepCopy() *PostProcessStep {
+	if in == nil {
+		return nil
+	}
+	out := new(PostProcessStep)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PublishedElements) DeepCopyInto(out *PublishedElements) {
+	*out = *in
+	if in.Elements != nil {
+		in, out := &in.Elements, &out.Elements
+		*out = make([]apiextensionsv1.JSON, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PublishedElements.
+func (in *PublishedElements) DeepCopy() *PublishedElements {
+	if in == nil {
+		return nil
+	}
+	out := new(PublishedElements)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PullRequestGenerator) DeepCopyInto(out *PullRequestGenerator) {
 	*out = *in
@@ -476,6 +908,21 @@ func (in *PullRequestGenerator) DeepCopyInto(out *PullRequestGenerator) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.TargetBranches != nil {
+		in, out := &in.TargetBranches, &out.TargetBranches
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Authors != nil {
+		in, out := &in.Authors, &out.Authors
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Draft != nil {
+		in, out := &in.Draft, &out.Draft
+		*out = new(bool)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PullRequestGenerator.
@@ -552,3 +999,125 @@ func (in *ResourceRef) DeepCopy() *ResourceRef {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Rollout) DeepCopyInto(out *Rollout) {
+	*out = *in
+	out.MaxConcurrent = in.MaxConcurrent
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Rollout.
+func (in *Rollout) DeepCopy() *Rollout {
+	if in == nil {
+		return nil
+	}
+	out := new(Rollout)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutStatus) DeepCopyInto(out *RolloutStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutStatus.
+func (in *RolloutStatus) DeepCopy() *RolloutStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SCMRepositoriesGenerator) DeepCopyInto(out *SCMRepositoriesGenerator) {
+	*out = *in
+	out.Interval = in.Interval
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(LocalObjectReference)
+		**out = **in
+	}
+	if in.Topics != nil {
+		in, out := &in.Topics, &out.Topics
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SCMRepositoriesGenerator.
+func (in *SCMRepositoriesGenerator) DeepCopy() *SCMRepositoriesGenerator {
+	if in == nil {
+		return nil
+	}
+	out := new(SCMRepositoriesGenerator)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServerSideApply) DeepCopyInto(out *ServerSideApply) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServerSideApply.
+func (in *ServerSideApply) DeepCopy() *ServerSideApply {
+	if in == nil {
+		return nil
+	}
+	out := new(ServerSideApply)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TemplatePartial) DeepCopyInto(out *TemplatePartial) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TemplatePartial.
+func (in *TemplatePartial) DeepCopy() *TemplatePartial {
+	if in == nil {
+		return nil
+	}
+	out := new(TemplatePartial)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TemplateRef) DeepCopyInto(out *TemplateRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TemplateRef.
+func (in *TemplateRef) DeepCopy() *TemplateRef {
+	if in == nil {
+		return nil
+	}
+	out := new(TemplateRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ValuesReference) DeepCopyInto(out *ValuesReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ValuesReference.
+func (in *ValuesReference) DeepCopy() *ValuesReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ValuesReference)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	templatesv1 "github.com/weaveworks/gitopssets-controller/api/v1alpha1"
+)
+
+const (
+	gitOpsSetNameLabel      = "sets.gitops.pro/name"
+	gitOpsSetNamespaceLabel = "sets.gitops.pro/namespace"
+)
+
+// AdoptionRefusedError is returned when an existing resource is owned by a
+// different GitOpsSet.
+type AdoptionRefusedError struct {
+	Resource client.ObjectKey
+	Kind     string
+	Owner    client.ObjectKey
+}
+
+func (e AdoptionRefusedError) Error() string {
+	return fmt.Sprintf("refusing to adopt %s %s owned by GitOpsSet %s", e.Kind, e.Resource, e.Owner)
+}
+
+// adoptionEnabled returns true if the existing resource can be adopted by the
+// GitOpsSet.
+//
+// Adoption must be enabled with a selector that matches the labels of the
+// existing resource, the templates can't opt in to adopting resources.
+func adoptionEnabled(gs *templatesv1.GitOpsSet, existing *unstructured.Unstructured) (bool, error) {
+	if gs.Spec.Adoption == nil || !gs.Spec.Adoption.Enabled {
+		return false, nil
+	}
+
+	if gs.Spec.Adoption.Selector == nil {
+		return false, errors.New("adoption requires a selector for the resources to adopt")
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(gs.Spec.Adoption.Selector)
+	if err != nil {
+		return false, fmt.Errorf("unable to convert adoption selector: %w", err)
+	}
+
+	return selector.Matches(labels.Set(existing.GetLabels())), nil
+}
+
+// adoptResource takes ownership of an existing resource that is not in the
+// inventory, updating it from the generated resource.
+//
+// If the resource can't be adopted, false is returned.
+func (r *GitOpsSetReconciler) adoptResource(ctx context.Context, k8sClient client.Client, gs *templatesv1.GitOpsSet, newResource *unstructured.Unstructured) (bool, error) {
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(newResource.GroupVersionKind())
+	if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(newResource), existing); err != nil {
+		return false, fmt.Errorf("failed to load existing Resource: %w", err)
+	}
+
+	enabled, err := adoptionEnabled(gs, existing)
+	if err != nil || !enabled {
+		return false, err
+	}
+
+	existingLabels := existing.GetLabels()
+	owner := client.ObjectKey{Name: existingLabels[gitOpsSetNameLabel], Namespace: existingLabels[gitOpsSetNamespaceLabel]}
+	if (owner.Name != "" || owner.Namespace != "") && owner != client.ObjectKeyFromObject(gs) {
+		return false, AdoptionRefusedError{Resource: client.ObjectKeyFromObject(existing), Kind: existing.GetKind(), Owner: owner}
+	}
+
+	if r.serverSideApply(gs) {
+		_, err := applyResource(ctx, k8sClient, gs, newResource, csaFieldManagers)
+		return err == nil, err
+	}
+
+	if err := k8sClient.Patch(ctx, copyUnstructuredContent(existing, newResource), client.MergeFrom(existing), client.FieldOwner(fieldManager)); err != nil {
+		return false, fmt.Errorf("failed to adopt Resource: %w", err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	templatesv1 "github.com/weaveworks/gitopssets-controller/api/v1alpha1"
+	"github.com/weaveworks/gitopssets-controller/test"
+)
+
+func TestAdoptionEnabled(t *testing.T) {
+	devSelector := &metav1.LabelSelector{MatchLabels: map[string]string{"env": "dev"}}
+
+	tests := []struct {
+		name           string
+		adoption       *templatesv1.Adoption
+		existingLabels map[string]string
+		want           bool
+		wantErr        string
+	}{
+		{
+			name: "adoption not configured",
+			want: false,
+		},
+		{
+			name:     "adoption enabled without a selector",
+			adoption: &templatesv1.Adoption{Enabled: true},
+			wantErr:  "adoption requires a selector for the resources to adopt",
+		},
+		{
+			name:           "adoption enabled with matching selector",
+			adoption:       &templatesv1.Adoption{Enabled: true, Selector: devSelector},
+			existingLabels: map[string]string{"env": "dev"},
+			want:           true,
+		},
+		{
+			name:           "adoption enabled with non-matching selector",
+			adoption:       &templatesv1.Adoption{Enabled: true, Selector: devSelector},
+			existingLabels: map[string]string{"env": "production"},
+			want:           false,
+		},
+		{
+			name:           "adoption not enabled with matching selector",
+			adoption:       &templatesv1.Adoption{Selector: devSelector},
+			existingLabels: map[string]string{"env": "dev"},
+			want:           false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gs := &templatesv1.GitOpsSet{
+				Spec: templatesv1.GitOpsSetSpec{
+					Adoption: tt.adoption,
+				},
+			}
+			existing := &unstructured.Unstructured{}
+			existing.SetLabels(tt.existingLabels)
+
+			got, err := adoptionEnabled(gs, existing)
+			if tt.wantErr != "" {
+				test.AssertErrorMatch(t, tt.wantErr, err)
+				return
+			}
+			test.AssertNoError(t, err)
+
+			if got != tt.want {
+				t.Errorf("adoptionEnabled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	templatesv1 "github.com/weaveworks/gitopssets-controller/api/v1alpha1"
+)
+
+// DeletionsBlockedError is returned when reconciling a GitOpsSet would delete
+// more resources than its MaxDeletions allows.
+type DeletionsBlockedError struct {
+	Deletions    int
+	MaxDeletions int
+}
+
+func (e DeletionsBlockedError) Error() string {
+	return fmt.Sprintf("%d resources would be deleted which exceeds the maximum of %d, set the %s annotation to approve the deletions",
+		e.Deletions, e.MaxDeletions, templatesv1.ApproveDeletionsAnnotation)
+}
+
+// checkDeletions returns a DeletionsBlockedError if the number of deletions
+// exceeds the MaxDeletions of the GitOpsSet and the deletions have not been
+// approved.
+//
+// A new value of the approval annotation approves the deletions for a single
+// reconciliation, the value is recorded in the status when it unblocks
+// deletions that exceed the maximum, so that an approval is not used up by
+// deletions that didn't need it.
+func checkDeletions(gs *templatesv1.GitOpsSet, deletions, inventorySize int) error {
+	if gs.Spec.MaxDeletions == nil || deletions == 0 {
+		return nil
+	}
+
+	maxDeletions, err := intstr.GetScaledValueFromIntOrPercent(gs.Spec.MaxDeletions, inventorySize, false)
+	if err != nil {
+		return fmt.Errorf("invalid maxDeletions: %w", err)
+	}
+
+	if deletions <= maxDeletions {
+		return nil
+	}
+
+	approval, ok := gs.GetAnnotations()[templatesv1.ApproveDeletionsAnnotation]
+	if ok && approval != gs.Status.LastHandledDeletionApproval {
+		gs.Status.LastHandledDeletionApproval = approval
+		return nil
+	}
+
+	return DeletionsBlockedError{Deletions: deletions, MaxDeletions: maxDeletions}
+}
+
+// deletionApprovalPredicate triggers reconciliation when the approve deletions
+// annotation is changed.
+var deletionApprovalPredicate = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		if e.ObjectOld == nil || e.ObjectNew == nil {
+			return false
+		}
+
+		approval, ok := e.ObjectNew.GetAnnotations()[templatesv1.ApproveDeletionsAnnotation]
+
+		return ok && approval != e.ObjectOld.GetAnnotations()[templatesv1.ApproveDeletionsAnnotation]
+	},
+}
@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+
+	templatesv1 "github.com/weaveworks/gitopssets-controller/api/v1alpha1"
+	"github.com/weaveworks/gitopssets-controller/test"
+)
+
+func TestCheckDeletions(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxDeletions  *intstr.IntOrString
+		annotations   map[string]string
+		lastApproval  string
+		deletions     int
+		inventorySize int
+		wantErr       string
+		wantApproval  string
+	}{
+		{
+			name:          "no maximum",
+			deletions:     10,
+			inventorySize: 10,
+		},
+		{
+			name:          "deletions within the maximum",
+			maxDeletions:  ptr.To(intstr.FromInt32(2)),
+			deletions:     2,
+			inventorySize: 10,
+		},
+		{
+			name:          "deletions exceed the maximum",
+			maxDeletions:  ptr.To(intstr.FromInt32(2)),
+			deletions:     3,
+			inventorySize: 10,
+			wantErr:       "3 resources would be deleted which exceeds the maximum of 2",
+		},
+		{
+			name:          "deletions within the maximum percentage",
+			maxDeletions:  ptr.To(intstr.FromString("50%")),
+			deletions:     5,
+			inventorySize: 10,
+		},
+		{
+			name:          "deletions exceed the maximum percentage",
+			maxDeletions:  ptr.To(intstr.FromString("50%")),
+			deletions:     6,
+			inventorySize: 10,
+			wantErr:       "6 resources would be deleted which exceeds the maximum of 5",
+		},
+		{
+			name:          "deletions exceeding the maximum are approved",
+			maxDeletions:  ptr.To(intstr.FromInt32(2)),
+			annotations:   map[string]string{templatesv1.ApproveDeletionsAnnotation: "approval-1"},
+			deletions:     3,
+			inventorySize: 10,
+			wantApproval:  "approval-1",
+		},
+		{
+			name:          "approval has already been handled",
+			maxDeletions:  ptr.To(intstr.FromInt32(2)),
+			annotations:   map[string]string{templatesv1.ApproveDeletionsAnnotation: "approval-1"},
+			lastApproval:  "approval-1",
+			deletions:     3,
+			inventorySize: 10,
+			wantErr:       "3 resources would be deleted which exceeds the maximum of 2",
+			wantApproval:  "approval-1",
+		},
+		{
+			name:          "approval is not used when deletions are within the maximum",
+			maxDeletions:  ptr.To(intstr.FromInt32(2)),
+			annotations:   map[string]string{templatesv1.ApproveDeletionsAnnotation: "approval-2"},
+			lastApproval:  "approval-1",
+			deletions:     1,
+			inventorySize: 10,
+			wantApproval:  "approval-1",
+		},
+		{
+			name:          "approval is not used when there is no maximum",
+			annotations:   map[string]string{templatesv1.ApproveDeletionsAnnotation: "approval-2"},
+			lastApproval:  "approval-1",
+			deletions:     3,
+			inventorySize: 10,
+			wantApproval:  "approval-1",
+		},
+		{
+			name:          "invalid maximum",
+			maxDeletions:  ptr.To(intstr.FromString("many")),
+			deletions:     1,
+			inventorySize: 10,
+			wantErr:       "invalid maxDeletions",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gs := &templatesv1.GitOpsSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: tt.annotations,
+				},
+				Spec: templatesv1.GitOpsSetSpec{
+					MaxDeletions: tt.maxDeletions,
+				},
+				Status: templatesv1.GitOpsSetStatus{
+					LastHandledDeletionApproval: tt.lastApproval,
+				},
+			}
+
+			err := checkDeletions(gs, tt.deletions, tt.inventorySize)
+			if tt.wantErr == "" {
+				test.AssertNoError(t, err)
+			} else {
+				test.AssertErrorMatch(t, tt.wantErr, err)
+			}
+
+			if gs.Status.LastHandledDeletionApproval != tt.wantApproval {
+				t.Errorf("got approval %q, want %q", gs.Status.LastHandledDeletionApproval, tt.wantApproval)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	templatesv1 "github.com/weaveworks/gitopssets-controller/api/v1alpha1"
+)
+
+const (
+	// DriftCorrectedReason is the reason for events recorded when a generated
+	// resource that was changed outside of the GitOpsSet is corrected.
+	DriftCorrectedReason = "DriftCorrected"
+
+	driftModified = "modified"
+	driftDeleted  = "deleted"
+)
+
+var driftCorrectionsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "gitopsset_drift_corrections_total",
+		Help: "The number of generated resources that were corrected after drifting from the GitOpsSet templates.",
+	},
+	[]string{"name", "namespace", "kind", "drift"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(driftCorrectionsTotal)
+}
+
+// recordDrift records an event and increments the drift metric for a
+// generated resource that was corrected.
+//
+// Nothing is recorded when the changes are only being planned.
+func (r *GitOpsSetReconciler) recordDrift(k8sClient client.Client, gs *templatesv1.GitOpsSet, obj *unstructured.Unstructured, drift string) {
+	if isPlanning(k8sClient) {
+		return
+	}
+
+	driftCorrectionsTotal.WithLabelValues(gs.GetName(), gs.GetNamespace(), obj.GetKind(), drift).Inc()
+
+	if r.EventRecorder != nil {
+		r.EventRecorder.Event(gs, corev1.EventTypeNormal, DriftCorrectedReason,
+			fmt.Sprintf("%s %s was %s outside of the GitOpsSet and has been corrected", obj.GetKind(), client.ObjectKeyFromObject(obj), drift))
+	}
+}
+
+// modifiedByOtherManager returns true if the most recent change to the
+// resource was made by a field manager other than the controller.
+//
+// Managed fields times only have a resolution of seconds, if the controller
+// and another field manager changed the resource in the same second, it's
+// treated as modified by the other field manager.
+func modifiedByOtherManager(obj *unstructured.Unstructured) bool {
+	var latest metav1.Time
+	modified := false
+	for _, entry := range obj.GetManagedFields() {
+		if entry.Subresource != "" || entry.Time == nil || entry.Time.Before(&latest) {
+			continue
+		}
+
+		other := !csaFieldManagers.Has(entry.Manager)
+		if entry.Time.Equal(&latest) {
+			modified = modified || other
+			continue
+		}
+
+		latest = *entry.Time
+		modified = other
+	}
+
+	return modified
+}
@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestModifiedByOtherManager(t *testing.T) {
+	now := time.Now()
+	earlier := metav1.NewTime(now.Add(-time.Minute))
+	later := metav1.NewTime(now)
+
+	tests := []struct {
+		name          string
+		managedFields []metav1.ManagedFieldsEntry
+		want          bool
+	}{
+		{
+			name: "no managed fields",
+			want: false,
+		},
+		{
+			name: "only modified by the controller",
+			managedFields: []metav1.ManagedFieldsEntry{
+				{Manager: fieldManager, Operation: metav1.ManagedFieldsOperationUpdate, Time: &earlier},
+			},
+			want: false,
+		},
+		{
+			name: "modified by another manager after the controller",
+			managedFields: []metav1.ManagedFieldsEntry{
+				{Manager: fieldManager, Operation: metav1.ManagedFieldsOperationUpdate, Time: &earlier},
+				{Manager: "kubectl-edit", Operation: metav1.ManagedFieldsOperationUpdate, Time: &later},
+			},
+			want: true,
+		},
+		{
+			name: "modified by the controller after another manager",
+			managedFields: []metav1.ManagedFieldsEntry{
+				{Manager: "kubectl-edit", Operation: metav1.ManagedFieldsOperationUpdate, Time: &earlier},
+				{Manager: fieldManager, Operation: metav1.ManagedFieldsOperationApply, Time: &later},
+			},
+			want: false,
+		},
+		{
+			name: "modified by the controller and another manager at the same time",
+			managedFields: []metav1.ManagedFieldsEntry{
+				{Manager: fieldManager, Operation: metav1.ManagedFieldsOperationUpdate, Time: &later},
+				{Manager: "kubectl-edit", Operation: metav1.ManagedFieldsOperationUpdate, Time: &later},
+			},
+			want: true,
+		},
+		{
+			name: "status modified by another manager",
+			managedFields: []metav1.ManagedFieldsEntry{
+				{Manager: fieldManager, Operation: metav1.ManagedFieldsOperationUpdate, Time: &earlier},
+				{Manager: "kustomize-controller", Operation: metav1.ManagedFieldsOperationUpdate, Time: &later, Subresource: "status"},
+			},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &unstructured.Unstructured{}
+			u.SetManagedFields(tt.managedFields)
+
+			if got := modifiedByOtherManager(u); got != tt.want {
+				t.Errorf("modifiedByOtherManager() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
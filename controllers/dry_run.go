@@ -0,0 +1,225 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/gitops-tools/pkg/sets"
+	"github.com/go-logr/logr"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	templatesv1 "github.com/weaveworks/gitopssets-controller/api/v1alpha1"
+	"github.com/weaveworks/gitopssets-controller/pkg/generators"
+)
+
+// maxDiffLength is the maximum length of the diff recorded for each planned
+// change, to avoid exceeding the size limits of the status.
+const maxDiffLength = 1024
+
+// omittedDiff replaces the diff of changes that could reveal the contents of
+// Secrets.
+const omittedDiff = "(diff omitted, the resource may contain values from a Secret)"
+
+var secretGroupKind = schema.GroupKind{Kind: "Secret"}
+
+// planResources renders the templates and reconciles the resources with a
+// client that records the changes, without making any changes.
+//
+// The resources are reconciled in the same way as when the changes are made,
+// so the error policy, rollout waves, adoption and the deletion limits are
+// respected, and each change is validated with a server-side dry-run.
+//
+// The status of the GitOpsSet is not changed.
+func (r *GitOpsSetReconciler) planResources(ctx context.Context, logger logr.Logger, k8sClient client.Client, gitOpsSet *templatesv1.GitOpsSet, instantiatedGenerators map[string]generators.Generator) ([]templatesv1.PlannedChange, error) {
+	planner := newPlanningClient(k8sClient, gitOpsSet)
+	_, err := r.renderAndReconcile(ctx, logger, planner, gitOpsSet.DeepCopy(), instantiatedGenerators)
+
+	return planner.plan(), err
+}
+
+// planningClient is a client that makes all changes with a server-side
+// dry-run, and records the changes that would be made to each resource.
+type planningClient struct {
+	client.Client
+
+	inventory sets.Set[templatesv1.ResourceRef]
+	omitDiffs bool
+	changes   map[templatesv1.ResourceRef]templatesv1.PlannedChange
+}
+
+func newPlanningClient(k8sClient client.Client, gitOpsSet *templatesv1.GitOpsSet) *planningClient {
+	inventory := sets.New[templatesv1.ResourceRef]()
+	if gitOpsSet.Status.Inventory != nil {
+		inventory.Insert(gitOpsSet.Status.Inventory.Entries...)
+	}
+
+	omitDiffs := false
+	for _, ref := range gitOpsSet.Spec.ValuesFrom {
+		if ref.Kind == "Secret" {
+			omitDiffs = true
+		}
+	}
+
+	return &planningClient{
+		Client:    client.NewDryRunClient(k8sClient),
+		inventory: inventory,
+		omitDiffs: omitDiffs,
+		changes:   map[templatesv1.ResourceRef]templatesv1.PlannedChange{},
+	}
+}
+
+// Create is an implementation of the client.Writer interface.
+func (c *planningClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	if err := c.Client.Create(ctx, obj, opts...); err != nil {
+		return err
+	}
+
+	return c.record(obj, templatesv1.PlannedChange{Action: templatesv1.PlanActionCreate})
+}
+
+// Patch is an implementation of the client.Writer interface.
+//
+// The patched resource is compared with the existing resource, existing
+// resources that are not in the inventory are recorded as adopted.
+func (c *planningClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(obj.GetObjectKind().GroupVersionKind())
+	err := c.Client.Get(ctx, client.ObjectKeyFromObject(obj), existing)
+	if client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("failed to load existing Resource: %w", err)
+	}
+	found := err == nil
+
+	if err := c.Client.Patch(ctx, obj, patch, opts...); err != nil {
+		return err
+	}
+
+	if !found {
+		return c.record(obj, templatesv1.PlannedChange{Action: templatesv1.PlanActionCreate})
+	}
+
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return err
+	}
+	updated := &unstructured.Unstructured{Object: content}
+
+	change := templatesv1.PlannedChange{Action: templatesv1.PlanActionUpdate}
+	if diff := cmp.Diff(comparableContent(existing), comparableContent(updated)); diff != "" {
+		change.Diff = truncateDiff(diff)
+	}
+
+	ref, err := templatesv1.ResourceRefFromObject(obj)
+	if err != nil {
+		return err
+	}
+	if !c.inventory.Has(ref) {
+		change.Action = templatesv1.PlanActionAdopt
+	} else if change.Diff == "" {
+		return nil
+	}
+
+	return c.record(obj, change)
+}
+
+// Delete is an implementation of the client.Writer interface.
+func (c *planningClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	if err := c.Client.Delete(ctx, obj, opts...); err != nil {
+		return err
+	}
+
+	return c.record(obj, templatesv1.PlannedChange{Action: templatesv1.PlanActionPrune})
+}
+
+func (c *planningClient) record(obj client.Object, change templatesv1.PlannedChange) error {
+	ref, err := templatesv1.ResourceRefFromObject(obj)
+	if err != nil {
+		return fmt.Errorf("failed to record planned change: %w", err)
+	}
+
+	if change.Diff != "" && (c.omitDiffs || obj.GetObjectKind().GroupVersionKind().GroupKind() == secretGroupKind) {
+		change.Diff = omittedDiff
+	}
+
+	change.ResourceRef = ref
+	c.changes[ref] = change
+
+	return nil
+}
+
+// plan returns the recorded changes ordered by resource.
+func (c *planningClient) plan() []templatesv1.PlannedChange {
+	plan := []templatesv1.PlannedChange{}
+	for _, change := range c.changes {
+		plan = append(plan, change)
+	}
+	sort.Slice(plan, func(i, j int) bool { return plan[i].ID < plan[j].ID })
+
+	return plan
+}
+
+// recordConflict records a generated resource that can't be created because
+// it already exists when the changes are being planned.
+func recordConflict(k8sClient client.Client, obj client.Object, conflict error) error {
+	planner, ok := k8sClient.(*planningClient)
+	if !ok {
+		return nil
+	}
+
+	return planner.record(obj, templatesv1.PlannedChange{Action: templatesv1.PlanActionConflict, Message: conflict.Error()})
+}
+
+// isPlanning returns true if the client only plans changes.
+func isPlanning(k8sClient client.Reader) bool {
+	_, ok := k8sClient.(*planningClient)
+
+	return ok
+}
+
+// isPlanError returns true if the error is reported in the plan, rather than
+// preventing the changes from being planned.
+func isPlanError(err error) bool {
+	return errors.As(err, &ElementsFailedError{}) || errors.As(err, &DeletionsBlockedError{})
+}
+
+// comparableContent returns the content of the resource without the fields
+// that are maintained by the API server.
+func comparableContent(u *unstructured.Unstructured) map[string]any {
+	content := u.DeepCopy().UnstructuredContent()
+	delete(content, "status")
+	for _, field := range []string{"managedFields", "resourceVersion", "generation", "creationTimestamp", "uid"} {
+		unstructured.RemoveNestedField(content, "metadata", field)
+	}
+
+	return content
+}
+
+func truncateDiff(diff string) string {
+	if len(diff) <= maxDiffLength {
+		return diff
+	}
+
+	return diff[:maxDiffLength] + "\n... (truncated)"
+}
+
+// summarizePlan returns a summary of the changes in the plan.
+func summarizePlan(plan []templatesv1.PlannedChange) string {
+	counts := map[string]int{}
+	for _, change := range plan {
+		counts[change.Action]++
+	}
+
+	summary := fmt.Sprintf("dry-run: %d resources would be created, %d updated, %d adopted and %d pruned",
+		counts[templatesv1.PlanActionCreate], counts[templatesv1.PlanActionUpdate], counts[templatesv1.PlanActionAdopt], counts[templatesv1.PlanActionPrune])
+	if conflicts := counts[templatesv1.PlanActionConflict]; conflicts > 0 {
+		summary += fmt.Sprintf(", %d resources conflict with existing resources", conflicts)
+	}
+
+	return summary
+}
@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	templatesv1 "github.com/weaveworks/gitopssets-controller/api/v1alpha1"
+	"github.com/weaveworks/gitopssets-controller/test"
+)
+
+func TestSummarizePlan(t *testing.T) {
+	plan := []templatesv1.PlannedChange{
+		{Action: templatesv1.PlanActionCreate},
+		{Action: templatesv1.PlanActionCreate},
+		{Action: templatesv1.PlanActionUpdate},
+	}
+
+	want := "dry-run: 2 resources would be created, 1 updated, 0 adopted and 0 pruned"
+	if got := summarizePlan(plan); got != want {
+		t.Errorf("summarizePlan() got %q, want %q", got, want)
+	}
+
+	plan = append(plan, templatesv1.PlannedChange{Action: templatesv1.PlanActionConflict})
+	want = "dry-run: 2 resources would be created, 1 updated, 0 adopted and 0 pruned, 1 resources conflict with existing resources"
+	if got := summarizePlan(plan); got != want {
+		t.Errorf("summarizePlan() got %q, want %q", got, want)
+	}
+}
+
+func TestTruncateDiff(t *testing.T) {
+	short := "-  path: old\n+  path: new"
+	if got := truncateDiff(short); got != short {
+		t.Errorf("truncateDiff() got %q, want %q", got, short)
+	}
+
+	long := strings.Repeat("a", maxDiffLength+10)
+	want := strings.Repeat("a", maxDiffLength) + "\n... (truncated)"
+	if got := truncateDiff(long); got != want {
+		t.Errorf("truncateDiff() got %q, want %q", got, want)
+	}
+}
+
+func TestPlanningClient(t *testing.T) {
+	ctx := context.TODO()
+	inInventory := test.NewConfigMap(func(c *corev1.ConfigMap) {
+		c.Name = "in-inventory"
+	})
+	unmanaged := test.NewConfigMap(func(c *corev1.ConfigMap) {
+		c.Name = "unmanaged"
+	})
+	pruned := test.NewConfigMap(func(c *corev1.ConfigMap) {
+		c.Name = "pruned"
+	})
+	secret := test.NewSecret()
+	k8sClient := fake.NewClientBuilder().WithRuntimeObjects(inInventory, unmanaged, pruned, secret).Build()
+
+	gs := &templatesv1.GitOpsSet{
+		Status: templatesv1.GitOpsSetStatus{
+			Inventory: &templatesv1.ResourceInventory{
+				Entries: []templatesv1.ResourceRef{
+					mustResourceRef(t, inInventory),
+					mustResourceRef(t, pruned),
+					mustResourceRef(t, secret),
+				},
+			},
+		},
+	}
+	planner := newPlanningClient(k8sClient, gs)
+
+	created := test.ToUnstructured(t, test.NewConfigMap(func(c *corev1.ConfigMap) {
+		c.Name = "created"
+	}))
+	test.AssertNoError(t, planner.Create(ctx, created))
+	for _, obj := range []client.Object{inInventory, unmanaged, secret} {
+		existing := test.ToUnstructured(t, obj)
+		updated := existing.DeepCopy()
+		value := "updated"
+		if obj == secret {
+			value = base64.StdEncoding.EncodeToString([]byte(value))
+		}
+		test.AssertNoError(t, unstructured.SetNestedField(updated.Object, value, "data", "testing"))
+		test.AssertNoError(t, planner.Patch(ctx, updated, client.MergeFrom(existing)))
+	}
+	test.AssertNoError(t, planner.Delete(ctx, test.ToUnstructured(t, pruned)))
+
+	gotActions := map[string]string{}
+	for _, change := range planner.plan() {
+		gotActions[change.ID] = change.Action
+		if change.Action == templatesv1.PlanActionUpdate && change.Diff == "" {
+			t.Errorf("got no diff for updated resource %s", change.ID)
+		}
+		if change.ID == "default_demo-secret__Secret" && change.Diff != omittedDiff {
+			t.Errorf("got diff %q for Secret, want it omitted", change.Diff)
+		}
+	}
+	wantActions := map[string]string{
+		"default_created__ConfigMap":      templatesv1.PlanActionCreate,
+		"default_in-inventory__ConfigMap": templatesv1.PlanActionUpdate,
+		"default_unmanaged__ConfigMap":    templatesv1.PlanActionAdopt,
+		"default_demo-secret__Secret":     templatesv1.PlanActionUpdate,
+		"default_pruned__ConfigMap":       templatesv1.PlanActionPrune,
+	}
+	if diff := cmp.Diff(wantActions, gotActions); diff != "" {
+		t.Fatalf("failed to plan changes:\n%s", diff)
+	}
+
+	// Nothing was changed in the cluster.
+	test.AssertNotFound(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(created), &corev1.ConfigMap{}))
+	test.AssertNoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(pruned), &corev1.ConfigMap{}))
+	current := &corev1.ConfigMap{}
+	test.AssertNoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(inInventory), current))
+	if diff := cmp.Diff(inInventory.Data, current.Data); diff != "" {
+		t.Fatalf("ConfigMap was updated:\n%s", diff)
+	}
+}
+
+func mustResourceRef(t *testing.T, obj runtime.Object) templatesv1.ResourceRef {
+	t.Helper()
+	ref, err := templatesv1.ResourceRefFromObject(obj)
+	test.AssertNoError(t, err)
+
+	return ref
+}
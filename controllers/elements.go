@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/client-go/util/jsonpath"
+
+	templatesv1 "github.com/weaveworks/gitopssets-controller/api/v1alpha1"
+	"github.com/weaveworks/gitopssets-controller/controllers/templates"
+)
+
+// ElementsFailedError is returned when the resources generated for some of the
+// elements could not be applied.
+//
+// The errors for each element are recorded in the status.
+type ElementsFailedError struct {
+	Applied int
+	Total   int
+	Errs    []error
+}
+
+func (e ElementsFailedError) Error() string {
+	msg := fmt.Sprintf("%d/%d elements applied: %s", e.Applied, e.Total, e.Errs[0])
+	if len(e.Errs) > 1 {
+		msg += fmt.Sprintf(" (and %d more failures)", len(e.Errs)-1)
+	}
+
+	return msg
+}
+
+func (e ElementsFailedError) Unwrap() []error {
+	return e.Errs
+}
+
+// validateElementKey returns an error if the GitOpsSet uses features that
+// track elements between reconciliations without an ElementKey expression.
+//
+// The default key is a hash of the element, which changes whenever the element
+// changes, so it can only be used to display the elements in the status.
+func validateElementKey(gs *templatesv1.GitOpsSet) error {
+	if gs.Spec.ElementKey != "" {
+		return nil
+	}
+
+	if gs.Spec.Rollout != nil {
+		return errors.New("rollout requires an elementKey to identify the elements")
+	}
+
+	if gs.Spec.ErrorPolicy == templatesv1.ErrorPolicyContinue {
+		return errors.New("the continue errorPolicy requires an elementKey to identify the elements")
+	}
+
+	return nil
+}
+
+// elementKeys returns the keys that identify each of the elements in the
+// status.
+//
+// If the GitOpsSet doesn't have an ElementKey expression, the key is a hash of
+// the element, see validateElementKey.
+//
+// The keys from an ElementKey expression must be unique, the status, rollout
+// and resources of each element are tracked by its key.
+func elementKeys(gs *templatesv1.GitOpsSet, elements []templates.RenderedElement) ([]string, error) {
+	keys := make([]string, len(elements))
+	if gs.Spec.ElementKey == "" {
+		for i := range elements {
+			b, err := json.Marshal(elements[i].Element)
+			if err != nil {
+				return nil, fmt.Errorf("failed to calculate element key: %w", err)
+			}
+			keys[i] = fmt.Sprintf("%x", sha256.Sum256(b))[:16]
+		}
+
+		return keys, nil
+	}
+
+	jp := jsonpath.New("elementKey")
+	if err := jp.Parse(gs.Spec.ElementKey); err != nil {
+		return nil, fmt.Errorf("failed to parse elementKey %q: %w", gs.Spec.ElementKey, err)
+	}
+
+	seen := map[string]bool{}
+	for i := range elements {
+		var buf bytes.Buffer
+		if err := jp.Execute(&buf, elements[i].Element); err != nil {
+			return nil, fmt.Errorf("failed to evaluate elementKey %q: %w", gs.Spec.ElementKey, err)
+		}
+		key := buf.String()
+		if seen[key] {
+			return nil, fmt.Errorf("elementKey %q generated the duplicate key %q", gs.Spec.ElementKey, key)
+		}
+		seen[key] = true
+		keys[i] = key
+	}
+
+	return keys, nil
+}
+
+// publishElements records the generated elements in the status when the
+// GitOpsSet publishes its elements.
+//
+// If the generators failed, the previously published elements are kept, so
+// that the GitOpsSets using them don't lose elements.
+func publishElements(gs *templatesv1.GitOpsSet, elements []templates.RenderedElement, generatorErr error) error {
+	if !gs.Spec.PublishElements {
+		gs.Status.PublishedElements = nil
+		return nil
+	}
+
+	if generatorErr != nil {
+		return nil
+	}
+
+	published := &templatesv1.PublishedElements{}
+	for i := range elements {
+		b, err := json.Marshal(elements[i].Element)
+		if err != nil {
+			return fmt.Errorf("failed to publish elements: %w", err)
+		}
+		published.Elements = append(published.Elements, apiextensionsv1.JSON{Raw: b})
+	}
+	gs.Status.PublishedElements = published
+
+	return nil
+}
@@ -0,0 +1,215 @@
+package controllers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+
+	templatesv1 "github.com/weaveworks/gitopssets-controller/api/v1alpha1"
+	"github.com/weaveworks/gitopssets-controller/controllers/templates"
+	"github.com/weaveworks/gitopssets-controller/test"
+)
+
+func TestElementKeys(t *testing.T) {
+	elements := []templates.RenderedElement{
+		{Element: map[string]any{"ClusterName": "dev", "ClusterNamespace": "clusters"}},
+		{Element: map[string]any{"ClusterName": "prod", "ClusterNamespace": "clusters"}},
+	}
+
+	tests := []struct {
+		name       string
+		elementKey string
+		want       []string
+	}{
+		{
+			name: "hashed elements",
+			want: []string{"bf29e66d414821e0", "679238aefcaf4143"},
+		},
+		{
+			name:       "key from the elements",
+			elementKey: "{ .ClusterNamespace }/{ .ClusterName }",
+			want:       []string{"clusters/dev", "clusters/prod"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gs := &templatesv1.GitOpsSet{
+				Spec: templatesv1.GitOpsSetSpec{
+					ElementKey: tt.elementKey,
+				},
+			}
+
+			keys, err := elementKeys(gs, elements)
+			test.AssertNoError(t, err)
+
+			if diff := cmp.Diff(tt.want, keys); diff != "" {
+				t.Fatalf("failed to calculate keys:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestElementKeys_errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		elements []templates.RenderedElement
+		wantErr  string
+	}{
+		{
+			name:     "missing field",
+			elements: []templates.RenderedElement{{Element: map[string]any{"name": "dev"}}},
+			wantErr:  `failed to evaluate elementKey.*ClusterName is not found`,
+		},
+		{
+			name: "duplicate keys",
+			elements: []templates.RenderedElement{
+				{Element: map[string]any{"ClusterName": "dev", "ClusterNamespace": "team-a"}},
+				{Element: map[string]any{"ClusterName": "prod", "ClusterNamespace": "team-a"}},
+				{Element: map[string]any{"ClusterName": "dev", "ClusterNamespace": "team-b"}},
+			},
+			wantErr: `elementKey "{ .ClusterName }" generated the duplicate key "dev"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gs := &templatesv1.GitOpsSet{
+				Spec: templatesv1.GitOpsSetSpec{
+					ElementKey: "{ .ClusterName }",
+				},
+			}
+
+			_, err := elementKeys(gs, tt.elements)
+
+			test.AssertErrorMatch(t, tt.wantErr, err)
+		})
+	}
+}
+
+func TestValidateElementKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    templatesv1.GitOpsSetSpec
+		wantErr string
+	}{
+		{
+			name: "no per-element features",
+		},
+		{
+			name:    "rollout without an element key",
+			spec:    templatesv1.GitOpsSetSpec{Rollout: &templatesv1.Rollout{}},
+			wantErr: "rollout requires an elementKey",
+		},
+		{
+			name:    "continue error policy without an element key",
+			spec:    templatesv1.GitOpsSetSpec{ErrorPolicy: templatesv1.ErrorPolicyContinue},
+			wantErr: "the continue errorPolicy requires an elementKey",
+		},
+		{
+			name: "continue error policy with an element key",
+			spec: templatesv1.GitOpsSetSpec{ElementKey: "{ .ClusterName }", ErrorPolicy: templatesv1.ErrorPolicyContinue},
+		},
+		{
+			name: "rollout with an element key",
+			spec: templatesv1.GitOpsSetSpec{ElementKey: "{ .ClusterName }", Rollout: &templatesv1.Rollout{}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateElementKey(&templatesv1.GitOpsSet{Spec: tt.spec})
+
+			test.AssertErrorMatch(t, tt.wantErr, err)
+		})
+	}
+}
+
+func TestElementsFailedError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  ElementsFailedError
+		want string
+	}{
+		{
+			name: "single failure",
+			err:  ElementsFailedError{Applied: 2, Total: 3, Errs: []error{errors.New("failed to create Resource")}},
+			want: "2/3 elements applied: failed to create Resource",
+		},
+		{
+			name: "multiple failures",
+			err: ElementsFailedError{Applied: 297, Total: 300, Errs: []error{
+				errors.New("failed to create Resource"),
+				errors.New("failed to update Resource"),
+				errors.New("failed to update Resource"),
+			}},
+			want: "297/300 elements applied: failed to create Resource (and 2 more failures)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if msg := tt.err.Error(); msg != tt.want {
+				t.Errorf("got %q, want %q", msg, tt.want)
+			}
+		})
+	}
+}
+
+func TestPublishElements(t *testing.T) {
+	elements := []templates.RenderedElement{
+		{Element: map[string]any{"ClusterName": "dev"}},
+		{Element: map[string]any{"ClusterName": "prod"}},
+	}
+	previous := &templatesv1.PublishedElements{
+		Elements: []apiextensionsv1.JSON{{Raw: []byte(`{"ClusterName":"staging"}`)}},
+	}
+
+	tests := []struct {
+		name            string
+		publishElements bool
+		generatorErr    error
+		want            *templatesv1.PublishedElements
+	}{
+		{
+			name:            "publishing elements",
+			publishElements: true,
+			want: &templatesv1.PublishedElements{
+				Elements: []apiextensionsv1.JSON{
+					{Raw: []byte(`{"ClusterName":"dev"}`)},
+					{Raw: []byte(`{"ClusterName":"prod"}`)},
+				},
+			},
+		},
+		{
+			name:            "generators failed",
+			publishElements: true,
+			generatorErr:    errors.New("failed to generate"),
+			want:            previous,
+		},
+		{
+			name: "not publishing elements",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gs := &templatesv1.GitOpsSet{
+				Spec: templatesv1.GitOpsSetSpec{
+					PublishElements: tt.publishElements,
+				},
+				Status: templatesv1.GitOpsSetStatus{
+					PublishedElements: previous,
+				},
+			}
+
+			test.AssertNoError(t, publishElements(gs, elements, tt.generatorErr))
+
+			if diff := cmp.Diff(tt.want, gs.Status.PublishedElements); diff != "" {
+				t.Fatalf("failed to publish elements:\n%s", diff)
+			}
+		})
+	}
+}
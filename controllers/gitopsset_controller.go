@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"reflect"
 	"sort"
 	"time"
 
@@ -18,6 +19,7 @@ import (
 	"github.com/gitops-tools/pkg/sets"
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -30,6 +32,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
@@ -39,6 +42,7 @@ import (
 	templatesv1 "github.com/weaveworks/gitopssets-controller/api/v1alpha1"
 	"github.com/weaveworks/gitopssets-controller/controllers/templates"
 	"github.com/weaveworks/gitopssets-controller/pkg/generators"
+	"github.com/weaveworks/gitopssets-controller/pkg/parser"
 )
 
 var accessor = meta.NewAccessor()
@@ -46,9 +50,11 @@ var accessor = meta.NewAccessor()
 const (
 	gitRepositoryIndexKey string = ".metadata.gitRepository"
 	ociRepositoryIndexKey string = ".metadata.ociRepository"
+	bucketIndexKey        string = ".metadata.bucket"
 	imagePolicyIndexKey   string = ".metadata.imagePolicy"
 	configMapIndexKey     string = ".metadata.configMap"
 	secretIndexKey        string = ".metadata.secret"
+	gitOpsSetIndexKey     string = ".metadata.gitOpsSet"
 )
 
 type eventRecorder interface {
@@ -58,15 +64,27 @@ type eventRecorder interface {
 // GitOpsSetReconciler reconciles a GitOpsSet object
 type GitOpsSetReconciler struct {
 	client.Client
-	DefaultServiceAccount string
-	Config                *rest.Config
-	EventRecorder         eventRecorder
+	DefaultServiceAccount  string
+	DefaultServerSideApply bool
+	Config                 *rest.Config
+	EventRecorder          eventRecorder
 	runtimeCtrl.Metrics
 
 	Generators map[string]generators.GeneratorFactory
 
+	// Fetcher is used to load templates that are referenced from
+	// GitRepositories and OCIRepositories.
+	Fetcher parser.ArchiveFetcher
+
+	// OCIRepositoryTemplates enables loading templates from OCIRepositories
+	// when the OCIRepository generator is disabled.
+	OCIRepositoryTemplates bool
+
 	Scheme *runtime.Scheme
 	Mapper meta.RESTMapper
+
+	resourceWatches *resourceWatches
+	artifactFiles   artifactFileCache
 }
 
 // event emits a Kubernetes event using EventRecorder
@@ -89,6 +107,7 @@ func (r *GitOpsSetReconciler) event(obj *templatesv1.GitOpsSet, severity, msg st
 //+kubebuilder:rbac:groups=sets.gitops.pro,resources=gitopssets/finalizers,verbs=update
 //+kubebuilder:rbac:groups=source.toolkit.fluxcd.io,resources=gitrepositories,verbs=get;list;watch
 //+kubebuilder:rbac:groups=source.toolkit.fluxcd.io,resources=ocirepositories,verbs=get;list;watch
+//+kubebuilder:rbac:groups=source.toolkit.fluxcd.io,resources=buckets,verbs=get;list;watch
 //+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
 //+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
 //+kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=impersonate
@@ -107,6 +126,9 @@ func (r *GitOpsSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 
 	var gitOpsSet templatesv1.GitOpsSet
 	if err := r.Client.Get(ctx, req.NamespacedName, &gitOpsSet); err != nil {
+		if apierrors.IsNotFound(err) && r.resourceWatches != nil {
+			r.resourceWatches.release(req.NamespacedName)
+		}
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
@@ -166,6 +188,13 @@ func (r *GitOpsSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		}
 	}()
 
+	if gitOpsSet.Spec.DryRun {
+		return r.reconcileDryRun(ctx, req, k8sClient, &gitOpsSet)
+	}
+
+	previousGeneration := gitOpsSet.Status.ObservedGeneration
+	previousInventory := gitOpsSet.Status.Inventory.DeepCopy()
+
 	inventory, requeue, err := r.reconcileResources(ctx, k8sClient, &gitOpsSet)
 
 	if err != nil {
@@ -180,6 +209,19 @@ func (r *GitOpsSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 			return ctrl.Result{}, nil
 		}
 
+		// The deletions are blocked until they are approved, which will trigger
+		// a reconciliation, or until the generators no longer generate the
+		// deletions when they are requeued, and the condition is cleared.
+		if errors.As(err, &DeletionsBlockedError{}) {
+			templatesv1.SetGitOpsSetReadiness(&gitOpsSet, inventory, metav1.ConditionFalse, templatesv1.DeletionsBlockedReason, err.Error())
+			templatesv1.SetGitOpsSetStalled(&gitOpsSet, templatesv1.DeletionsBlockedReason, err.Error())
+			if err := r.patchStatus(ctx, req, gitOpsSet.Status); err != nil {
+				logger.Error(err, "failed to reconcile")
+			}
+			r.event(&gitOpsSet, eventv1.EventSeverityError, err.Error())
+			return ctrl.Result{RequeueAfter: requeue}, nil
+		}
+
 		templatesv1.SetGitOpsSetReadiness(&gitOpsSet, inventory, metav1.ConditionFalse, templatesv1.ReconciliationFailedReason, err.Error())
 		if err := r.patchStatus(ctx, req, gitOpsSet.Status); err != nil {
 			logger.Error(err, "failed to reconcile")
@@ -191,9 +233,14 @@ func (r *GitOpsSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	}
 
 	if inventory != nil {
+		meta.RemoveStatusCondition(&gitOpsSet.Status.Conditions, fluxMeta.StalledCondition)
+		gitOpsSet.Status.Plan = nil
 		templatesv1.SetGitOpsSetReadiness(&gitOpsSet, inventory, metav1.ConditionTrue, templatesv1.ReconciliationSucceededReason,
 			fmt.Sprintf("%d resources created", len(inventory.Entries)))
 
+		resourcesChanged := previousGeneration != gitOpsSet.Generation || !reflect.DeepEqual(previousInventory, gitOpsSet.Status.Inventory)
+		requeue = reconcileHealth(ctx, k8sClient, &gitOpsSet, resourcesChanged, requeue)
+
 		if err := r.patchStatus(ctx, req, gitOpsSet.Status); err != nil {
 			logger.Error(err, "failed to reconcile")
 			templatesv1.SetGitOpsSetReadiness(&gitOpsSet, inventory, metav1.ConditionFalse, templatesv1.ReconciliationFailedReason, err.Error())
@@ -209,13 +256,23 @@ func (r *GitOpsSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 
 func (r *GitOpsSetReconciler) reconcileResources(ctx context.Context, k8sClient client.Client, gitOpsSet *templatesv1.GitOpsSet) (*templatesv1.ResourceInventory, time.Duration, error) {
 	logger := log.FromContext(ctx)
-	instantiatedGenerators := map[string]generators.Generator{}
-	for k, factory := range r.Generators {
-		instantiatedGenerators[k] = factory(log.FromContext(ctx), r.Client)
+	if r.resourceWatches != nil {
+		if err := r.resourceWatches.watchKinds(gitOpsSet, r.kubernetesResourceToGitOpsSet); err != nil {
+			return nil, generators.NoRequeueInterval, err
+		}
 	}
 
+	instantiatedGenerators := r.instantiateGenerators(ctx, k8sClient)
 	inventory, err := r.renderAndReconcile(ctx, logger, k8sClient, gitOpsSet, instantiatedGenerators)
 	if err != nil {
+		// Blocked deletions are requeued with the generators' interval, so that
+		// the deletions are unblocked if the generated elements recover.
+		if errors.As(err, &DeletionsBlockedError{}) {
+			if requeueAfter, intervalErr := calculateInterval(gitOpsSet, instantiatedGenerators); intervalErr == nil {
+				return inventory, requeueAfter, err
+			}
+		}
+
 		return inventory, generators.NoRequeueInterval, err
 	}
 
@@ -224,15 +281,142 @@ func (r *GitOpsSetReconciler) reconcileResources(ctx context.Context, k8sClient
 		return inventory, generators.NoRequeueInterval, fmt.Errorf("failed to calculate requeue interval: %w", err)
 	}
 
+	if gitOpsSet.Status.Rollout != nil && !gitOpsSet.Status.Rollout.Complete() &&
+		(requeueAfter == generators.NoRequeueInterval || requeueAfter > rolloutInterval) {
+		requeueAfter = rolloutInterval
+	}
+
 	return inventory, requeueAfter, nil
 }
 
+// reconcileDryRun records the changes that would be made to the generated
+// resources in the status without making any changes.
+//
+// Resources that conflict with existing resources, and deletions that would be
+// blocked, are reported with the plan.
+func (r *GitOpsSetReconciler) reconcileDryRun(ctx context.Context, req ctrl.Request, k8sClient client.Client, gitOpsSet *templatesv1.GitOpsSet) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	plan, requeue, err := r.planDryRun(ctx, k8sClient, gitOpsSet)
+	if err != nil && !isPlanError(err) {
+		templatesv1.SetGitOpsSetReadiness(gitOpsSet, nil, metav1.ConditionFalse, templatesv1.ReconciliationFailedReason, err.Error())
+		if err := r.patchStatus(ctx, req, gitOpsSet.Status); err != nil {
+			logger.Error(err, "failed to reconcile")
+		}
+
+		return ctrl.Result{}, err
+	}
+
+	summary := summarizePlan(plan)
+	if err != nil {
+		summary += ": " + err.Error()
+	}
+	gitOpsSet.Status.Plan = plan
+	templatesv1.SetGitOpsSetReadiness(gitOpsSet, nil, metav1.ConditionUnknown, templatesv1.DryRunReason, summary)
+	if err := r.patchStatus(ctx, req, gitOpsSet.Status); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to update status with the plan: %w", err)
+	}
+
+	if r.EventRecorder != nil {
+		r.event(gitOpsSet, eventv1.EventSeverityInfo, summary)
+	}
+
+	return ctrl.Result{RequeueAfter: requeue}, nil
+}
+
+func (r *GitOpsSetReconciler) planDryRun(ctx context.Context, k8sClient client.Client, gitOpsSet *templatesv1.GitOpsSet) ([]templatesv1.PlannedChange, time.Duration, error) {
+	logger := log.FromContext(ctx)
+	if r.resourceWatches != nil {
+		if err := r.resourceWatches.watchKinds(gitOpsSet, r.kubernetesResourceToGitOpsSet); err != nil {
+			return nil, generators.NoRequeueInterval, err
+		}
+	}
+
+	instantiatedGenerators := r.instantiateGenerators(ctx, k8sClient)
+	plan, planErr := r.planResources(ctx, logger, k8sClient, gitOpsSet, instantiatedGenerators)
+	if planErr != nil && !isPlanError(planErr) {
+		return nil, generators.NoRequeueInterval, planErr
+	}
+
+	requeueAfter, err := calculateInterval(gitOpsSet, instantiatedGenerators)
+	if err != nil {
+		return nil, generators.NoRequeueInterval, fmt.Errorf("failed to calculate requeue interval: %w", err)
+	}
+
+	return plan, requeueAfter, planErr
+}
+
+// instantiateGenerators creates the generators for a reconciliation.
+//
+// The KubernetesResources generator queries resources with the client for the
+// GitOpsSet's service account, so that it can only read the resources that
+// the service account can read, including when it's nested in the Matrix and
+// Merge generators.
+func (r *GitOpsSetReconciler) instantiateGenerators(ctx context.Context, k8sClient client.Client) map[string]generators.Generator {
+	c := &generators.ServiceAccountReader{Reader: r.Client, ServiceAccount: k8sClient}
+	instantiatedGenerators := map[string]generators.Generator{}
+	for k, factory := range r.Generators {
+		instantiatedGenerators[k] = factory(log.FromContext(ctx), generators.ReaderFor(k, c))
+	}
+
+	return instantiatedGenerators
+}
+
+// resolveReferences returns a copy of the GitOpsSet with the templates and
+// values that are referenced from other resources loaded.
+func (r *GitOpsSetReconciler) resolveReferences(ctx context.Context, gitOpsSet *templatesv1.GitOpsSet) (*templatesv1.GitOpsSet, error) {
+	resolved, err := r.resolveTemplateRefs(ctx, r.Client, gitOpsSet)
+	if err != nil {
+		return nil, err
+	}
+
+	return resolveValues(ctx, r.Client, resolved)
+}
+
 func (r *GitOpsSetReconciler) renderAndReconcile(ctx context.Context, logger logr.Logger, k8sClient client.Client, gitOpsSet *templatesv1.GitOpsSet, instantiatedGenerators map[string]generators.Generator) (*templatesv1.ResourceInventory, error) {
-	resources, err := templates.Render(ctx, gitOpsSet, instantiatedGenerators)
+	if err := validateElementKey(gitOpsSet); err != nil {
+		return nil, err
+	}
+
+	renderable, err := r.resolveReferences(ctx, gitOpsSet)
+	if err != nil {
+		return nil, err
+	}
+
+	elements, generatorErr := templates.RenderElements(ctx, renderable, instantiatedGenerators)
+	if generatorErr != nil && gitOpsSet.Spec.ErrorPolicy != templatesv1.ErrorPolicyContinue {
+		return nil, generatorErr
+	}
+
+	resourceCount := 0
+	for _, element := range elements {
+		resourceCount += len(element.Resources)
+	}
+	logger.Info("rendered templates", "elementCount", len(elements), "resourceCount", resourceCount)
+
+	if err := publishElements(gitOpsSet, elements, generatorErr); err != nil {
+		return nil, err
+	}
+
+	gitOpsSet.Status.Rollout = nil
+	rolledOut := make([]bool, len(elements))
+	for i := range rolledOut {
+		rolledOut[i] = true
+	}
+	updated := len(elements)
+	if gitOpsSet.Spec.Rollout != nil {
+		var err error
+		rolledOut, err = r.reconcileRollout(ctx, k8sClient, gitOpsSet, elements)
+		if err != nil {
+			return nil, err
+		}
+		updated = gitOpsSet.Status.Rollout.UpdatedElements
+		logger.Info("rolling out elements", "wave", gitOpsSet.Status.Rollout.CurrentWave, "elementCount", updated)
+	}
+
+	keys, err := elementKeys(gitOpsSet, elements)
 	if err != nil {
 		return nil, err
 	}
-	logger.Info("rendered templates", "resourceCount", len(resources))
 
 	var inventoryErr error
 
@@ -241,53 +425,97 @@ func (r *GitOpsSetReconciler) renderAndReconcile(ctx context.Context, logger log
 		existingEntries.Insert(gitOpsSet.Status.Inventory.Entries...)
 	}
 
+	previousElements := map[string]templatesv1.ElementStatus{}
+	for _, element := range gitOpsSet.Status.Elements {
+		previousElements[element.Key] = element
+	}
+
+	var elementStatuses []templatesv1.ElementStatus
+	var elementErrs []error
+	applied := 0
 	entries := sets.New[templatesv1.ResourceRef]()
-	for _, newResource := range resources {
-		ref, err := templatesv1.ResourceRefFromObject(newResource)
-		if err != nil {
-			inventoryErr = errors.Join(inventoryErr, fmt.Errorf("failed to update inventory: %w", err))
+	// The resources previously generated for the elements that rendered, only
+	// these can be pruned when any of the elements fail to render.
+	renderedEntries := sets.New[templatesv1.ResourceRef]()
+	renderFailed := false
+	for i, element := range elements {
+		previous, hasPrevious := previousElements[keys[i]]
+		if !rolledOut[i] {
+			// Elements that haven't been rolled out yet keep their previous
+			// status.
+			if hasPrevious {
+				elementStatuses = append(elementStatuses, previous)
+			}
 			continue
 		}
 
-		if existingEntries.Has(ref) {
-			existing, err := unstructuredFromResourceRef(ref)
-			if err != nil {
-				inventoryErr = errors.Join(inventoryErr, fmt.Errorf("failed to convert resource for update: %w", err))
-				continue
-			}
-			// We can add the entry because we know it exists
-			entries.Insert(ref)
-			err = k8sClient.Get(ctx, client.ObjectKeyFromObject(newResource), existing)
-			if err == nil {
-				newResource = copyUnstructuredContent(existing, newResource)
-				if err := k8sClient.Patch(ctx, newResource, client.MergeFrom(existing)); err != nil {
-					inventoryErr = errors.Join(inventoryErr, fmt.Errorf("failed to update Resource: %w", err))
+		if element.Err != nil {
+			renderFailed = true
+			// Elements that failed to render keep their previous resources.
+			for _, ref := range previous.Resources {
+				if existingEntries.Has(ref) {
+					entries.Insert(ref)
 				}
-				continue
 			}
+			elementStatuses = append(elementStatuses, templatesv1.ElementStatus{
+				Key:       keys[i],
+				Resources: previous.Resources,
+				LastError: element.Err.Error(),
+			})
+			elementErrs = append(elementErrs, element.Err)
+			continue
+		}
 
-			if !apierrors.IsNotFound(err) {
-				inventoryErr = errors.Join(inventoryErr, fmt.Errorf("failed to load existing Resource: %w", err))
+		renderedEntries.Insert(previous.Resources...)
+		elementStatus := templatesv1.ElementStatus{Key: keys[i]}
+		var elementErr error
+		for _, newResource := range element.Resources {
+			ref, err := templatesv1.ResourceRefFromObject(newResource)
+			if err != nil {
+				elementErr = errors.Join(elementErr, fmt.Errorf("failed to update inventory: %w", err))
 				continue
 			}
+			elementStatus.Resources = append(elementStatus.Resources, ref)
+
+			resourceApplied, err := r.reconcileResource(ctx, logger, k8sClient, gitOpsSet, newResource, existingEntries.Has(ref))
+			if resourceApplied {
+				entries.Insert(ref)
+			}
+			elementErr = errors.Join(elementErr, err)
 		}
 
-		if err := logResourceMessage(logger, "creating new resource", newResource); err != nil {
-			inventoryErr = errors.Join(inventoryErr, err)
-			continue
+		elementStatus.Ready = elementErr == nil
+		if elementErr != nil {
+			elementStatus.LastError = elementErr.Error()
+			elementErrs = append(elementErrs, elementErr)
+		} else {
+			applied++
+			revision, err := elementRevision(element)
+			if err != nil {
+				return nil, err
+			}
+			elementStatus.Revision = revision
 		}
+		elementStatuses = append(elementStatuses, elementStatus)
+	}
 
-		if err := k8sClient.Create(ctx, newResource); err != nil {
-			inventoryErr = errors.Join(inventoryErr, fmt.Errorf("failed to create Resource: %w", err))
-			if apierrors.IsAlreadyExists(err) {
-				if err := logResourceMessage(logger, "resource already exists", newResource); err != nil {
-					inventoryErr = errors.Join(inventoryErr, err)
-				}
+	if generatorErr != nil {
+		// Elements that are no longer generated may have come from the
+		// generators that failed, so they keep their previous status.
+		generatedKeys := sets.New(keys...)
+		for _, previous := range gitOpsSet.Status.Elements {
+			if !generatedKeys.Has(previous.Key) {
+				previous.Ready = false
+				previous.LastError = generatorErr.Error()
+				elementStatuses = append(elementStatuses, previous)
 			}
-			continue
 		}
+		elementErrs = append(elementErrs, generatorErr)
+	}
+	gitOpsSet.Status.Elements = elementStatuses
 
-		entries.Insert(ref)
+	if len(elementErrs) > 0 {
+		inventoryErr = ElementsFailedError{Applied: applied, Total: updated, Errs: elementErrs}
 	}
 
 	if gitOpsSet.Status.Inventory == nil {
@@ -296,9 +524,32 @@ func (r *GitOpsSetReconciler) renderAndReconcile(ctx context.Context, logger log
 		})}, inventoryErr
 
 	}
-	objectsToRemove := existingEntries.Difference(entries)
-	if err := r.removeResourceRefs(ctx, k8sClient, objectsToRemove.List()); err != nil {
-		inventoryErr = errors.Join(inventoryErr, err)
+	if (gitOpsSet.Status.Rollout != nil && !gitOpsSet.Status.Rollout.Complete()) || generatorErr != nil {
+		// The resources for the elements that haven't been rolled out yet, or
+		// that may have come from generators that failed, are kept, and
+		// nothing is pruned until the rollout is complete, and the generators
+		// succeed.
+		entries.Insert(existingEntries.List()...)
+	} else if gitOpsSet.Spec.Prune == nil || *gitOpsSet.Spec.Prune {
+		if renderFailed {
+			// Resources that aren't known to belong to an element that
+			// rendered may belong to the elements that failed, so they are
+			// kept.
+			for _, ref := range existingEntries.Difference(entries).List() {
+				if !renderedEntries.Has(ref) {
+					entries.Insert(ref)
+				}
+			}
+		}
+		objectsToRemove := existingEntries.Difference(entries).List()
+		if err := checkDeletions(gitOpsSet, len(objectsToRemove), len(existingEntries.List())); err != nil {
+			// The resources are kept in the inventory until the deletions are
+			// approved.
+			entries.Insert(objectsToRemove...)
+			inventoryErr = errors.Join(inventoryErr, err)
+		} else if err := r.removeResourceRefs(ctx, k8sClient, objectsToRemove); err != nil {
+			inventoryErr = errors.Join(inventoryErr, err)
+		}
 	}
 
 	return &templatesv1.ResourceInventory{Entries: entries.SortedList(func(x, y templatesv1.ResourceRef) bool {
@@ -306,6 +557,87 @@ func (r *GitOpsSetReconciler) renderAndReconcile(ctx context.Context, logger log
 	})}, inventoryErr
 }
 
+// reconcileResource creates or updates a generated resource.
+//
+// If the resource exists in the cluster, it's recorded in the inventory, and
+// true is returned, even if updating it fails.
+func (r *GitOpsSetReconciler) reconcileResource(ctx context.Context, logger logr.Logger, k8sClient client.Client, gitOpsSet *templatesv1.GitOpsSet, newResource *unstructured.Unstructured, inInventory bool) (bool, error) {
+	if inInventory && r.serverSideApply(gitOpsSet) {
+		// We can add the entry because we know it was created.
+		drift, err := applyResource(ctx, k8sClient, gitOpsSet, newResource, inventoryFieldManagers)
+		if err != nil {
+			return true, err
+		}
+		if drift != "" {
+			r.recordDrift(k8sClient, gitOpsSet, newResource, drift)
+		}
+		return true, nil
+	}
+
+	if inInventory {
+		existing := &unstructured.Unstructured{}
+		existing.SetGroupVersionKind(newResource.GroupVersionKind())
+		// We can add the entry because we know it exists
+		err := k8sClient.Get(ctx, client.ObjectKeyFromObject(newResource), existing)
+		if err == nil {
+			newResource = copyUnstructuredContent(existing, newResource)
+			if equality.Semantic.DeepEqual(existing, newResource) {
+				return true, nil
+			}
+
+			if err := k8sClient.Patch(ctx, newResource, client.MergeFrom(existing), client.FieldOwner(fieldManager)); err != nil {
+				return true, fmt.Errorf("failed to update Resource: %w", err)
+			}
+
+			if modifiedByOtherManager(existing) {
+				r.recordDrift(k8sClient, gitOpsSet, newResource, driftModified)
+			}
+			return true, nil
+		}
+
+		if !apierrors.IsNotFound(err) {
+			return true, fmt.Errorf("failed to load existing Resource: %w", err)
+		}
+	}
+
+	if err := logResourceMessage(logger, "creating new resource", newResource); err != nil {
+		return false, err
+	}
+
+	if err := r.createResource(ctx, k8sClient, gitOpsSet, newResource); err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			return false, fmt.Errorf("failed to create Resource: %w", err)
+		}
+
+		adopted, adoptErr := r.adoptResource(ctx, k8sClient, gitOpsSet, newResource)
+		if adoptErr != nil {
+			if errors.As(adoptErr, &AdoptionRefusedError{}) {
+				return false, errors.Join(adoptErr, recordConflict(k8sClient, newResource, adoptErr))
+			}
+			return false, adoptErr
+		}
+
+		if !adopted {
+			createErr := fmt.Errorf("failed to create Resource: %w", err)
+			if err := recordConflict(k8sClient, newResource, createErr); err != nil {
+				return false, errors.Join(createErr, err)
+			}
+			if err := logResourceMessage(logger, "resource already exists", newResource); err != nil {
+				return false, errors.Join(createErr, err)
+			}
+			return false, createErr
+		}
+
+		return true, logResourceMessage(logger, "adopted existing resource", newResource)
+	}
+
+	if inInventory {
+		r.recordDrift(k8sClient, gitOpsSet, newResource, driftDeleted)
+	}
+
+	return true, nil
+}
+
 func (r *GitOpsSetReconciler) patchStatus(ctx context.Context, req ctrl.Request, newStatus templatesv1.GitOpsSetStatus) error {
 	var set templatesv1.GitOpsSet
 	if err := r.Get(ctx, req.NamespacedName, &set); err != nil {
@@ -325,6 +657,23 @@ func (r *GitOpsSetReconciler) removeResourceRefs(ctx context.Context, k8sClient
 		if err != nil {
 			return err
 		}
+
+		existing := &unstructured.Unstructured{}
+		existing.SetGroupVersionKind(u.GroupVersionKind())
+		if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(u), existing); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("failed to load %v: %w", u, err)
+		}
+
+		if existing.GetAnnotations()[templatesv1.PruneAnnotation] == templatesv1.PruneDisabledValue {
+			if err := logResourceMessage(logger, "skipping deletion of resource with pruning disabled", u); err != nil {
+				return err
+			}
+			continue
+		}
+
 		if err := logResourceMessage(logger, "deleting resource", u); err != nil {
 			return err
 		}
@@ -355,14 +704,9 @@ func (r *GitOpsSetReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		return fmt.Errorf("failed setting index field for Secret: %w", err)
 	}
 
-	if err := mgr.GetCache().IndexField(
-		context.TODO(), &templatesv1.GitOpsSet{}, ociRepositoryIndexKey, indexOCIRepositories); err != nil {
-		return fmt.Errorf("failed setting index field for OCIRepository: %w", err)
-	}
-
-	builder := ctrl.NewControllerManagedBy(mgr).
+	b := ctrl.NewControllerManagedBy(mgr).
 		For(&templatesv1.GitOpsSet{}, builder.WithPredicates(
-			predicate.Or(predicate.GenerationChangedPredicate{}, predicates.ReconcileRequestedPredicate{}))).
+			predicate.Or(predicate.GenerationChangedPredicate{}, predicates.ReconcileRequestedPredicate{}, deletionApprovalPredicate))).
 		Watches(
 			&sourcev1.GitRepository{},
 			handler.EnqueueRequestsFromMapFunc(r.gitRepositoryToGitOpsSet),
@@ -370,25 +714,47 @@ func (r *GitOpsSetReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Watches(
 			&corev1.Secret{},
 			handler.EnqueueRequestsFromMapFunc(r.secretToGitOpsSet),
-		)
-
-	if r.Generators["Config"] != nil {
-		builder.Watches(
+		).
+		// ConfigMaps are watched even if the Config generator is disabled,
+		// because templates and values can be referenced from ConfigMaps.
+		Watches(
 			&corev1.ConfigMap{},
 			handler.EnqueueRequestsFromMapFunc(r.configMapToGitOpsSet),
 		)
-	}
 
-	if r.Generators["OCIRepository"] != nil {
-		builder.Watches(
+	// Only watch for OCIRepository objects if the OCIRepository generator is
+	// enabled, or templates can be loaded from OCIRepositories, the
+	// OCIRepository CRD may not be installed otherwise.
+	if r.ociRepositoriesEnabled() {
+		// Index the GitOpsSets by the OCIRepository references they (may) point at.
+		if err := mgr.GetCache().IndexField(
+			context.TODO(), &templatesv1.GitOpsSet{}, ociRepositoryIndexKey, indexOCIRepositories); err != nil {
+			return fmt.Errorf("failed setting index field for OCIRepository: %w", err)
+		}
+
+		b.Watches(
 			&sourcev1beta2.OCIRepository{},
 			handler.EnqueueRequestsFromMapFunc(r.ociRepositoryToGitOpsSet),
 		)
 	}
 
+	// Only watch for Bucket objects if the Bucket generator is enabled.
+	if r.Generators["Bucket"] != nil {
+		// Index the GitOpsSets by the Bucket references they (may) point at.
+		if err := mgr.GetCache().IndexField(
+			context.TODO(), &templatesv1.GitOpsSet{}, bucketIndexKey, indexBuckets); err != nil {
+			return fmt.Errorf("failed setting index field for Bucket: %w", err)
+		}
+
+		b.Watches(
+			&sourcev1.Bucket{},
+			handler.EnqueueRequestsFromMapFunc(r.bucketToGitOpsSet),
+		)
+	}
+
 	// Only watch for GitopsCluster objects if the Cluster generator is enabled.
 	if r.Generators["Cluster"] != nil {
-		builder.Watches(
+		b.Watches(
 			&clustersv1.GitopsCluster{},
 			handler.EnqueueRequestsFromMapFunc(r.gitOpsClusterToGitOpsSet),
 		)
@@ -402,13 +768,47 @@ func (r *GitOpsSetReconciler) SetupWithManager(mgr ctrl.Manager) error {
 			return fmt.Errorf("failed setting index fields: %w", err)
 		}
 
-		builder.Watches(
+		b.Watches(
 			&imagev1.ImagePolicy{},
 			handler.EnqueueRequestsFromMapFunc(r.imagePolicyToGitOpsSet),
 		)
 	}
 
-	return builder.Complete(r)
+	// Only watch for GitOpsSets publishing elements if the GitOpsSet generator
+	// is enabled.
+	if r.Generators["GitOpsSet"] != nil {
+		// Index the GitOpsSets by the GitOpsSet references they (may) point at.
+		if err := mgr.GetCache().IndexField(
+			context.TODO(), &templatesv1.GitOpsSet{}, gitOpsSetIndexKey, indexGitOpsSets); err != nil {
+			return fmt.Errorf("failed setting index field for GitOpsSet: %w", err)
+		}
+
+		b.Watches(
+			&templatesv1.GitOpsSet{},
+			handler.EnqueueRequestsFromMapFunc(r.gitOpsSetToGitOpsSet),
+			builder.WithPredicates(publishedElementsPredicate),
+		)
+	}
+
+	c, err := b.Build(r)
+	if err != nil {
+		return err
+	}
+
+	// The Kinds queried by KubernetesResources generators are watched as
+	// GitOpsSets are reconciled.
+	if r.Generators["KubernetesResources"] != nil {
+		r.resourceWatches = newResourceWatches(c, mgr.GetConfig(), mgr.GetScheme(), mgr.GetRESTMapper())
+	}
+
+	return nil
+}
+
+// ociRepositoriesEnabled returns true if GitOpsSets can reference
+// OCIRepositories, either from the OCIRepository generator or from
+// templateRefs.
+func (r *GitOpsSetReconciler) ociRepositoriesEnabled() bool {
+	return r.Generators["OCIRepository"] != nil || r.OCIRepositoryTemplates
 }
 
 // gitOpsClusterToGitOpsSet maps a GitopsCluster object to its related GitOpsSet objects
@@ -441,6 +841,7 @@ func (r *GitOpsSetReconciler) finalize(ctx context.Context, gs *templatesv1.GitO
 	logger.Info("finalizing resources")
 
 	if !gs.Spec.Suspend &&
+		gs.Spec.DeletionPolicy != templatesv1.DeletionPolicyOrphan &&
 		gs.Status.Inventory != nil &&
 		gs.Status.Inventory.Entries != nil {
 
@@ -451,6 +852,10 @@ func (r *GitOpsSetReconciler) finalize(ctx context.Context, gs *templatesv1.GitO
 		logger.Info("cleaned resources")
 	}
 
+	if r.resourceWatches != nil {
+		r.resourceWatches.release(client.ObjectKeyFromObject(gs))
+	}
+
 	logger.Info("removing the finalizer")
 	// Remove our finalizer from the list and update it
 	controllerutil.RemoveFinalizer(gs, templatesv1.GitOpsSetFinalizer)
@@ -476,17 +881,51 @@ func getClusterSelectors(generator templatesv1.GitOpsSetGenerator) []metav1.Labe
 		selectors = append(selectors, generator.Cluster.Selector)
 	}
 
-	if generator.Matrix != nil && generator.Matrix.Generators != nil {
-		for _, matrixGenerator := range generator.Matrix.Generators {
-			if matrixGenerator.Cluster != nil {
-				selectors = append(selectors, matrixGenerator.Cluster.Selector)
-			}
+	for _, nestedGenerator := range nestedGenerators(generator) {
+		if nestedGenerator.Cluster != nil {
+			selectors = append(selectors, nestedGenerator.Cluster.Selector)
 		}
 	}
 
 	return selectors
 }
 
+// nestedGenerators returns the generators nested in the Matrix and Merge
+// generators, including the generators nested in nested Matrix and Merge
+// generators.
+//
+// Nested generators that can't be parsed are ignored, the error is reported
+// when generating.
+func nestedGenerators(generator templatesv1.GitOpsSetGenerator) []templatesv1.GitOpsSetNestedGenerator {
+	var nested []templatesv1.GitOpsSetNestedGenerator
+	if generator.Matrix != nil {
+		nested = append(nested, generator.Matrix.Generators...)
+	}
+
+	if generator.Merge != nil {
+		nested = append(nested, generator.Merge.Generators...)
+	}
+
+	return appendNestedGenerators(nil, nested, 1)
+}
+
+func appendNestedGenerators(result, nested []templatesv1.GitOpsSetNestedGenerator, depth int) []templatesv1.GitOpsSetNestedGenerator {
+	for _, generator := range nested {
+		result = append(result, generator)
+		if depth >= templatesv1.MaxNestingDepth {
+			continue
+		}
+
+		children, err := generator.NestedGenerators()
+		if err != nil {
+			continue
+		}
+		result = appendNestedGenerators(result, children, depth+1)
+	}
+
+	return result
+}
+
 func selectorMatchesCluster(labelSelector metav1.LabelSelector, cluster *clustersv1.GitopsCluster) bool {
 	selector, err := metav1.LabelSelectorAsSelector(&labelSelector)
 	if err != nil {
@@ -515,10 +954,35 @@ func (r *GitOpsSetReconciler) ociRepositoryToGitOpsSet(ctx context.Context, obj
 	return r.queryIndexedGitOpsSets(ctx, ociRepositoryIndexKey, obj)
 }
 
+func (r *GitOpsSetReconciler) bucketToGitOpsSet(ctx context.Context, obj client.Object) []reconcile.Request {
+	return r.queryIndexedGitOpsSets(ctx, bucketIndexKey, obj)
+}
+
 func (r *GitOpsSetReconciler) imagePolicyToGitOpsSet(ctx context.Context, obj client.Object) []reconcile.Request {
 	return r.queryIndexedGitOpsSets(ctx, imagePolicyIndexKey, obj)
 }
 
+func (r *GitOpsSetReconciler) gitOpsSetToGitOpsSet(ctx context.Context, obj client.Object) []reconcile.Request {
+	return r.queryIndexedGitOpsSets(ctx, gitOpsSetIndexKey, obj)
+}
+
+// publishedElementsPredicate triggers reconciliation of the GitOpsSets that
+// use the elements of a GitOpsSet when the published elements are changed.
+var publishedElementsPredicate = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		oldSet, ok := e.ObjectOld.(*templatesv1.GitOpsSet)
+		if !ok {
+			return false
+		}
+		newSet, ok := e.ObjectNew.(*templatesv1.GitOpsSet)
+		if !ok {
+			return false
+		}
+
+		return !equality.Semantic.DeepEqual(oldSet.Status.PublishedElements, newSet.Status.PublishedElements)
+	},
+}
+
 func (r *GitOpsSetReconciler) queryIndexedGitOpsSets(ctx context.Context, key string, obj client.Object) []reconcile.Request {
 	var list templatesv1.GitOpsSetList
 
@@ -565,24 +1029,22 @@ func indexGitRepositories(o client.Object) []string {
 		if gen.GitRepository != nil {
 			referencedRepositories = append(referencedRepositories, gen.GitRepository)
 		}
-		if gen.Matrix != nil && gen.Matrix.Generators != nil {
-			for _, matrixGen := range gen.Matrix.Generators {
-				if matrixGen.GitRepository != nil {
-					referencedRepositories = append(referencedRepositories, matrixGen.GitRepository)
-				}
+		for _, nestedGen := range nestedGenerators(gen) {
+			if nestedGen.GitRepository != nil {
+				referencedRepositories = append(referencedRepositories, nestedGen.GitRepository)
 			}
 		}
 	}
 
-	if len(referencedRepositories) == 0 {
-		return nil
-	}
-
-	referencedNames := []string{}
+	referencedNames := templateRefNames(ks, "GitRepository")
 	for _, grg := range referencedRepositories {
 		referencedNames = append(referencedNames, fmt.Sprintf("%s/%s", ks.GetNamespace(), grg.RepositoryRef))
 	}
 
+	if len(referencedNames) == 0 {
+		return nil
+	}
+
 	return referencedNames
 }
 
@@ -597,22 +1059,50 @@ func indexOCIRepositories(o client.Object) []string {
 		if gen.OCIRepository != nil {
 			referencedRepositories = append(referencedRepositories, gen.OCIRepository)
 		}
-		if gen.Matrix != nil && gen.Matrix.Generators != nil {
-			for _, matrixGen := range gen.Matrix.Generators {
-				if matrixGen.OCIRepository != nil {
-					referencedRepositories = append(referencedRepositories, matrixGen.OCIRepository)
-				}
+		for _, nestedGen := range nestedGenerators(gen) {
+			if nestedGen.OCIRepository != nil {
+				referencedRepositories = append(referencedRepositories, nestedGen.OCIRepository)
+			}
+		}
+	}
+
+	referencedNames := templateRefNames(ks, "OCIRepository")
+	for _, org := range referencedRepositories {
+		referencedNames = append(referencedNames, fmt.Sprintf("%s/%s", ks.GetNamespace(), org.RepositoryRef))
+	}
+
+	if len(referencedNames) == 0 {
+		return nil
+	}
+
+	return referencedNames
+}
+
+func indexBuckets(o client.Object) []string {
+	ks, ok := o.(*templatesv1.GitOpsSet)
+	if !ok {
+		panic(fmt.Sprintf("Expected a GitOpsSet, got %T", o))
+	}
+
+	referencedBuckets := []*templatesv1.BucketGenerator{}
+	for _, gen := range ks.Spec.Generators {
+		if gen.Bucket != nil {
+			referencedBuckets = append(referencedBuckets, gen.Bucket)
+		}
+		for _, nestedGen := range nestedGenerators(gen) {
+			if nestedGen.Bucket != nil {
+				referencedBuckets = append(referencedBuckets, nestedGen.Bucket)
 			}
 		}
 	}
 
-	if len(referencedRepositories) == 0 {
+	if len(referencedBuckets) == 0 {
 		return nil
 	}
 
 	referencedNames := []string{}
-	for _, org := range referencedRepositories {
-		referencedNames = append(referencedNames, fmt.Sprintf("%s/%s", ks.GetNamespace(), org.RepositoryRef))
+	for _, bg := range referencedBuckets {
+		referencedNames = append(referencedNames, fmt.Sprintf("%s/%s", ks.GetNamespace(), bg.BucketRef))
 	}
 
 	return referencedNames
@@ -630,28 +1120,52 @@ func indexConfig(kind string) func(o client.Object) []string {
 			if gen.Config != nil && gen.Config.Kind == kind {
 				referencedResources = append(referencedResources, gen.Config)
 			}
-			if gen.Matrix != nil && gen.Matrix.Generators != nil {
-				for _, matrixGen := range gen.Matrix.Generators {
-					if matrixGen.Config != nil && matrixGen.Config.Kind == kind {
-						referencedResources = append(referencedResources, matrixGen.Config)
-					}
+			for _, nestedGen := range nestedGenerators(gen) {
+				if nestedGen.Config != nil && nestedGen.Config.Kind == kind {
+					referencedResources = append(referencedResources, nestedGen.Config)
 				}
 			}
 		}
 
-		if len(referencedResources) == 0 {
-			return nil
-		}
-
-		referencedNames := []string{}
+		referencedNames := append(templateRefNames(ks, kind), valuesFromNames(ks, kind)...)
 		for _, grg := range referencedResources {
 			referencedNames = append(referencedNames, fmt.Sprintf("%s/%s", ks.GetNamespace(), grg.Name))
 		}
 
+		if len(referencedNames) == 0 {
+			return nil
+		}
+
 		return referencedNames
 	}
 }
 
+// templateRefNames returns the names of the resources of the kind that
+// templates are referenced from.
+func templateRefNames(gs *templatesv1.GitOpsSet, kind string) []string {
+	names := []string{}
+	for _, tmpl := range gs.Spec.Templates {
+		if tmpl.TemplateRef != nil && tmpl.TemplateRef.Kind == kind {
+			names = append(names, fmt.Sprintf("%s/%s", gs.GetNamespace(), tmpl.TemplateRef.Name))
+		}
+	}
+
+	return names
+}
+
+// valuesFromNames returns the names of the resources of the kind that values
+// are loaded from.
+func valuesFromNames(gs *templatesv1.GitOpsSet, kind string) []string {
+	names := []string{}
+	for _, ref := range gs.Spec.ValuesFrom {
+		if ref.Kind == kind {
+			names = append(names, fmt.Sprintf("%s/%s", gs.GetNamespace(), ref.Name))
+		}
+	}
+
+	return names
+}
+
 func indexImagePolicies(o client.Object) []string {
 	ks, ok := o.(*templatesv1.GitOpsSet)
 	if !ok {
@@ -664,11 +1178,9 @@ func indexImagePolicies(o client.Object) []string {
 			referencedPolicies = append(referencedPolicies, gen.ImagePolicy)
 			continue
 		}
-		if gen.Matrix != nil && gen.Matrix.Generators != nil {
-			for _, matrixGen := range gen.Matrix.Generators {
-				if matrixGen.ImagePolicy != nil {
-					referencedPolicies = append(referencedPolicies, matrixGen.ImagePolicy)
-				}
+		for _, nestedGen := range nestedGenerators(gen) {
+			if nestedGen.ImagePolicy != nil {
+				referencedPolicies = append(referencedPolicies, nestedGen.ImagePolicy)
 			}
 		}
 	}
@@ -685,6 +1197,36 @@ func indexImagePolicies(o client.Object) []string {
 	return referencedNames
 }
 
+func indexGitOpsSets(o client.Object) []string {
+	ks, ok := o.(*templatesv1.GitOpsSet)
+	if !ok {
+		panic(fmt.Sprintf("Expected a GitOpsSet, got %T", o))
+	}
+
+	referencedSets := []*templatesv1.GitOpsSetRefGenerator{}
+	for _, gen := range ks.Spec.Generators {
+		if gen.GitOpsSet != nil {
+			referencedSets = append(referencedSets, gen.GitOpsSet)
+		}
+		for _, nestedGen := range nestedGenerators(gen) {
+			if nestedGen.GitOpsSet != nil {
+				referencedSets = append(referencedSets, nestedGen.GitOpsSet)
+			}
+		}
+	}
+
+	if len(referencedSets) == 0 {
+		return nil
+	}
+
+	referencedNames := []string{}
+	for _, gs := range referencedSets {
+		referencedNames = append(referencedNames, fmt.Sprintf("%s/%s", ks.GetNamespace(), gs.GitOpsSetRef))
+	}
+
+	return referencedNames
+}
+
 func unstructuredFromResourceRef(ref templatesv1.ResourceRef) (*unstructured.Unstructured, error) {
 	objMeta, err := object.ParseObjMetadata(ref.ID)
 	if err != nil {
@@ -737,6 +1279,10 @@ func logResourceMessage(logger logr.Logger, msg string, obj runtime.Object) erro
 
 func calculateInterval(gs *templatesv1.GitOpsSet, configuredGenerators map[string]generators.Generator) (time.Duration, error) {
 	res := []time.Duration{}
+	if gs.Spec.Interval != nil && gs.Spec.Interval.Duration > generators.NoRequeueInterval {
+		res = append(res, gs.Spec.Interval.Duration)
+	}
+
 	for _, mg := range gs.Spec.Generators {
 		relevantGenerators, err := generators.FindRelevantGenerators(mg, configuredGenerators)
 		if err != nil {
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"path/filepath"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -25,7 +26,9 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -155,6 +158,194 @@ func TestReconciliation(t *testing.T) {
 		assertGitOpsSetCondition(t, gs, meta.ReadyCondition, "3 resources created")
 	})
 
+	t.Run("reconciling removal of resources with pruning disabled", func(t *testing.T) {
+		ctx := context.TODO()
+		gs := makeTestGitOpsSet(t, func(gs *templatesv1.GitOpsSet) {
+			gs.Spec.Prune = ptr.To(false)
+			gs.Spec.Generators = []templatesv1.GitOpsSetGenerator{
+				{
+					List: &templatesv1.ListGenerator{
+						Elements: []apiextensionsv1.JSON{
+							{Raw: []byte(`{"cluster": "engineering-prod"}`)},
+							{Raw: []byte(`{"cluster": "engineering-preprod"}`)},
+						},
+					},
+				},
+			}
+		})
+		gs = createAndReconcileToFinalizedState(t, k8sClient, reconciler, gs)
+		defer deleteAllKustomizations(t, k8sClient)
+		defer deleteGitOpsSetAndFinalize(t, k8sClient, reconciler, gs)
+		// Initial creation of resources
+		_, err = reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(gs)})
+		test.AssertNoError(t, err)
+
+		test.AssertNoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(gs), gs))
+		gs.Spec.Generators = []templatesv1.GitOpsSetGenerator{
+			{
+				List: &templatesv1.ListGenerator{
+					Elements: []apiextensionsv1.JSON{
+						{Raw: []byte(`{"cluster": "engineering-prod"}`)},
+					},
+				},
+			},
+		}
+		test.AssertNoError(t, k8sClient.Update(ctx, gs))
+
+		// Updated set of resources.
+		_, err = reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(gs)})
+		test.AssertNoError(t, err)
+
+		test.AssertNoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(gs), gs))
+		want := []runtime.Object{
+			test.MakeTestKustomization(nsn("default", "engineering-prod-demo")),
+		}
+		test.AssertInventoryHasItems(t, gs, want...)
+		assertKustomizationsExist(t, k8sClient, "default", "engineering-prod-demo", "engineering-preprod-demo")
+	})
+
+	t.Run("reconciling removal of resources exceeding the maximum deletions", func(t *testing.T) {
+		ctx := context.TODO()
+		gs := makeTestGitOpsSet(t, func(gs *templatesv1.GitOpsSet) {
+			gs.Spec.MaxDeletions = ptr.To(intstr.FromInt32(1))
+		})
+		gs = createAndReconcileToFinalizedState(t, k8sClient, reconciler, gs)
+		defer deleteGitOpsSetAndFinalize(t, k8sClient, reconciler, gs)
+		// Initial creation of resources
+		_, err = reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(gs)})
+		test.AssertNoError(t, err)
+
+		test.AssertNoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(gs), gs))
+		gs.Spec.Generators = []templatesv1.GitOpsSetGenerator{
+			{
+				List: &templatesv1.ListGenerator{
+					Elements: []apiextensionsv1.JSON{
+						{Raw: []byte(`{"cluster": "engineering-dev"}`)},
+					},
+				},
+			},
+		}
+		test.AssertNoError(t, k8sClient.Update(ctx, gs))
+
+		// The deletions are blocked.
+		_, err = reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(gs)})
+		test.AssertNoError(t, err)
+
+		test.AssertNoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(gs), gs))
+		wantMsg := "2 resources would be deleted which exceeds the maximum of 1, set the sets.gitops.pro/approve-deletions annotation to approve the deletions"
+		assertGitOpsSetCondition(t, gs, meta.ReadyCondition, wantMsg)
+		assertGitOpsSetCondition(t, gs, meta.StalledCondition, wantMsg)
+		test.AssertInventoryHasItems(t, gs,
+			test.MakeTestKustomization(nsn("default", "engineering-dev-demo")),
+			test.MakeTestKustomization(nsn("default", "engineering-prod-demo")),
+			test.MakeTestKustomization(nsn("default", "engineering-preprod-demo")),
+		)
+		assertKustomizationsExist(t, k8sClient, "default", "engineering-dev-demo", "engineering-prod-demo", "engineering-preprod-demo")
+
+		// Approving the deletions.
+		gs.SetAnnotations(map[string]string{templatesv1.ApproveDeletionsAnnotation: "approval-1"})
+		test.AssertNoError(t, k8sClient.Update(ctx, gs))
+
+		_, err = reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(gs)})
+		test.AssertNoError(t, err)
+
+		test.AssertNoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(gs), gs))
+		assertGitOpsSetCondition(t, gs, meta.ReadyCondition, "1 resources created")
+		if cond := apimeta.FindStatusCondition(gs.Status.Conditions, meta.StalledCondition); cond != nil {
+			t.Errorf("got Stalled condition %v after approval", cond)
+		}
+		test.AssertInventoryHasItems(t, gs, test.MakeTestKustomization(nsn("default", "engineering-dev-demo")))
+		assertKustomizationsExist(t, k8sClient, "default", "engineering-dev-demo")
+	})
+
+	t.Run("reconciling blocked deletions when the generated elements recover", func(t *testing.T) {
+		ctx := context.TODO()
+		gs := makeTestGitOpsSet(t, func(gs *templatesv1.GitOpsSet) {
+			gs.Spec.MaxDeletions = ptr.To(intstr.FromInt32(1))
+			gs.Spec.Interval = &metav1.Duration{Duration: 5 * time.Minute}
+		})
+		gs = createAndReconcileToFinalizedState(t, k8sClient, reconciler, gs)
+		defer deleteGitOpsSetAndFinalize(t, k8sClient, reconciler, gs)
+		// Initial creation of resources
+		_, err = reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(gs)})
+		test.AssertNoError(t, err)
+
+		test.AssertNoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(gs), gs))
+		originalGenerators := gs.Spec.Generators
+		gs.Spec.Generators = []templatesv1.GitOpsSetGenerator{
+			{
+				List: &templatesv1.ListGenerator{
+					Elements: []apiextensionsv1.JSON{},
+				},
+			},
+		}
+		test.AssertNoError(t, k8sClient.Update(ctx, gs))
+
+		// The deletions are blocked, and the generators are polled again.
+		result, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(gs)})
+		test.AssertNoError(t, err)
+		if result.RequeueAfter != 5*time.Minute {
+			t.Fatalf("got RequeueAfter %v, want %v", result.RequeueAfter, 5*time.Minute)
+		}
+
+		test.AssertNoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(gs), gs))
+		assertGitOpsSetCondition(t, gs, meta.StalledCondition,
+			"3 resources would be deleted which exceeds the maximum of 1, set the sets.gitops.pro/approve-deletions annotation to approve the deletions")
+
+		// The generators recover without approving the deletions.
+		gs.Spec.Generators = originalGenerators
+		test.AssertNoError(t, k8sClient.Update(ctx, gs))
+
+		_, err = reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(gs)})
+		test.AssertNoError(t, err)
+
+		test.AssertNoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(gs), gs))
+		assertGitOpsSetCondition(t, gs, meta.ReadyCondition, "3 resources created")
+		if cond := apimeta.FindStatusCondition(gs.Status.Conditions, meta.StalledCondition); cond != nil {
+			t.Errorf("got Stalled condition %v after the generators recovered", cond)
+		}
+		assertKustomizationsExist(t, k8sClient, "default", "engineering-dev-demo", "engineering-prod-demo", "engineering-preprod-demo")
+	})
+
+	t.Run("reconciling removal of resources with the prune annotation", func(t *testing.T) {
+		ctx := context.TODO()
+		gs := makeTestGitOpsSet(t, func(gs *templatesv1.GitOpsSet) {
+			gs.Spec.Templates = []templatesv1.GitOpsSetTemplate{
+				{
+					Content: runtime.RawExtension{
+						Raw: mustMarshalJSON(t, test.MakeTestKustomization(nsn("default", "{{ .Element.cluster }}-demo"), func(ks *kustomizev1.Kustomization) {
+							ks.Annotations = map[string]string{
+								templatesv1.PruneAnnotation: templatesv1.PruneDisabledValue,
+							}
+						})),
+					},
+				},
+			}
+		})
+		gs = createAndReconcileToFinalizedState(t, k8sClient, reconciler, gs)
+		defer deleteAllKustomizations(t, k8sClient)
+		// Initial creation of resources
+		_, err = reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(gs)})
+		test.AssertNoError(t, err)
+
+		deleteGitOpsSetAndFinalize(t, k8sClient, reconciler, gs)
+		assertKustomizationsExist(t, k8sClient, "default", "engineering-dev-demo", "engineering-prod-demo", "engineering-preprod-demo")
+	})
+
+	t.Run("reconciling cleanup when deleted with the orphan deletion policy", func(t *testing.T) {
+		ctx := context.TODO()
+		gs := createAndReconcileToFinalizedState(t, k8sClient, reconciler, makeTestGitOpsSet(t, func(gs *templatesv1.GitOpsSet) {
+			gs.Spec.DeletionPolicy = templatesv1.DeletionPolicyOrphan
+		}))
+		defer deleteAllKustomizations(t, k8sClient)
+
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(gs)})
+		test.AssertNoError(t, err)
+
+		deleteGitOpsSetAndFinalize(t, k8sClient, reconciler, gs)
+		assertKustomizationsExist(t, k8sClient, "default", "engineering-dev-demo", "engineering-prod-demo", "engineering-preprod-demo")
+	})
+
 	t.Run("reconciling cleanup when deleted", func(t *testing.T) {
 		ctx := context.TODO()
 		gs := createAndReconcileToFinalizedState(t, k8sClient, reconciler, makeTestGitOpsSet(t))
@@ -214,7 +405,7 @@ func TestReconciliation(t *testing.T) {
 
 		updated := &templatesv1.GitOpsSet{}
 		test.AssertNoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(gs), updated))
-		assertGitOpsSetCondition(t, updated, meta.ReadyCondition, "failed to create Resource: kustomizations.kustomize.toolkit.fluxcd.io \"engineering-dev-demo\" already exists")
+		assertGitOpsSetCondition(t, updated, meta.ReadyCondition, "2/3 elements applied: failed to create Resource: kustomizations.kustomize.toolkit.fluxcd.io \"engineering-dev-demo\" already exists")
 	})
 
 	t.Run("reconciling removal of resources", func(t *testing.T) {
@@ -449,6 +640,344 @@ func TestReconciliation(t *testing.T) {
 		}
 	})
 
+	t.Run("reconciling update of resources with server-side apply", func(t *testing.T) {
+		ctx := context.TODO()
+		gs := makeTestGitOpsSet(t, func(gs *templatesv1.GitOpsSet) {
+			gs.Spec.ServerSideApply = &templatesv1.ServerSideApply{Enabled: ptr.To(true)}
+			gs.Spec.Templates = []templatesv1.GitOpsSetTemplate{
+				{
+					Content: runtime.RawExtension{
+						Raw: mustMarshalJSON(t, test.NewConfigMap(func(c *corev1.ConfigMap) {
+							c.Data = map[string]string{
+								"testing": "{{ .Element.configValue }}",
+								"removed": "{{ .Element.cluster }}",
+							}
+						})),
+					},
+				},
+			}
+
+			gs.Spec.Generators = []templatesv1.GitOpsSetGenerator{
+				{
+					List: &templatesv1.ListGenerator{
+						Elements: []apiextensionsv1.JSON{
+							{Raw: []byte(`{"cluster": "engineering-dev","configValue":"test-value1"}`)},
+						},
+					},
+				},
+			}
+		})
+		gs = createAndReconcileToFinalizedState(t, k8sClient, reconciler, gs)
+		defer deleteGitOpsSetAndFinalize(t, k8sClient, reconciler, gs)
+
+		// Initial creation of ConfigMaps
+		_, err = reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(gs)})
+		test.AssertNoError(t, err)
+
+		createdCM := &corev1.ConfigMap{}
+		test.AssertNoError(t, k8sClient.Get(ctx, client.ObjectKey{Name: "demo-cm", Namespace: "default"}, createdCM))
+		for _, entry := range createdCM.GetManagedFields() {
+			if entry.Manager == fieldManager && entry.Operation != metav1.ManagedFieldsOperationApply {
+				t.Fatalf("ConfigMap was created with a %s operation, want Apply", entry.Operation)
+			}
+		}
+
+		// Another field manager adds a field to the generated ConfigMap.
+		external := &unstructured.Unstructured{}
+		external.SetGroupVersionKind(configMapGVK)
+		external.SetName("demo-cm")
+		external.SetNamespace("default")
+		test.AssertNoError(t, unstructured.SetNestedField(external.Object, "other-value", "data", "external"))
+		test.AssertNoError(t, k8sClient.Patch(ctx, external, client.Apply, client.FieldOwner("test-manager")))
+
+		updated := &templatesv1.GitOpsSet{}
+		test.AssertNoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(gs), updated))
+		updated.Spec.Templates = []templatesv1.GitOpsSetTemplate{
+			{
+				Content: runtime.RawExtension{
+					Raw: mustMarshalJSON(t, test.NewConfigMap(func(c *corev1.ConfigMap) {
+						c.Data = map[string]string{
+							"testing": "{{ .Element.configValue }}",
+						}
+					})),
+				},
+			},
+		}
+		updated.Spec.Generators = []templatesv1.GitOpsSetGenerator{
+			{
+				List: &templatesv1.ListGenerator{
+					Elements: []apiextensionsv1.JSON{
+						{Raw: []byte(`{"cluster": "engineering-dev","configValue":"test-value2"}`)},
+					},
+				},
+			},
+		}
+		test.AssertNoError(t, k8sClient.Update(ctx, updated))
+
+		_, err = reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(gs)})
+		test.AssertNoError(t, err)
+
+		wantCM := test.NewConfigMap(func(c *corev1.ConfigMap) {
+			c.ObjectMeta.Labels = map[string]string{
+				"sets.gitops.pro/name":      "demo-set",
+				"sets.gitops.pro/namespace": "default",
+			}
+			c.Data = map[string]string{
+				"testing":  "test-value2",
+				"external": "other-value",
+			}
+		})
+
+		updatedCM := &unstructured.Unstructured{}
+		updatedCM.SetGroupVersionKind(configMapGVK)
+		test.AssertNoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(wantCM), updatedCM))
+
+		if diff := cmp.Diff(test.ToUnstructured(t, wantCM), updatedCM, objectMetaIgnore()); diff != "" {
+			t.Fatalf("failed to apply ConfigMap:\n%s", diff)
+		}
+	})
+
+	t.Run("reconciling drift of modified resources", func(t *testing.T) {
+		ctx := context.TODO()
+		gs := makeTestGitOpsSet(t, func(gs *templatesv1.GitOpsSet) {
+			gs.Spec.Templates = []templatesv1.GitOpsSetTemplate{
+				{
+					Content: runtime.RawExtension{
+						Raw: mustMarshalJSON(t, test.NewConfigMap(func(c *corev1.ConfigMap) {
+							c.Data = map[string]string{
+								"testing": "{{ .Element.configValue }}",
+							}
+						})),
+					},
+				},
+			}
+
+			gs.Spec.Generators = []templatesv1.GitOpsSetGenerator{
+				{
+					List: &templatesv1.ListGenerator{
+						Elements: []apiextensionsv1.JSON{
+							{Raw: []byte(`{"cluster": "engineering-dev","configValue":"test-value1"}`)},
+						},
+					},
+				},
+			}
+		})
+		gs = createAndReconcileToFinalizedState(t, k8sClient, reconciler, gs)
+		defer deleteGitOpsSetAndFinalize(t, k8sClient, reconciler, gs)
+
+		// Initial creation of ConfigMaps
+		_, err = reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(gs)})
+		test.AssertNoError(t, err)
+
+		// The ConfigMap is modified outside of the GitOpsSet.
+		cm := &corev1.ConfigMap{}
+		test.AssertNoError(t, k8sClient.Get(ctx, client.ObjectKey{Name: "demo-cm", Namespace: "default"}, cm))
+		cm.Data["testing"] = "modified-value"
+		test.AssertNoError(t, k8sClient.Update(ctx, cm, client.FieldOwner("test-manager")))
+
+		eventRecorder.Reset()
+		_, err = reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(gs)})
+		test.AssertNoError(t, err)
+
+		test.AssertNoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(cm), cm))
+		if v := cm.Data["testing"]; v != "test-value1" {
+			t.Errorf("got ConfigMap value %q, want %q", v, "test-value1")
+		}
+
+		want := &test.EventData{
+			EventType: corev1.EventTypeNormal,
+			Reason:    DriftCorrectedReason,
+			Message:   "ConfigMap default/demo-cm was modified outside of the GitOpsSet and has been corrected",
+		}
+		if diff := cmp.Diff(want, eventRecorder.Events[0]); diff != "" {
+			t.Fatalf("failed to record drift event:\n%s", diff)
+		}
+	})
+
+	t.Run("reconciling in dry-run mode", func(t *testing.T) {
+		ctx := context.TODO()
+		gs := makeTestGitOpsSet(t, func(gs *templatesv1.GitOpsSet) {
+			gs.Spec.Generators = []templatesv1.GitOpsSetGenerator{
+				{
+					List: &templatesv1.ListGenerator{
+						Elements: []apiextensionsv1.JSON{
+							{Raw: []byte(`{"cluster": "engineering-dev"}`)},
+							{Raw: []byte(`{"cluster": "engineering-prod"}`)},
+						},
+					},
+				},
+			}
+		})
+		gs = createAndReconcileToFinalizedState(t, k8sClient, reconciler, gs)
+		defer deleteGitOpsSetAndFinalize(t, k8sClient, reconciler, gs)
+		// Initial creation of resources
+		_, err = reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(gs)})
+		test.AssertNoError(t, err)
+
+		test.AssertNoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(gs), gs))
+		gs.Spec.DryRun = true
+		gs.Spec.Generators = []templatesv1.GitOpsSetGenerator{
+			{
+				List: &templatesv1.ListGenerator{
+					Elements: []apiextensionsv1.JSON{
+						{Raw: []byte(`{"cluster": "engineering-dev"}`)},
+						{Raw: []byte(`{"cluster": "engineering-preprod"}`)},
+					},
+				},
+			},
+		}
+		gs.Spec.Templates[0].Content.Raw = mustMarshalJSON(t, test.MakeTestKustomization(nsn("default", "{{ .Element.cluster }}-demo"), func(k *kustomizev1.Kustomization) {
+			k.Spec.Path = "./updated/{{ .Element.cluster }}/"
+		}))
+		test.AssertNoError(t, k8sClient.Update(ctx, gs))
+
+		_, err = reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(gs)})
+		test.AssertNoError(t, err)
+
+		test.AssertNoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(gs), gs))
+		assertGitOpsSetCondition(t, gs, meta.ReadyCondition, "dry-run: 1 resources would be created, 1 updated, 0 adopted and 1 pruned")
+		gotActions := map[string]string{}
+		for _, change := range gs.Status.Plan {
+			gotActions[change.ID] = change.Action
+			if change.Action == templatesv1.PlanActionUpdate && change.Diff == "" {
+				t.Errorf("got no diff for updated resource %s", change.ID)
+			}
+		}
+		wantActions := map[string]string{
+			"default_engineering-dev-demo_kustomize.toolkit.fluxcd.io_Kustomization":     templatesv1.PlanActionUpdate,
+			"default_engineering-preprod-demo_kustomize.toolkit.fluxcd.io_Kustomization": templatesv1.PlanActionCreate,
+			"default_engineering-prod-demo_kustomize.toolkit.fluxcd.io_Kustomization":    templatesv1.PlanActionPrune,
+		}
+		if diff := cmp.Diff(wantActions, gotActions); diff != "" {
+			t.Fatalf("failed to plan changes:\n%s", diff)
+		}
+
+		// Nothing was changed in the cluster.
+		assertKustomizationsExist(t, k8sClient, "default", "engineering-dev-demo", "engineering-prod-demo")
+		test.AssertInventoryHasItems(t, gs,
+			test.MakeTestKustomization(nsn("default", "engineering-dev-demo")),
+			test.MakeTestKustomization(nsn("default", "engineering-prod-demo")),
+		)
+	})
+
+	t.Run("reconciling a rollout in waves", func(t *testing.T) {
+		ctx := context.TODO()
+		gs := makeTestGitOpsSet(t, func(gs *templatesv1.GitOpsSet) {
+			gs.Spec.Generators = []templatesv1.GitOpsSetGenerator{
+				{
+					List: &templatesv1.ListGenerator{
+						Elements: []apiextensionsv1.JSON{
+							{Raw: []byte(`{"cluster": "engineering-prod", "tier": "2"}`)},
+							{Raw: []byte(`{"cluster": "engineering-dev", "tier": "1"}`)},
+						},
+					},
+				},
+			}
+			gs.Spec.ElementKey = "{ .cluster }"
+			gs.Spec.Rollout = &templatesv1.Rollout{
+				MaxConcurrent: intstr.FromInt(1),
+				OrderBy:       "{ .tier }",
+			}
+		})
+		gs = createAndReconcileToFinalizedState(t, k8sClient, reconciler, gs)
+		defer deleteGitOpsSetAndFinalize(t, k8sClient, reconciler, gs)
+
+		result, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(gs)})
+		test.AssertNoError(t, err)
+		if result.RequeueAfter != rolloutInterval {
+			t.Errorf("got requeue %v, want %v", result.RequeueAfter, rolloutInterval)
+		}
+
+		test.AssertNoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(gs), gs))
+		assertKustomizationsExist(t, k8sClient, "default", "engineering-dev-demo")
+		if gs.Status.Rollout.CurrentWave != 1 || gs.Status.Rollout.TotalWaves != 2 {
+			t.Errorf("got rollout wave %d of %d, want 1 of 2", gs.Status.Rollout.CurrentWave, gs.Status.Rollout.TotalWaves)
+		}
+
+		_, err = reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(gs)})
+		test.AssertNoError(t, err)
+
+		test.AssertNoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(gs), gs))
+		assertKustomizationsExist(t, k8sClient, "default", "engineering-dev-demo", "engineering-prod-demo")
+		if !gs.Status.Rollout.Complete() {
+			t.Errorf("got incomplete rollout %#v", gs.Status.Rollout)
+		}
+		test.AssertInventoryHasItems(t, gs,
+			test.MakeTestKustomization(nsn("default", "engineering-dev-demo")),
+			test.MakeTestKustomization(nsn("default", "engineering-prod-demo")),
+		)
+	})
+
+	t.Run("reconciling with elements that fail to render", func(t *testing.T) {
+		ctx := context.TODO()
+		gs := makeTestGitOpsSet(t, func(gs *templatesv1.GitOpsSet) {
+			gs.Spec.ErrorPolicy = templatesv1.ErrorPolicyContinue
+			gs.Spec.ElementKey = "{ .name }"
+			gs.Spec.Generators = []templatesv1.GitOpsSetGenerator{
+				{
+					List: &templatesv1.ListGenerator{
+						Elements: []apiextensionsv1.JSON{
+							{Raw: []byte(`{"name": "dev", "cluster": "engineering-dev"}`)},
+							{Raw: []byte(`{"name": "prod", "cluster": "engineering-prod"}`)},
+						},
+					},
+				},
+			}
+		})
+		gs = createAndReconcileToFinalizedState(t, k8sClient, reconciler, gs)
+		defer deleteGitOpsSetAndFinalize(t, k8sClient, reconciler, gs)
+
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(gs)})
+		test.AssertNoError(t, err)
+
+		test.AssertNoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(gs), gs))
+		gs.Spec.Generators[0].List.Elements = []apiextensionsv1.JSON{
+			{Raw: []byte(`{"name": "dev", "cluster": "engineering-dev"}`)},
+			{Raw: []byte(`{"name": "prod"}`)},
+		}
+		test.AssertNoError(t, k8sClient.Update(ctx, gs))
+
+		_, err = reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(gs)})
+		test.AssertErrorMatch(t, `1/2 elements applied: failed to render template params.*map has no entry for key "cluster"`, err)
+
+		test.AssertNoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(gs), gs))
+		// The resources for the failed element are not pruned.
+		assertKustomizationsExist(t, k8sClient, "default", "engineering-dev-demo", "engineering-prod-demo")
+		test.AssertInventoryHasItems(t, gs,
+			test.MakeTestKustomization(nsn("default", "engineering-dev-demo")),
+			test.MakeTestKustomization(nsn("default", "engineering-prod-demo")),
+		)
+
+		prod := gs.Status.Elements[1]
+		if prod.Key != "prod" || prod.Ready || len(prod.Resources) != 1 || !strings.Contains(prod.LastError, `map has no entry for key "cluster"`) {
+			t.Errorf("got status %#v for the failed element", prod)
+		}
+	})
+
+	t.Run("reconciling with the continue error policy without an element key", func(t *testing.T) {
+		ctx := context.TODO()
+		gs := makeTestGitOpsSet(t, func(gs *templatesv1.GitOpsSet) {
+			gs.Spec.ErrorPolicy = templatesv1.ErrorPolicyContinue
+			gs.Spec.Generators = []templatesv1.GitOpsSetGenerator{
+				{
+					List: &templatesv1.ListGenerator{
+						Elements: []apiextensionsv1.JSON{
+							{Raw: []byte(`{"cluster": "engineering-dev"}`)},
+						},
+					},
+				},
+			}
+		})
+		gs = createAndReconcileToFinalizedState(t, k8sClient, reconciler, gs)
+		defer deleteGitOpsSetAndFinalize(t, k8sClient, reconciler, gs)
+
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(gs)})
+		test.AssertErrorMatch(t, "the continue errorPolicy requires an elementKey", err)
+
+		test.AssertNoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(gs), gs))
+		assertInventoryHasNoItems(t, gs)
+	})
+
 	t.Run("reconciling with no generated resources", func(t *testing.T) {
 		ctx := context.TODO()
 		gs := makeTestGitOpsSet(t, func(gs *templatesv1.GitOpsSet) {
@@ -767,9 +1296,87 @@ func TestReconciliation(t *testing.T) {
 		assertGitOpsSetCondition(t, gs, meta.ReadyCondition, "waiting for artifact")
 	})
 
+	t.Run("reconciling adoption of existing resources", func(t *testing.T) {
+		ctx := context.TODO()
+		gs := makeTestGitOpsSet(t, func(gs *templatesv1.GitOpsSet) {
+			gs.Spec.Adoption = &templatesv1.Adoption{
+				Enabled: true,
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{"example.com/adopt": "true"},
+				},
+			}
+			gs.Spec.Generators = []templatesv1.GitOpsSetGenerator{
+				{
+					List: &templatesv1.ListGenerator{
+						Elements: []apiextensionsv1.JSON{
+							{Raw: []byte(`{"cluster": "engineering-dev"}`)},
+						},
+					},
+				},
+			}
+		})
+		gs = createAndReconcileToFinalizedState(t, k8sClient, reconciler, gs)
+		defer deleteGitOpsSetAndFinalize(t, k8sClient, reconciler, gs)
+
+		devKS := test.MakeTestKustomization(nsn("default", "engineering-dev-demo"), func(k *kustomizev1.Kustomization) {
+			k.ObjectMeta.Labels = map[string]string{
+				"example.com/adopt": "true",
+			}
+			k.Spec.Path = "./existing"
+		})
+		test.AssertNoError(t, k8sClient.Create(ctx, test.ToUnstructured(t, devKS)))
+
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(gs)})
+		test.AssertNoError(t, err)
+
+		test.AssertNoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(gs), gs))
+		test.AssertInventoryHasItems(t, gs, test.MakeTestKustomization(nsn("default", "engineering-dev-demo")))
+
+		kustomization := &unstructured.Unstructured{}
+		kustomization.SetGroupVersionKind(kustomizationGVK)
+		test.AssertNoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(devKS), kustomization))
+		if path, _, _ := unstructured.NestedString(kustomization.Object, "spec", "path"); path != "./clusters/engineering-dev/" {
+			t.Errorf("got path %q, want %q", path, "./clusters/engineering-dev/")
+		}
+		if name := kustomization.GetLabels()["sets.gitops.pro/name"]; name != "demo-set" {
+			t.Errorf("got GitOpsSet name label %q, want %q", name, "demo-set")
+		}
+	})
+
+	t.Run("reconciling adoption of resources owned by another GitOpsSet", func(t *testing.T) {
+		ctx := context.TODO()
+		gs := makeTestGitOpsSet(t, func(gs *templatesv1.GitOpsSet) {
+			gs.Spec.Adoption = &templatesv1.Adoption{Enabled: true}
+			gs.Spec.Generators = []templatesv1.GitOpsSetGenerator{
+				{
+					List: &templatesv1.ListGenerator{
+						Elements: []apiextensionsv1.JSON{
+							{Raw: []byte(`{"cluster": "engineering-dev"}`)},
+						},
+					},
+				},
+			}
+		})
+		gs = createAndReconcileToFinalizedState(t, k8sClient, reconciler, gs)
+		defer deleteGitOpsSetAndFinalize(t, k8sClient, reconciler, gs)
+
+		devKS := test.MakeTestKustomization(nsn("default", "engineering-dev-demo"), func(k *kustomizev1.Kustomization) {
+			k.ObjectMeta.Labels = map[string]string{
+				"sets.gitops.pro/name":      "other-set",
+				"sets.gitops.pro/namespace": "default",
+			}
+		})
+		test.AssertNoError(t, k8sClient.Create(ctx, test.ToUnstructured(t, devKS)))
+		defer deleteAllKustomizations(t, k8sClient)
+
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(gs)})
+		test.AssertErrorMatch(t, "refusing to adopt Kustomization default/engineering-dev-demo owned by GitOpsSet default/other-set", err)
+	})
+
 	t.Run("error conditions - existing resource", func(t *testing.T) {
 		ctx := context.TODO()
 		gs := makeTestGitOpsSet(t, func(gs *templatesv1.GitOpsSet) {
+			gs.Spec.ElementKey = "{ .cluster }"
 			gs.Spec.Templates = []templatesv1.GitOpsSetTemplate{
 				{
 					Content: runtime.RawExtension{
@@ -803,7 +1410,28 @@ func TestReconciliation(t *testing.T) {
 
 		updated := &templatesv1.GitOpsSet{}
 		test.AssertNoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(gs), updated))
-		assertGitOpsSetCondition(t, updated, meta.ReadyCondition, "failed to create Resource: kustomizations.kustomize.toolkit.fluxcd.io \"engineering-dev-demo\" already exists")
+		assertGitOpsSetCondition(t, updated, meta.ReadyCondition, "2/3 elements applied: failed to create Resource: kustomizations.kustomize.toolkit.fluxcd.io \"engineering-dev-demo\" already exists")
+
+		want := []templatesv1.ElementStatus{
+			{
+				Key:       "engineering-dev",
+				Resources: []templatesv1.ResourceRef{{ID: "default_engineering-dev-demo_kustomize.toolkit.fluxcd.io_Kustomization", Version: "v1beta2"}},
+				LastError: "failed to create Resource: kustomizations.kustomize.toolkit.fluxcd.io \"engineering-dev-demo\" already exists",
+			},
+			{
+				Key:       "engineering-prod",
+				Ready:     true,
+				Resources: []templatesv1.ResourceRef{{ID: "default_engineering-prod-demo_kustomize.toolkit.fluxcd.io_Kustomization", Version: "v1beta2"}},
+			},
+			{
+				Key:       "engineering-preprod",
+				Ready:     true,
+				Resources: []templatesv1.ResourceRef{{ID: "default_engineering-preprod-demo_kustomize.toolkit.fluxcd.io_Kustomization", Version: "v1beta2"}},
+			},
+		}
+		if diff := cmp.Diff(want, updated.Status.Elements); diff != "" {
+			t.Fatalf("failed to record element status:\n%s", diff)
+		}
 	})
 }
 
@@ -871,6 +1499,61 @@ func TestGetClusterSelectors(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "with merge",
+			generator: templatesv1.GitOpsSetGenerator{
+				Merge: &templatesv1.MergeGenerator{
+					MergeKeys: []string{"ClusterName"},
+					Generators: []templatesv1.GitOpsSetNestedGenerator{
+						{
+							Cluster: &templatesv1.ClusterGenerator{
+								Selector: metav1.LabelSelector{
+									MatchLabels: map[string]string{
+										"env": "prod",
+									},
+								},
+							},
+						},
+						{
+							List: &templatesv1.ListGenerator{},
+						},
+					},
+				},
+			},
+			want: []metav1.LabelSelector{
+				{
+					MatchLabels: map[string]string{
+						"env": "prod",
+					},
+				},
+			},
+		},
+		{
+			name: "with nested matrix",
+			generator: templatesv1.GitOpsSetGenerator{
+				Matrix: &templatesv1.MatrixGenerator{
+					Generators: []templatesv1.GitOpsSetNestedGenerator{
+						{
+							List: &templatesv1.ListGenerator{},
+						},
+						{
+							Matrix: &templatesv1.NestedMatrixGenerator{
+								Generators: []apiextensionsv1.JSON{
+									{Raw: []byte(`{"cluster": {"selector": {"matchLabels": {"env": "dev"}}}}`)},
+								},
+							},
+						},
+					},
+				},
+			},
+			want: []metav1.LabelSelector{
+				{
+					MatchLabels: map[string]string{
+						"env": "dev",
+					},
+				},
+			},
+		},
 		{
 			name:      "without cluster or matrix",
 			generator: templatesv1.GitOpsSetGenerator{},
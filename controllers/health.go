@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	fluxMeta "github.com/fluxcd/pkg/apis/meta"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	templatesv1 "github.com/weaveworks/gitopssets-controller/api/v1alpha1"
+	"github.com/weaveworks/gitopssets-controller/pkg/generators"
+)
+
+const (
+	// defaultHealthCheckTimeout is used when the GitOpsSet doesn't configure a
+	// Timeout.
+	defaultHealthCheckTimeout = 5 * time.Minute
+
+	// healthCheckInterval is how often the resources are checked while they
+	// are progressing.
+	healthCheckInterval = 10 * time.Second
+)
+
+// resourceHealth is the computed status of a generated resource.
+type resourceHealth struct {
+	ID      string
+	Status  status.Status
+	Message string
+}
+
+func (h resourceHealth) String() string {
+	if h.Message == "" {
+		return fmt.Sprintf("%s: %s", h.ID, h.Status)
+	}
+
+	return fmt.Sprintf("%s: %s: %s", h.ID, h.Status, h.Message)
+}
+
+// reconcileHealth assesses the health of the resources in the inventory and
+// sets the Healthy condition.
+//
+// While the resources are progressing, the GitOpsSet is requeued until they
+// become healthy, or the Timeout passes.
+//
+// If reset is true, the resources have changed and the Timeout is restarted.
+func reconcileHealth(ctx context.Context, k8sClient client.Reader, gs *templatesv1.GitOpsSet, reset bool, requeue time.Duration) time.Duration {
+	if !gs.Spec.Wait {
+		apimeta.RemoveStatusCondition(&gs.Status.Conditions, fluxMeta.HealthyCondition)
+		return requeue
+	}
+
+	if reset {
+		apimeta.RemoveStatusCondition(&gs.Status.Conditions, fluxMeta.HealthyCondition)
+	}
+
+	var entries []templatesv1.ResourceRef
+	if gs.Status.Inventory != nil {
+		entries = gs.Status.Inventory.Entries
+	}
+
+	var unhealthy []resourceHealth
+	failed := false
+	for _, ref := range entries {
+		health := checkResourceHealth(ctx, k8sClient, ref)
+		switch health.Status {
+		case status.CurrentStatus:
+			continue
+		case status.FailedStatus:
+			failed = true
+		}
+		unhealthy = append(unhealthy, health)
+	}
+
+	if len(unhealthy) == 0 {
+		templatesv1.SetGitOpsSetHealthiness(gs, metav1.ConditionTrue, fluxMeta.SucceededReason,
+			fmt.Sprintf("%d resources are healthy", len(entries)))
+		return requeue
+	}
+
+	msg := healthMessage(unhealthy)
+	existing := apimeta.FindStatusCondition(gs.Status.Conditions, fluxMeta.HealthyCondition)
+	switch {
+	case failed:
+		templatesv1.SetGitOpsSetHealthiness(gs, metav1.ConditionFalse, fluxMeta.HealthCheckFailedReason, msg)
+		return requeue
+	case existing != nil && existing.Status == metav1.ConditionFalse:
+		templatesv1.SetGitOpsSetHealthiness(gs, metav1.ConditionFalse, fluxMeta.HealthCheckFailedReason, msg)
+		return requeue
+	case existing != nil && existing.Status == metav1.ConditionUnknown && time.Since(existing.LastTransitionTime.Time) > healthCheckTimeout(gs):
+		templatesv1.SetGitOpsSetHealthiness(gs, metav1.ConditionFalse, fluxMeta.HealthCheckFailedReason,
+			fmt.Sprintf("timeout waiting for %s", msg))
+		return requeue
+	}
+
+	templatesv1.SetGitOpsSetHealthiness(gs, metav1.ConditionUnknown, fluxMeta.ProgressingReason, msg)
+	if requeue == generators.NoRequeueInterval || requeue > healthCheckInterval {
+		return healthCheckInterval
+	}
+
+	return requeue
+}
+
+func checkResourceHealth(ctx context.Context, k8sClient client.Reader, ref templatesv1.ResourceRef) resourceHealth {
+	u, err := unstructuredFromResourceRef(ref)
+	if err != nil {
+		return resourceHealth{ID: ref.ID, Status: status.UnknownStatus, Message: err.Error()}
+	}
+
+	id := fmt.Sprintf("%s %s", u.GetKind(), client.ObjectKeyFromObject(u))
+	if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(u), u); err != nil {
+		if apierrors.IsNotFound(err) {
+			return resourceHealth{ID: id, Status: status.NotFoundStatus}
+		}
+
+		return resourceHealth{ID: id, Status: status.UnknownStatus, Message: err.Error()}
+	}
+
+	result, err := status.Compute(u)
+	if err != nil {
+		return resourceHealth{ID: id, Status: status.UnknownStatus, Message: err.Error()}
+	}
+
+	return resourceHealth{ID: id, Status: result.Status, Message: result.Message}
+}
+
+func healthCheckTimeout(gs *templatesv1.GitOpsSet) time.Duration {
+	if gs.Spec.Timeout != nil {
+		return gs.Spec.Timeout.Duration
+	}
+
+	return defaultHealthCheckTimeout
+}
+
+func healthMessage(unhealthy []resourceHealth) string {
+	messages := make([]string, len(unhealthy))
+	for i := range unhealthy {
+		messages[i] = unhealthy[i].String()
+	}
+
+	return fmt.Sprintf("%d resources are not healthy: %s", len(unhealthy), strings.Join(messages, "; "))
+}
@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	fluxMeta "github.com/fluxcd/pkg/apis/meta"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	templatesv1 "github.com/weaveworks/gitopssets-controller/api/v1alpha1"
+	"github.com/weaveworks/gitopssets-controller/pkg/generators"
+	"github.com/weaveworks/gitopssets-controller/test"
+)
+
+func TestReconcileHealth(t *testing.T) {
+	configMapRef := templatesv1.ResourceRef{ID: "default_demo-cm__ConfigMap", Version: "v1"}
+	deploymentRef := templatesv1.ResourceRef{ID: "default_demo-deploy_apps_Deployment", Version: "v1"}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "demo-cm", Namespace: "default"},
+	}
+	progressingDeployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "demo-deploy", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: ptr.To[int32](1),
+		},
+	}
+
+	tests := []struct {
+		name        string
+		wait        bool
+		reset       bool
+		entries     []templatesv1.ResourceRef
+		conditions  []metav1.Condition
+		objs        []runtime.Object
+		wantCond    *metav1.Condition
+		wantRequeue time.Duration
+	}{
+		{
+			name:    "health checks not enabled",
+			entries: []templatesv1.ResourceRef{configMapRef},
+			conditions: []metav1.Condition{
+				{Type: fluxMeta.HealthyCondition, Status: metav1.ConditionTrue, Reason: fluxMeta.SucceededReason},
+			},
+			objs:        []runtime.Object{configMap},
+			wantRequeue: time.Minute,
+		},
+		{
+			name:    "healthy resources",
+			wait:    true,
+			entries: []templatesv1.ResourceRef{configMapRef},
+			objs:    []runtime.Object{configMap},
+			wantCond: &metav1.Condition{
+				Type:    fluxMeta.HealthyCondition,
+				Status:  metav1.ConditionTrue,
+				Reason:  fluxMeta.SucceededReason,
+				Message: "1 resources are healthy",
+			},
+			wantRequeue: time.Minute,
+		},
+		{
+			name:    "progressing resources",
+			wait:    true,
+			entries: []templatesv1.ResourceRef{configMapRef, deploymentRef},
+			objs:    []runtime.Object{configMap, progressingDeployment},
+			wantCond: &metav1.Condition{
+				Type:    fluxMeta.HealthyCondition,
+				Status:  metav1.ConditionUnknown,
+				Reason:  fluxMeta.ProgressingReason,
+				Message: "1 resources are not healthy: Deployment default/demo-deploy: InProgress: Replicas: 0/1",
+			},
+			wantRequeue: healthCheckInterval,
+		},
+		{
+			name:    "missing resources",
+			wait:    true,
+			entries: []templatesv1.ResourceRef{configMapRef},
+			wantCond: &metav1.Condition{
+				Type:    fluxMeta.HealthyCondition,
+				Status:  metav1.ConditionUnknown,
+				Reason:  fluxMeta.ProgressingReason,
+				Message: "1 resources are not healthy: ConfigMap default/demo-cm: NotFound",
+			},
+			wantRequeue: healthCheckInterval,
+		},
+		{
+			name:    "progressing resources after the timeout",
+			wait:    true,
+			entries: []templatesv1.ResourceRef{deploymentRef},
+			conditions: []metav1.Condition{
+				{
+					Type:               fluxMeta.HealthyCondition,
+					Status:             metav1.ConditionUnknown,
+					Reason:             fluxMeta.ProgressingReason,
+					LastTransitionTime: metav1.NewTime(time.Now().Add(-10 * time.Minute)),
+				},
+			},
+			objs: []runtime.Object{progressingDeployment},
+			wantCond: &metav1.Condition{
+				Type:    fluxMeta.HealthyCondition,
+				Status:  metav1.ConditionFalse,
+				Reason:  fluxMeta.HealthCheckFailedReason,
+				Message: "timeout waiting for 1 resources are not healthy: Deployment default/demo-deploy: InProgress: Replicas: 0/1",
+			},
+			wantRequeue: time.Minute,
+		},
+		{
+			name:    "failed health checks are restarted when resources change",
+			wait:    true,
+			reset:   true,
+			entries: []templatesv1.ResourceRef{deploymentRef},
+			conditions: []metav1.Condition{
+				{Type: fluxMeta.HealthyCondition, Status: metav1.ConditionFalse, Reason: fluxMeta.HealthCheckFailedReason},
+			},
+			objs: []runtime.Object{progressingDeployment},
+			wantCond: &metav1.Condition{
+				Type:    fluxMeta.HealthyCondition,
+				Status:  metav1.ConditionUnknown,
+				Reason:  fluxMeta.ProgressingReason,
+				Message: "1 resources are not healthy: Deployment default/demo-deploy: InProgress: Replicas: 0/1",
+			},
+			wantRequeue: healthCheckInterval,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			test.AssertNoError(t, clientgoscheme.AddToScheme(scheme))
+			k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(tt.objs...).Build()
+
+			gs := &templatesv1.GitOpsSet{
+				Spec: templatesv1.GitOpsSetSpec{
+					Wait: tt.wait,
+				},
+				Status: templatesv1.GitOpsSetStatus{
+					Conditions: tt.conditions,
+					Inventory:  &templatesv1.ResourceInventory{Entries: tt.entries},
+				},
+			}
+
+			requeue := reconcileHealth(context.TODO(), k8sClient, gs, tt.reset, time.Minute)
+			if requeue != tt.wantRequeue {
+				t.Errorf("got requeue %v, want %v", requeue, tt.wantRequeue)
+			}
+
+			cond := apimeta.FindStatusCondition(gs.Status.Conditions, fluxMeta.HealthyCondition)
+			if diff := cmp.Diff(tt.wantCond, cond, cmpopts.IgnoreFields(metav1.Condition{}, "LastTransitionTime")); diff != "" {
+				t.Fatalf("failed to set Healthy condition:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReconcileHealth_requeue_with_no_interval(t *testing.T) {
+	gs := &templatesv1.GitOpsSet{
+		Spec: templatesv1.GitOpsSetSpec{
+			Wait: true,
+		},
+		Status: templatesv1.GitOpsSetStatus{
+			Inventory: &templatesv1.ResourceInventory{
+				Entries: []templatesv1.ResourceRef{{ID: "default_demo-cm__ConfigMap", Version: "v1"}},
+			},
+		},
+	}
+	k8sClient := fake.NewClientBuilder().Build()
+
+	requeue := reconcileHealth(context.TODO(), k8sClient, gs, false, generators.NoRequeueInterval)
+	if requeue != healthCheckInterval {
+		t.Errorf("got requeue %v, want %v", requeue, healthCheckInterval)
+	}
+}
@@ -0,0 +1,273 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8ssets "k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	templatesv1 "github.com/weaveworks/gitopssets-controller/api/v1alpha1"
+	"github.com/weaveworks/gitopssets-controller/pkg/generators/kubernetesresources"
+)
+
+// resourceWatches tracks the Kinds that are queried by KubernetesResources
+// generators.
+//
+// The Kinds are not known when the controller is setup, so the watches are
+// added as GitOpsSets that query them are reconciled, and each watch is
+// stopped when there are no GitOpsSets that query the Kind.
+//
+// Each watch has its own cache, restricted to the namespace that is queried,
+// unless the resources are cluster-scoped or queried in all namespaces.
+type resourceWatches struct {
+	sync.Mutex
+	controller controller.Controller
+	mapper     meta.RESTMapper
+	newCache   func(namespace string) (cache.Cache, error)
+
+	watches    map[resourceWatchKey]*resourceWatch
+	gitOpsSets map[client.ObjectKey]k8ssets.Set[resourceWatchKey]
+}
+
+// resourceWatchKey identifies a watch, the namespace is empty when all
+// namespaces are watched.
+type resourceWatchKey struct {
+	gvk       schema.GroupVersionKind
+	namespace string
+}
+
+type resourceWatch struct {
+	cancel     context.CancelFunc
+	gitOpsSets k8ssets.Set[client.ObjectKey]
+}
+
+func newResourceWatches(c controller.Controller, cfg *rest.Config, scheme *runtime.Scheme, mapper meta.RESTMapper) *resourceWatches {
+	return &resourceWatches{
+		controller: c,
+		mapper:     mapper,
+		newCache: func(namespace string) (cache.Cache, error) {
+			opts := cache.Options{Scheme: scheme, Mapper: mapper}
+			if namespace != "" {
+				opts.DefaultNamespaces = map[string]cache.Config{namespace: {}}
+			}
+
+			return cache.New(cfg, opts)
+		},
+		watches:    map[resourceWatchKey]*resourceWatch{},
+		gitOpsSets: map[client.ObjectKey]k8ssets.Set[resourceWatchKey]{},
+	}
+}
+
+// watchKinds ensures that there's a watch for each of the Kinds queried by
+// the GitOpsSet's KubernetesResources generators.
+//
+// The watches for Kinds that the GitOpsSet no longer queries are released.
+func (w *resourceWatches) watchKinds(gs *templatesv1.GitOpsSet, mapFunc handler.MapFunc) error {
+	w.Lock()
+	defer w.Unlock()
+
+	gsKey := client.ObjectKeyFromObject(gs)
+	keys := k8ssets.New[resourceWatchKey]()
+	for _, gen := range kubernetesResourcesGenerators(gs) {
+		key, err := w.watchKey(gen, gs)
+		if err != nil {
+			return err
+		}
+		keys.Insert(key)
+
+		if watch, ok := w.watches[key]; ok {
+			watch.gitOpsSets.Insert(gsKey)
+			continue
+		}
+
+		cancel, err := w.startWatch(key, mapFunc)
+		if err != nil {
+			return fmt.Errorf("failed to watch %s: %w", key.gvk, err)
+		}
+		w.watches[key] = &resourceWatch{cancel: cancel, gitOpsSets: k8ssets.New(gsKey)}
+	}
+
+	for _, key := range w.gitOpsSets[gsKey].Difference(keys).UnsortedList() {
+		w.releaseWatch(key, gsKey)
+	}
+
+	if keys.Len() == 0 {
+		delete(w.gitOpsSets, gsKey)
+		return nil
+	}
+	w.gitOpsSets[gsKey] = keys
+
+	return nil
+}
+
+// release releases the watches for the Kinds queried by a GitOpsSet that is
+// being deleted.
+func (w *resourceWatches) release(gsKey client.ObjectKey) {
+	w.Lock()
+	defer w.Unlock()
+
+	for _, key := range w.gitOpsSets[gsKey].UnsortedList() {
+		w.releaseWatch(key, gsKey)
+	}
+	delete(w.gitOpsSets, gsKey)
+}
+
+// releaseWatch stops the watch when no other GitOpsSets use it.
+func (w *resourceWatches) releaseWatch(key resourceWatchKey, gsKey client.ObjectKey) {
+	watch, ok := w.watches[key]
+	if !ok {
+		return
+	}
+
+	watch.gitOpsSets.Delete(gsKey)
+	if watch.gitOpsSets.Len() > 0 {
+		return
+	}
+
+	if watch.cancel != nil {
+		watch.cancel()
+	}
+	delete(w.watches, key)
+}
+
+// watchKey returns the key for the watch needed by the generator, resources
+// are watched in the namespace of the GitOpsSet, unless they're
+// cluster-scoped, or queried in all namespaces.
+func (w *resourceWatches) watchKey(gen *templatesv1.KubernetesResourcesGenerator, gs *templatesv1.GitOpsSet) (resourceWatchKey, error) {
+	gvk, err := kubernetesresources.GroupVersionKind(gen)
+	if err != nil {
+		return resourceWatchKey{}, err
+	}
+
+	if gen.AllNamespaces {
+		return resourceWatchKey{gvk: gvk}, nil
+	}
+
+	namespaced, err := apiutil.IsGVKNamespaced(gvk, w.mapper)
+	if err != nil {
+		return resourceWatchKey{}, fmt.Errorf("failed to determine the scope of %s: %w", gvk, err)
+	}
+
+	if !namespaced {
+		return resourceWatchKey{gvk: gvk}, nil
+	}
+
+	return resourceWatchKey{gvk: gvk, namespace: gs.GetNamespace()}, nil
+}
+
+// startWatch starts a cache for the watch, and watches it from the
+// controller, the returned function stops the cache.
+func (w *resourceWatches) startWatch(key resourceWatchKey, mapFunc handler.MapFunc) (context.CancelFunc, error) {
+	ca, err := w.newCache(key.namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cache: %w", err)
+	}
+
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(key.gvk)
+
+	// The controller is started before GitOpsSets are reconciled, so the
+	// source is started when it's watched.
+	var cancel context.CancelFunc
+	src := source.Func(func(ctx context.Context, queue workqueue.TypedRateLimitingInterface[reconcile.Request]) error {
+		watchCtx, watchCancel := context.WithCancel(ctx)
+		cancel = watchCancel
+		go func() {
+			if err := ca.Start(watchCtx); err != nil {
+				log.FromContext(ctx).Error(err, "failed to start cache", "kind", key.gvk, "namespace", key.namespace)
+			}
+		}()
+
+		return source.Kind[client.Object](ca, u, handler.EnqueueRequestsFromMapFunc(mapFunc)).Start(watchCtx, queue)
+	})
+
+	if err := w.controller.Watch(src); err != nil {
+		if cancel != nil {
+			cancel()
+		}
+		return nil, err
+	}
+
+	return cancel, nil
+}
+
+// kubernetesResourceToGitOpsSet maps a resource to the GitOpsSets with
+// KubernetesResources generators that query it and returns a list of reconcile
+// requests for the GitOpsSets.
+func (r *GitOpsSetReconciler) kubernetesResourceToGitOpsSet(ctx context.Context, obj client.Object) []reconcile.Request {
+	list := &templatesv1.GitOpsSetList{}
+	if err := r.List(ctx, list, &client.ListOptions{}); err != nil {
+		return nil
+	}
+
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	var result []reconcile.Request
+	for i := range list.Items {
+		gs := &list.Items[i]
+		for _, gen := range kubernetesResourcesGenerators(gs) {
+			if matchKubernetesResource(gen, gs, gvk, obj) {
+				result = append(result, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(gs)})
+				break
+			}
+		}
+	}
+
+	return result
+}
+
+func matchKubernetesResource(gen *templatesv1.KubernetesResourcesGenerator, gs *templatesv1.GitOpsSet, gvk schema.GroupVersionKind, obj client.Object) bool {
+	genGVK, err := kubernetesresources.GroupVersionKind(gen)
+	if err != nil || genGVK != gvk {
+		return false
+	}
+
+	// Cluster-scoped resources have no namespace.
+	if !gen.AllNamespaces && obj.GetNamespace() != "" && obj.GetNamespace() != gs.GetNamespace() {
+		return false
+	}
+
+	if gen.Selector == nil {
+		return true
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(gen.Selector)
+	if err != nil {
+		return false
+	}
+
+	return selector.Matches(labels.Set(obj.GetLabels()))
+}
+
+func kubernetesResourcesGenerators(gs *templatesv1.GitOpsSet) []*templatesv1.KubernetesResourcesGenerator {
+	var result []*templatesv1.KubernetesResourcesGenerator
+	for _, gen := range gs.Spec.Generators {
+		if gen.KubernetesResources != nil {
+			result = append(result, gen.KubernetesResources)
+		}
+
+		for _, nestedGen := range nestedGenerators(gen) {
+			if nestedGen.KubernetesResources != nil {
+				result = append(result, nestedGen.KubernetesResources)
+			}
+		}
+	}
+
+	return result
+}
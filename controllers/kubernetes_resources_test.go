@@ -0,0 +1,308 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8ssets "k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	templatesv1 "github.com/weaveworks/gitopssets-controller/api/v1alpha1"
+	"github.com/weaveworks/gitopssets-controller/pkg/generators"
+	"github.com/weaveworks/gitopssets-controller/pkg/generators/kubernetesresources"
+	"github.com/weaveworks/gitopssets-controller/pkg/generators/list"
+	"github.com/weaveworks/gitopssets-controller/pkg/generators/matrix"
+	"github.com/weaveworks/gitopssets-controller/pkg/generators/merge"
+	"github.com/weaveworks/gitopssets-controller/test"
+)
+
+func TestMatchKubernetesResource(t *testing.T) {
+	configMapGVK := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	namespaceGVK := schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}
+	gs := &templatesv1.GitOpsSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "demo-set",
+			Namespace: "test-ns",
+		},
+	}
+
+	tests := []struct {
+		name      string
+		generator *templatesv1.KubernetesResourcesGenerator
+		gvk       schema.GroupVersionKind
+		obj       client.Object
+		want      bool
+	}{
+		{
+			name:      "matching kind in the same namespace",
+			generator: &templatesv1.KubernetesResourcesGenerator{APIVersion: "v1", Kind: "ConfigMap"},
+			gvk:       configMapGVK,
+			obj:       newTestConfigMap("test-ns", nil),
+			want:      true,
+		},
+		{
+			name:      "different kind",
+			generator: &templatesv1.KubernetesResourcesGenerator{APIVersion: "v1", Kind: "Secret"},
+			gvk:       configMapGVK,
+			obj:       newTestConfigMap("test-ns", nil),
+			want:      false,
+		},
+		{
+			name:      "matching kind in a different namespace",
+			generator: &templatesv1.KubernetesResourcesGenerator{APIVersion: "v1", Kind: "ConfigMap"},
+			gvk:       configMapGVK,
+			obj:       newTestConfigMap("other-ns", nil),
+			want:      false,
+		},
+		{
+			name:      "matching kind in a different namespace with all namespaces",
+			generator: &templatesv1.KubernetesResourcesGenerator{APIVersion: "v1", Kind: "ConfigMap", AllNamespaces: true},
+			gvk:       configMapGVK,
+			obj:       newTestConfigMap("other-ns", nil),
+			want:      true,
+		},
+		{
+			name:      "cluster-scoped resource",
+			generator: &templatesv1.KubernetesResourcesGenerator{APIVersion: "v1", Kind: "Namespace"},
+			gvk:       namespaceGVK,
+			obj:       &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a"}},
+			want:      true,
+		},
+		{
+			name: "matching selector",
+			generator: &templatesv1.KubernetesResourcesGenerator{
+				APIVersion: "v1",
+				Kind:       "ConfigMap",
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{"env": "dev"},
+				},
+			},
+			gvk:  configMapGVK,
+			obj:  newTestConfigMap("test-ns", map[string]string{"env": "dev"}),
+			want: true,
+		},
+		{
+			name: "non-matching selector",
+			generator: &templatesv1.KubernetesResourcesGenerator{
+				APIVersion: "v1",
+				Kind:       "ConfigMap",
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{"env": "dev"},
+				},
+			},
+			gvk:  configMapGVK,
+			obj:  newTestConfigMap("test-ns", map[string]string{"env": "production"}),
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchKubernetesResource(tt.generator, gs, tt.gvk, tt.obj); got != tt.want {
+				t.Errorf("matchKubernetesResource() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func newTestConfigMap(namespace string, labels map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-configmap",
+			Namespace: namespace,
+			Labels:    labels,
+		},
+	}
+}
+
+func TestResourceWatches(t *testing.T) {
+	configMapGVK := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	namespaceGVK := schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(configMapGVK, meta.RESTScopeNamespace)
+	mapper.Add(namespaceGVK, meta.RESTScopeRoot)
+
+	fc := &fakeController{}
+	var cacheNamespaces []string
+	watches := &resourceWatches{
+		controller: fc,
+		mapper:     mapper,
+		newCache: func(namespace string) (cache.Cache, error) {
+			cacheNamespaces = append(cacheNamespaces, namespace)
+			return nil, nil
+		},
+		watches:    map[resourceWatchKey]*resourceWatch{},
+		gitOpsSets: map[client.ObjectKey]k8ssets.Set[resourceWatchKey]{},
+	}
+
+	configMaps := &templatesv1.KubernetesResourcesGenerator{APIVersion: "v1", Kind: "ConfigMap"}
+	namespaces := &templatesv1.KubernetesResourcesGenerator{APIVersion: "v1", Kind: "Namespace"}
+	gs1 := newTestResourcesGitOpsSet("set-1", "team-a", configMaps, namespaces)
+	gs2 := newTestResourcesGitOpsSet("set-2", "team-a", configMaps)
+	gs3 := newTestResourcesGitOpsSet("set-3", "team-b", configMaps)
+
+	for _, gs := range []*templatesv1.GitOpsSet{gs1, gs2, gs3} {
+		test.AssertNoError(t, watches.watchKinds(gs, nil))
+	}
+
+	// The ConfigMaps are watched in each namespace, and the cluster-scoped
+	// Namespaces are watched once.
+	if diff := cmp.Diff([]string{"team-a", "", "team-b"}, cacheNamespaces); diff != "" {
+		t.Fatalf("failed to create caches:\n%s", diff)
+	}
+	if fc.watches != 3 {
+		t.Fatalf("got %d watches, want 3", fc.watches)
+	}
+
+	// The first set no longer queries the Namespaces.
+	gs1.Spec.Generators = gs1.Spec.Generators[:1]
+	test.AssertNoError(t, watches.watchKinds(gs1, nil))
+	assertWatched(t, watches,
+		resourceWatchKey{gvk: configMapGVK, namespace: "team-a"},
+		resourceWatchKey{gvk: configMapGVK, namespace: "team-b"})
+
+	// The ConfigMaps in team-a are still watched for the second set.
+	watches.release(client.ObjectKeyFromObject(gs1))
+	assertWatched(t, watches,
+		resourceWatchKey{gvk: configMapGVK, namespace: "team-a"},
+		resourceWatchKey{gvk: configMapGVK, namespace: "team-b"})
+
+	watches.release(client.ObjectKeyFromObject(gs2))
+	watches.release(client.ObjectKeyFromObject(gs3))
+	assertWatched(t, watches)
+	if len(watches.gitOpsSets) != 0 {
+		t.Errorf("got GitOpsSets %v, want none", watches.gitOpsSets)
+	}
+}
+
+func assertWatched(t *testing.T, watches *resourceWatches, want ...resourceWatchKey) {
+	t.Helper()
+	got := k8ssets.KeySet(watches.watches)
+	if !got.Equal(k8ssets.New(want...)) {
+		t.Fatalf("got watches %v, want %v", got.UnsortedList(), want)
+	}
+}
+
+// fakeController records the sources that are watched without starting them.
+type fakeController struct {
+	controller.Controller
+	watches int
+}
+
+func (c *fakeController) Watch(src source.Source) error {
+	c.watches++
+	return nil
+}
+
+func newTestResourcesGitOpsSet(name, namespace string, gens ...*templatesv1.KubernetesResourcesGenerator) *templatesv1.GitOpsSet {
+	gs := &templatesv1.GitOpsSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+	}
+	for _, gen := range gens {
+		gs.Spec.Generators = append(gs.Spec.Generators, templatesv1.GitOpsSetGenerator{KubernetesResources: gen})
+	}
+
+	return gs
+}
+
+func TestInstantiateGenerators_nested_kubernetes_resources(t *testing.T) {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+	controllerClient := fake.NewClientBuilder().WithRESTMapper(mapper).WithObjects(newTestDataConfigMap("controller-config", map[string]string{"env": "controller"})).Build()
+	serviceAccountClient := fake.NewClientBuilder().WithRESTMapper(mapper).WithObjects(newTestDataConfigMap("team-config", map[string]string{"env": "dev"})).Build()
+
+	nested := map[string]generators.GeneratorFactory{
+		"List":                list.GeneratorFactory,
+		"KubernetesResources": kubernetesresources.GeneratorFactory(false),
+	}
+	reconciler := &GitOpsSetReconciler{
+		Client: controllerClient,
+		Generators: map[string]generators.GeneratorFactory{
+			"Matrix": matrix.GeneratorFactory(nested),
+			"Merge":  merge.GeneratorFactory(nested),
+		},
+	}
+
+	testCases := []struct {
+		name      string
+		kind      string
+		generator templatesv1.GitOpsSetGenerator
+		want      []map[string]any
+	}{
+		{
+			name: "nested in a matrix",
+			kind: "Matrix",
+			generator: templatesv1.GitOpsSetGenerator{
+				Matrix: &templatesv1.MatrixGenerator{
+					Generators: []templatesv1.GitOpsSetNestedGenerator{
+						{KubernetesResources: &templatesv1.KubernetesResourcesGenerator{APIVersion: "v1", Kind: "ConfigMap", JSONPath: "{.data}"}},
+						{List: &templatesv1.ListGenerator{Elements: []apiextensionsv1.JSON{{Raw: []byte(`{"cluster": "cluster-1"}`)}}}},
+					},
+				},
+			},
+			want: []map[string]any{{"env": "dev", "cluster": "cluster-1"}},
+		},
+		{
+			name: "nested in a merge",
+			kind: "Merge",
+			generator: templatesv1.GitOpsSetGenerator{
+				Merge: &templatesv1.MergeGenerator{
+					MergeKeys: []string{"env"},
+					Generators: []templatesv1.GitOpsSetNestedGenerator{
+						{List: &templatesv1.ListGenerator{Elements: []apiextensionsv1.JSON{{Raw: []byte(`{"env": "dev", "cluster": "cluster-1"}`)}}}},
+						{KubernetesResources: &templatesv1.KubernetesResourcesGenerator{APIVersion: "v1", Kind: "ConfigMap", JSONPath: "{.data}"}},
+					},
+				},
+			},
+			want: []map[string]any{{"env": "dev", "cluster": "cluster-1"}},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			gs := &templatesv1.GitOpsSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "demo-set",
+					Namespace: "test-ns",
+				},
+				Spec: templatesv1.GitOpsSetSpec{
+					ServiceAccountName: "test-sa",
+					Generators:         []templatesv1.GitOpsSetGenerator{tt.generator},
+				},
+			}
+
+			instantiated := reconciler.instantiateGenerators(context.TODO(), serviceAccountClient)
+			elements, err := instantiated[tt.kind].Generate(context.TODO(), &gs.Spec.Generators[0], gs)
+			test.AssertNoError(t, err)
+
+			// The ConfigMap is listed with the service account's client, not the
+			// controller's client.
+			if diff := cmp.Diff(tt.want, elements); diff != "" {
+				t.Fatalf("failed to generate with the service account's client:\n%s", diff)
+			}
+		})
+	}
+}
+
+func newTestDataConfigMap(name string, data map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "test-ns",
+		},
+		Data: data,
+	}
+}
@@ -0,0 +1,224 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/util/jsonpath"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	templatesv1 "github.com/weaveworks/gitopssets-controller/api/v1alpha1"
+	"github.com/weaveworks/gitopssets-controller/controllers/templates"
+)
+
+const (
+	// RolloutWaveReason is the reason for events recorded when a wave of a
+	// rollout is applied.
+	RolloutWaveReason = "RolloutWave"
+
+	// rolloutInterval is how often the GitOpsSet is requeued while a rollout
+	// is in progress.
+	rolloutInterval = 10 * time.Second
+)
+
+// reconcileRollout orders the elements for the rollout, and records the
+// progress of the rollout in the status.
+//
+// Elements that have already been rolled out, because their resources haven't
+// changed since they were last applied, are always applied, and the elements
+// that have changed are rolled out in waves. The next wave is started if the
+// elements that have been rolled out are healthy, or the rollout doesn't wait
+// for health.
+//
+// The elements that should be applied are returned, in the same order as the
+// ordered elements.
+func (r *GitOpsSetReconciler) reconcileRollout(ctx context.Context, k8sClient client.Reader, gs *templatesv1.GitOpsSet, elements []templates.RenderedElement) ([]bool, error) {
+	if err := validateElementKey(gs); err != nil {
+		return nil, err
+	}
+
+	if err := orderElements(gs.Spec.Rollout.OrderBy, elements); err != nil {
+		return nil, err
+	}
+
+	keys, err := elementKeys(gs, elements)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := make([]string, len(elements))
+	for i := range elements {
+		revisions[i], err = elementRevision(elements[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	waveSize, err := rolloutWaveSize(gs.Spec.Rollout, len(elements))
+	if err != nil {
+		return nil, err
+	}
+
+	appliedRevisions := map[string]string{}
+	for _, element := range gs.Status.Elements {
+		appliedRevisions[element.Key] = element.Revision
+	}
+
+	apply := make([]bool, len(elements))
+	var rolledOut []templates.RenderedElement
+	var pending []int
+	for i := range elements {
+		if elements[i].Err == nil && appliedRevisions[keys[i]] == revisions[i] {
+			apply[i] = true
+			rolledOut = append(rolledOut, elements[i])
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	revision := rolloutRevision(keys, revisions)
+	currentWave := 0
+	if previous := gs.Status.Rollout; previous != nil && previous.Revision == revision {
+		currentWave = previous.CurrentWave
+	}
+
+	updated := len(rolledOut)
+	if len(pending) > 0 && (currentWave == 0 || !gs.Spec.Rollout.WaitForHealth || elementsHealthy(ctx, k8sClient, rolledOut)) {
+		wave := pending[:min(waveSize, len(pending))]
+		for _, i := range wave {
+			apply[i] = true
+		}
+		pending = pending[len(wave):]
+		updated += len(wave)
+		currentWave++
+
+		if r.EventRecorder != nil && !isPlanning(k8sClient) {
+			r.EventRecorder.Event(gs, corev1.EventTypeNormal, RolloutWaveReason,
+				fmt.Sprintf("rolling out wave %d of %d, %d of %d elements updated",
+					currentWave, currentWave+waves(len(pending), waveSize), updated, len(elements)))
+		}
+	}
+
+	gs.Status.Rollout = &templatesv1.RolloutStatus{
+		Revision:        revision,
+		CurrentWave:     currentWave,
+		TotalWaves:      currentWave + waves(len(pending), waveSize),
+		UpdatedElements: updated,
+		TotalElements:   len(elements),
+	}
+
+	return apply, nil
+}
+
+// orderElements sorts the elements by the result of evaluating the JSONPath
+// expression against each element.
+//
+// The results are compared as numbers if they are both numbers, and elements
+// with the same result keep the order they were generated in.
+func orderElements(orderBy string, elements []templates.RenderedElement) error {
+	if orderBy == "" {
+		return nil
+	}
+
+	jp := jsonpath.New("orderBy").AllowMissingKeys(true)
+	if err := jp.Parse(orderBy); err != nil {
+		return fmt.Errorf("failed to parse rollout orderBy %q: %w", orderBy, err)
+	}
+
+	type orderedElement struct {
+		key     string
+		element templates.RenderedElement
+	}
+
+	ordered := make([]orderedElement, len(elements))
+	for i := range elements {
+		var buf bytes.Buffer
+		if err := jp.Execute(&buf, elements[i].Element); err != nil {
+			return fmt.Errorf("failed to evaluate rollout orderBy %q: %w", orderBy, err)
+		}
+		ordered[i] = orderedElement{key: buf.String(), element: elements[i]}
+	}
+
+	sort.SliceStable(ordered, func(i, j int) bool { return orderKeyLess(ordered[i].key, ordered[j].key) })
+	for i := range ordered {
+		elements[i] = ordered[i].element
+	}
+
+	return nil
+}
+
+func orderKeyLess(x, y string) bool {
+	xf, xErr := strconv.ParseFloat(x, 64)
+	yf, yErr := strconv.ParseFloat(y, 64)
+	if xErr == nil && yErr == nil {
+		return xf < yf
+	}
+
+	return x < y
+}
+
+// elementRevision returns a hash of the resources rendered for an element.
+func elementRevision(element templates.RenderedElement) (string, error) {
+	h := sha256.New()
+	for _, resource := range element.Resources {
+		b, err := json.Marshal(resource.Object)
+		if err != nil {
+			return "", fmt.Errorf("failed to calculate element revision: %w", err)
+		}
+		h.Write(b)
+	}
+
+	return fmt.Sprintf("sha256:%x", h.Sum(nil)), nil
+}
+
+// rolloutRevision returns a hash of the keys and revisions of the elements in
+// rollout order, a new rollout is started when this changes.
+func rolloutRevision(keys, revisions []string) string {
+	h := sha256.New()
+	for i := range keys {
+		fmt.Fprintf(h, "%s=%s\n", keys[i], revisions[i])
+	}
+
+	return fmt.Sprintf("sha256:%x", h.Sum(nil))
+}
+
+func rolloutWaveSize(rollout *templatesv1.Rollout, total int) (int, error) {
+	size, err := intstr.GetScaledValueFromIntOrPercent(&rollout.MaxConcurrent, total, true)
+	if err != nil {
+		return 0, fmt.Errorf("invalid rollout maxConcurrent: %w", err)
+	}
+
+	return max(size, 1), nil
+}
+
+// elementsHealthy returns true if all the resources generated for the
+// elements are healthy.
+func elementsHealthy(ctx context.Context, k8sClient client.Reader, elements []templates.RenderedElement) bool {
+	for _, element := range elements {
+		for _, resource := range element.Resources {
+			ref, err := templatesv1.ResourceRefFromObject(resource)
+			if err != nil {
+				return false
+			}
+
+			if checkResourceHealth(ctx, k8sClient, ref).Status != status.CurrentStatus {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func waves(elements, waveSize int) int {
+	return (elements + waveSize - 1) / waveSize
+}
@@ -0,0 +1,252 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	templatesv1 "github.com/weaveworks/gitopssets-controller/api/v1alpha1"
+	"github.com/weaveworks/gitopssets-controller/controllers/templates"
+	"github.com/weaveworks/gitopssets-controller/test"
+)
+
+func TestOrderElements(t *testing.T) {
+	tests := []struct {
+		name    string
+		orderBy string
+		want    []string
+	}{
+		{
+			name: "no ordering",
+			want: []string{"prod-eu", "dev", "prod-us", "staging"},
+		},
+		{
+			name:    "ordered by a field",
+			orderBy: "{ .tier }",
+			want:    []string{"dev", "staging", "prod-eu", "prod-us"},
+		},
+		{
+			name:    "ordered by a numeric field",
+			orderBy: "{ .priority }",
+			want:    []string{"staging", "prod-us", "dev", "prod-eu"},
+		},
+		{
+			name:    "ordered by a missing field",
+			orderBy: "{ .region }",
+			want:    []string{"prod-eu", "dev", "prod-us", "staging"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			elements := []templates.RenderedElement{
+				{Element: map[string]any{"name": "prod-eu", "tier": "3-prod", "priority": 100}},
+				{Element: map[string]any{"name": "dev", "tier": "1-dev", "priority": 20}},
+				{Element: map[string]any{"name": "prod-us", "tier": "3-prod", "priority": 10}},
+				{Element: map[string]any{"name": "staging", "tier": "2-staging", "priority": 9}},
+			}
+
+			test.AssertNoError(t, orderElements(tt.orderBy, elements))
+
+			var names []string
+			for _, element := range elements {
+				names = append(names, element.Element["name"].(string))
+			}
+			if diff := cmp.Diff(tt.want, names); diff != "" {
+				t.Fatalf("failed to order elements:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestOrderElements_errors(t *testing.T) {
+	err := orderElements("{ .tier", []templates.RenderedElement{{Element: map[string]any{"tier": "dev"}}})
+
+	test.AssertErrorMatch(t, "failed to parse rollout orderBy", err)
+}
+
+func TestReconcileRollout(t *testing.T) {
+	elements := []templates.RenderedElement{
+		newTestRolloutElement("cm-1"),
+		newTestRolloutElement("cm-2"),
+		newTestRolloutElement("cm-3"),
+	}
+	keys := []string{"cm-1", "cm-2", "cm-3"}
+	revisions := make([]string, len(elements))
+	for i := range elements {
+		var err error
+		revisions[i], err = elementRevision(elements[i])
+		test.AssertNoError(t, err)
+	}
+	revision := rolloutRevision(keys, revisions)
+
+	// The status of elements that were applied in earlier waves.
+	applied := func(names ...string) []templatesv1.ElementStatus {
+		var statuses []templatesv1.ElementStatus
+		for _, name := range names {
+			for i := range keys {
+				if keys[i] == name {
+					statuses = append(statuses, templatesv1.ElementStatus{Key: name, Ready: true, Revision: revisions[i]})
+				}
+			}
+		}
+		return statuses
+	}
+
+	tests := []struct {
+		name          string
+		maxConcurrent intstr.IntOrString
+		waitForHealth bool
+		status        *templatesv1.RolloutStatus
+		elements      []templatesv1.ElementStatus
+		objs          []runtime.Object
+		wantApplied   []bool
+		wantStatus    *templatesv1.RolloutStatus
+	}{
+		{
+			name:          "first wave",
+			maxConcurrent: intstr.FromInt(2),
+			wantApplied:   []bool{true, true, false},
+			wantStatus:    &templatesv1.RolloutStatus{Revision: revision, CurrentWave: 1, TotalWaves: 2, UpdatedElements: 2, TotalElements: 3},
+		},
+		{
+			name:          "first wave with a percentage",
+			maxConcurrent: intstr.FromString("30%"),
+			wantApplied:   []bool{true, false, false},
+			wantStatus:    &templatesv1.RolloutStatus{Revision: revision, CurrentWave: 1, TotalWaves: 3, UpdatedElements: 1, TotalElements: 3},
+		},
+		{
+			name:          "next wave",
+			maxConcurrent: intstr.FromInt(2),
+			status:        &templatesv1.RolloutStatus{Revision: revision, CurrentWave: 1, TotalWaves: 2, UpdatedElements: 2, TotalElements: 3},
+			elements:      applied("cm-1", "cm-2"),
+			wantApplied:   []bool{true, true, true},
+			wantStatus:    &templatesv1.RolloutStatus{Revision: revision, CurrentWave: 2, TotalWaves: 2, UpdatedElements: 3, TotalElements: 3},
+		},
+		{
+			name:          "waiting for unhealthy elements",
+			maxConcurrent: intstr.FromInt(1),
+			waitForHealth: true,
+			status:        &templatesv1.RolloutStatus{Revision: revision, CurrentWave: 1, TotalWaves: 3, UpdatedElements: 1, TotalElements: 3},
+			elements:      applied("cm-1"),
+			wantApplied:   []bool{true, false, false},
+			wantStatus:    &templatesv1.RolloutStatus{Revision: revision, CurrentWave: 1, TotalWaves: 3, UpdatedElements: 1, TotalElements: 3},
+		},
+		{
+			name:          "next wave after healthy elements",
+			maxConcurrent: intstr.FromInt(1),
+			waitForHealth: true,
+			status:        &templatesv1.RolloutStatus{Revision: revision, CurrentWave: 1, TotalWaves: 3, UpdatedElements: 1, TotalElements: 3},
+			elements:      applied("cm-1"),
+			objs:          []runtime.Object{newTestRolloutConfigMap("cm-1")},
+			wantApplied:   []bool{true, true, false},
+			wantStatus:    &templatesv1.RolloutStatus{Revision: revision, CurrentWave: 2, TotalWaves: 3, UpdatedElements: 2, TotalElements: 3},
+		},
+		{
+			name:          "changed elements are rolled out without restarting unchanged elements",
+			maxConcurrent: intstr.FromInt(1),
+			status:        &templatesv1.RolloutStatus{Revision: "sha256:previous", CurrentWave: 3, TotalWaves: 3, UpdatedElements: 3, TotalElements: 3},
+			elements: []templatesv1.ElementStatus{
+				{Key: "cm-1", Ready: true, Revision: revisions[0]},
+				{Key: "cm-2", Ready: true, Revision: "sha256:previous"},
+				{Key: "cm-3", Ready: true, Revision: revisions[2]},
+			},
+			wantApplied: []bool{true, true, true},
+			wantStatus:  &templatesv1.RolloutStatus{Revision: revision, CurrentWave: 1, TotalWaves: 1, UpdatedElements: 3, TotalElements: 3},
+		},
+		{
+			name:          "new elements are rolled out in waves",
+			maxConcurrent: intstr.FromInt(1),
+			status:        &templatesv1.RolloutStatus{Revision: "sha256:previous", CurrentWave: 1, TotalWaves: 1, UpdatedElements: 1, TotalElements: 1},
+			elements:      applied("cm-2"),
+			wantApplied:   []bool{true, true, false},
+			wantStatus:    &templatesv1.RolloutStatus{Revision: revision, CurrentWave: 1, TotalWaves: 2, UpdatedElements: 2, TotalElements: 3},
+		},
+		{
+			name:          "complete rollout",
+			maxConcurrent: intstr.FromInt(2),
+			status:        &templatesv1.RolloutStatus{Revision: revision, CurrentWave: 2, TotalWaves: 2, UpdatedElements: 3, TotalElements: 3},
+			elements:      applied("cm-1", "cm-2", "cm-3"),
+			wantApplied:   []bool{true, true, true},
+			wantStatus:    &templatesv1.RolloutStatus{Revision: revision, CurrentWave: 2, TotalWaves: 2, UpdatedElements: 3, TotalElements: 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			test.AssertNoError(t, clientgoscheme.AddToScheme(scheme))
+			k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(tt.objs...).Build()
+
+			gs := &templatesv1.GitOpsSet{
+				Spec: templatesv1.GitOpsSetSpec{
+					ElementKey: "{ .name }",
+					Rollout: &templatesv1.Rollout{
+						MaxConcurrent: tt.maxConcurrent,
+						WaitForHealth: tt.waitForHealth,
+					},
+				},
+				Status: templatesv1.GitOpsSetStatus{
+					Rollout:  tt.status,
+					Elements: tt.elements,
+				},
+			}
+			r := &GitOpsSetReconciler{}
+
+			applied, err := r.reconcileRollout(context.TODO(), k8sClient, gs, elements)
+			test.AssertNoError(t, err)
+
+			if diff := cmp.Diff(tt.wantApplied, applied); diff != "" {
+				t.Errorf("failed to select the elements to apply:\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantStatus, gs.Status.Rollout); diff != "" {
+				t.Fatalf("failed to record rollout status:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReconcileRollout_without_element_key(t *testing.T) {
+	gs := &templatesv1.GitOpsSet{
+		Spec: templatesv1.GitOpsSetSpec{
+			Rollout: &templatesv1.Rollout{MaxConcurrent: intstr.FromInt(1)},
+		},
+	}
+	r := &GitOpsSetReconciler{}
+
+	_, err := r.reconcileRollout(context.TODO(), fake.NewClientBuilder().Build(), gs, []templates.RenderedElement{newTestRolloutElement("cm-1")})
+
+	test.AssertErrorMatch(t, "rollout requires an elementKey", err)
+}
+
+func newTestRolloutElement(name string) templates.RenderedElement {
+	return templates.RenderedElement{
+		Element: map[string]any{"name": name},
+		Resources: []*unstructured.Unstructured{
+			{
+				Object: map[string]any{
+					"apiVersion": "v1",
+					"kind":       "ConfigMap",
+					"metadata": map[string]any{
+						"name":      name,
+						"namespace": "default",
+					},
+				},
+			},
+		},
+	}
+}
+
+func newTestRolloutConfigMap(name string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+	}
+}
@@ -0,0 +1,146 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	k8ssets "k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/csaupgrade"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	templatesv1 "github.com/weaveworks/gitopssets-controller/api/v1alpha1"
+)
+
+// fieldManager is the field manager used when creating and updating generated
+// resources.
+const fieldManager = "gitopssets-controller"
+
+// legacyFieldManager is the field manager that was recorded for resources
+// created and updated by the controller before it set a field manager, this is
+// the name of the controller binary.
+const legacyFieldManager = "manager"
+
+// csaFieldManagers are the field managers that may have been recorded for
+// resources updated by the controller before server-side apply was enabled.
+//
+// Only the controller's own field manager is migrated, fields that are owned
+// by other managers are left with them.
+var csaFieldManagers = k8ssets.New(fieldManager)
+
+// inventoryFieldManagers are the field managers that are migrated for
+// resources in the inventory.
+//
+// These were created by the controller, so the fields owned by the legacy
+// field manager are the controller's, other resources may be updated by other
+// controllers with the same binary name.
+var inventoryFieldManagers = csaFieldManagers.Union(k8ssets.New(legacyFieldManager))
+
+// serverSideApply returns true if the GitOpsSet's resources should be applied
+// using server-side apply.
+func (r *GitOpsSetReconciler) serverSideApply(gs *templatesv1.GitOpsSet) bool {
+	if gs.Spec.ServerSideApply != nil && gs.Spec.ServerSideApply.Enabled != nil {
+		return *gs.Spec.ServerSideApply.Enabled
+	}
+
+	return r.DefaultServerSideApply
+}
+
+// applyResource applies the resource using server-side apply.
+//
+// Any fields that were previously owned by the controller through updates with
+// one of the migrated field managers are migrated to the apply field manager
+// first, so that fields removed from the templates are removed from the
+// resource.
+//
+// If the resource had drifted from the template, the kind of drift that was
+// corrected is returned.
+func applyResource(ctx context.Context, k8sClient client.Client, gs *templatesv1.GitOpsSet, obj *unstructured.Unstructured, migratedManagers k8ssets.Set[string]) (string, error) {
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(obj.GroupVersionKind())
+	if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(obj), existing); client.IgnoreNotFound(err) != nil {
+		return "", fmt.Errorf("failed to load existing Resource: %w", err)
+	}
+
+	drifted := modifiedByOtherManager(existing)
+	if existing.GetUID() != "" {
+		if err := upgradeManagedFields(ctx, k8sClient, existing, migratedManagers); err != nil {
+			return "", err
+		}
+	}
+
+	obj.SetManagedFields(nil)
+	obj.SetResourceVersion("")
+	if err := k8sClient.Patch(ctx, obj, client.Apply, applyOptions(gs)...); err != nil {
+		return "", fmt.Errorf("failed to apply Resource: %w", err)
+	}
+
+	switch {
+	case existing.GetUID() == "":
+		return driftDeleted, nil
+	case obj.GetResourceVersion() != existing.GetResourceVersion() && drifted:
+		return driftModified, nil
+	}
+
+	return "", nil
+}
+
+// createResource creates a resource that is not in the inventory.
+//
+// When server-side apply is enabled, the resource is created with an apply
+// patch so that the fields are owned by the apply field manager from the
+// start, an AlreadyExists error is returned if the resource exists.
+func (r *GitOpsSetReconciler) createResource(ctx context.Context, k8sClient client.Client, gs *templatesv1.GitOpsSet, obj *unstructured.Unstructured) error {
+	if !r.serverSideApply(gs) {
+		return k8sClient.Create(ctx, obj, client.FieldOwner(fieldManager))
+	}
+
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(obj.GroupVersionKind())
+	err := k8sClient.Get(ctx, client.ObjectKeyFromObject(obj), existing)
+	if err == nil {
+		gvk := obj.GroupVersionKind()
+		mapping, err := k8sClient.RESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			return err
+		}
+		return apierrors.NewAlreadyExists(mapping.Resource.GroupResource(), obj.GetName())
+	}
+	if !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	obj.SetManagedFields(nil)
+	obj.SetResourceVersion("")
+
+	return k8sClient.Patch(ctx, obj, client.Apply, applyOptions(gs)...)
+}
+
+// applyOptions returns the options for applying the GitOpsSet's resources.
+func applyOptions(gs *templatesv1.GitOpsSet) []client.PatchOption {
+	opts := []client.PatchOption{client.FieldOwner(fieldManager)}
+	if gs.Spec.ServerSideApply != nil && gs.Spec.ServerSideApply.Force {
+		opts = append(opts, client.ForceOwnership)
+	}
+
+	return opts
+}
+
+func upgradeManagedFields(ctx context.Context, k8sClient client.Client, existing *unstructured.Unstructured, migratedManagers k8ssets.Set[string]) error {
+	patch, err := csaupgrade.UpgradeManagedFieldsPatch(existing, migratedManagers, fieldManager)
+	if err != nil {
+		return fmt.Errorf("failed to calculate managed fields upgrade: %w", err)
+	}
+
+	if patch == nil {
+		return nil
+	}
+
+	if err := k8sClient.Patch(ctx, existing, client.RawPatch(types.JSONPatchType, patch)); err != nil {
+		return fmt.Errorf("failed to upgrade managed fields: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,160 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8ssets "k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	templatesv1 "github.com/weaveworks/gitopssets-controller/api/v1alpha1"
+	"github.com/weaveworks/gitopssets-controller/test"
+)
+
+func TestServerSideApply(t *testing.T) {
+	tests := []struct {
+		name          string
+		defaultApply  bool
+		serverSideApp *templatesv1.ServerSideApply
+		want          bool
+	}{
+		{
+			name: "not configured",
+			want: false,
+		},
+		{
+			name:         "not configured with controller default",
+			defaultApply: true,
+			want:         true,
+		},
+		{
+			name:          "enabled in the GitOpsSet",
+			serverSideApp: &templatesv1.ServerSideApply{Enabled: ptr.To(true)},
+			want:          true,
+		},
+		{
+			name:          "disabled in the GitOpsSet with controller default",
+			defaultApply:  true,
+			serverSideApp: &templatesv1.ServerSideApply{Enabled: ptr.To(false)},
+			want:          false,
+		},
+		{
+			name:          "only force configured in the GitOpsSet",
+			defaultApply:  true,
+			serverSideApp: &templatesv1.ServerSideApply{Force: true},
+			want:          true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &GitOpsSetReconciler{DefaultServerSideApply: tt.defaultApply}
+			gs := &templatesv1.GitOpsSet{
+				Spec: templatesv1.GitOpsSetSpec{
+					ServerSideApply: tt.serverSideApp,
+				},
+			}
+
+			if got := r.serverSideApply(gs); got != tt.want {
+				t.Errorf("serverSideApply() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpgradeManagedFields(t *testing.T) {
+	dataFields := &metav1.FieldsV1{Raw: []byte(`{"f:data":{"f:team":{}}}`)}
+	labelFields := &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:labels":{"f:team":{}}}}`)}
+
+	tests := []struct {
+		name             string
+		managedFields    []metav1.ManagedFieldsEntry
+		migratedManagers k8ssets.Set[string]
+		want             []metav1.ManagedFieldsEntry
+	}{
+		{
+			name: "inventory resource created before the field manager was set",
+			managedFields: []metav1.ManagedFieldsEntry{
+				{Manager: legacyFieldManager, Operation: metav1.ManagedFieldsOperationUpdate, APIVersion: "v1", FieldsType: "FieldsV1", FieldsV1: dataFields},
+				{Manager: "kubectl-edit", Operation: metav1.ManagedFieldsOperationUpdate, APIVersion: "v1", FieldsType: "FieldsV1", FieldsV1: labelFields},
+			},
+			migratedManagers: inventoryFieldManagers,
+			want: []metav1.ManagedFieldsEntry{
+				{Manager: fieldManager, Operation: metav1.ManagedFieldsOperationApply, APIVersion: "v1", FieldsType: "FieldsV1", FieldsV1: dataFields},
+				{Manager: "kubectl-edit", Operation: metav1.ManagedFieldsOperationUpdate, APIVersion: "v1", FieldsType: "FieldsV1", FieldsV1: labelFields},
+			},
+		},
+		{
+			name: "inventory resource updated by the controller",
+			managedFields: []metav1.ManagedFieldsEntry{
+				{Manager: fieldManager, Operation: metav1.ManagedFieldsOperationUpdate, APIVersion: "v1", FieldsType: "FieldsV1", FieldsV1: dataFields},
+			},
+			migratedManagers: inventoryFieldManagers,
+			want: []metav1.ManagedFieldsEntry{
+				{Manager: fieldManager, Operation: metav1.ManagedFieldsOperationApply, APIVersion: "v1", FieldsType: "FieldsV1", FieldsV1: dataFields},
+			},
+		},
+		{
+			name: "adopted resource created by another controller",
+			managedFields: []metav1.ManagedFieldsEntry{
+				{Manager: legacyFieldManager, Operation: metav1.ManagedFieldsOperationUpdate, APIVersion: "v1", FieldsType: "FieldsV1", FieldsV1: dataFields},
+			},
+			migratedManagers: csaFieldManagers,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var patched []metav1.ManagedFieldsEntry
+			k8sClient := fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+				Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+					patched = managedFieldsFromPatch(t, obj, patch)
+					return nil
+				},
+			}).Build()
+			existing := &unstructured.Unstructured{}
+			existing.SetAPIVersion("v1")
+			existing.SetKind("ConfigMap")
+			existing.SetName("test-configmap")
+			existing.SetNamespace("default")
+			existing.SetManagedFields(tt.managedFields)
+
+			test.AssertNoError(t, upgradeManagedFields(context.TODO(), k8sClient, existing, tt.migratedManagers))
+
+			if diff := cmp.Diff(tt.want, patched); diff != "" {
+				t.Fatalf("failed to upgrade managed fields:\n%s", diff)
+			}
+		})
+	}
+}
+
+// managedFieldsFromPatch returns the managed fields that are replaced by the
+// JSON patch.
+func managedFieldsFromPatch(t *testing.T, obj client.Object, patch client.Patch) []metav1.ManagedFieldsEntry {
+	t.Helper()
+	data, err := patch.Data(obj)
+	test.AssertNoError(t, err)
+
+	var ops []struct {
+		Path  string          `json:"path"`
+		Value json.RawMessage `json:"value"`
+	}
+	test.AssertNoError(t, json.Unmarshal(data, &ops))
+
+	for _, op := range ops {
+		if op.Path == "/metadata/managedFields" {
+			var entries []metav1.ManagedFieldsEntry
+			test.AssertNoError(t, json.Unmarshal(op.Value, &entries))
+			return entries
+		}
+	}
+
+	return nil
+}
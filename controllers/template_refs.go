@@ -0,0 +1,215 @@
+package controllers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	sourcev1beta2 "github.com/fluxcd/source-controller/api/v1beta2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	syaml "sigs.k8s.io/yaml"
+
+	templatesv1 "github.com/weaveworks/gitopssets-controller/api/v1alpha1"
+	"github.com/weaveworks/gitopssets-controller/pkg/generators"
+	"github.com/weaveworks/gitopssets-controller/pkg/parser"
+)
+
+// resolveTemplateRefs returns a copy of the GitOpsSet with the templates that
+// are referenced from ConfigMaps and repositories loaded into the templates.
+//
+// If the GitOpsSet doesn't reference any templates, it's returned unchanged.
+func (r *GitOpsSetReconciler) resolveTemplateRefs(ctx context.Context, c client.Reader, gs *templatesv1.GitOpsSet) (*templatesv1.GitOpsSet, error) {
+	if !hasTemplateRefs(gs) {
+		return gs, nil
+	}
+
+	resolved := gs.DeepCopy()
+	resolved.Spec.Templates = nil
+	for _, tmpl := range gs.Spec.Templates {
+		if tmpl.TemplateRef == nil {
+			resolved.Spec.Templates = append(resolved.Spec.Templates, tmpl)
+			continue
+		}
+
+		b, err := r.loadTemplate(ctx, c, gs, tmpl.TemplateRef)
+		if err != nil {
+			return nil, err
+		}
+
+		contents, err := splitTemplates(b)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template from %s %s: %w", tmpl.TemplateRef.Kind, tmpl.TemplateRef.Name, err)
+		}
+
+		for _, content := range contents {
+			resolved.Spec.Templates = append(resolved.Spec.Templates, templatesv1.GitOpsSetTemplate{
+				Repeat:  tmpl.Repeat,
+				When:    tmpl.When,
+				Content: runtime.RawExtension{Raw: content},
+			})
+		}
+	}
+
+	return resolved, nil
+}
+
+func (r *GitOpsSetReconciler) loadTemplate(ctx context.Context, c client.Reader, gs *templatesv1.GitOpsSet, ref *templatesv1.TemplateRef) ([]byte, error) {
+	name := client.ObjectKey{Name: ref.Name, Namespace: gs.GetNamespace()}
+
+	switch ref.Kind {
+	case "ConfigMap":
+		var configMap corev1.ConfigMap
+		if err := c.Get(ctx, name, &configMap); err != nil {
+			return nil, fmt.Errorf("could not load ConfigMap: %w", err)
+		}
+
+		data, ok := configMap.Data[ref.Key]
+		if !ok {
+			return nil, fmt.Errorf("ConfigMap %s has no key %q", name, ref.Key)
+		}
+
+		return []byte(data), nil
+	case "GitRepository":
+		var repo sourcev1.GitRepository
+		if err := c.Get(ctx, name, &repo); err != nil {
+			return nil, fmt.Errorf("could not load GitRepository: %w", err)
+		}
+
+		if repo.Status.Artifact == nil {
+			return nil, generators.ArtifactError("GitRepository", name)
+		}
+
+		return r.readArtifactFile(ctx, "GitRepository/"+name.String(), repo.Status.Artifact.URL, repo.Status.Artifact.Digest, ref.Path)
+	case "OCIRepository":
+		if !r.ociRepositoriesEnabled() {
+			return nil, fmt.Errorf("templateRef %s to OCIRepository is not enabled, enable the OCIRepository generator or OCIRepository templates", name)
+		}
+
+		var repo sourcev1beta2.OCIRepository
+		if err := c.Get(ctx, name, &repo); err != nil {
+			return nil, fmt.Errorf("could not load OCIRepository: %w", err)
+		}
+
+		if repo.Status.Artifact == nil {
+			return nil, generators.ArtifactError("OCIRepository", name)
+		}
+
+		return r.readArtifactFile(ctx, "OCIRepository/"+name.String(), repo.Status.Artifact.URL, repo.Status.Artifact.Digest, ref.Path)
+	}
+
+	return nil, fmt.Errorf("unknown templateRef kind %q", ref.Kind)
+}
+
+// readArtifactFile reads a file from the artifact of a source, the files are
+// cached until the digest of the source's artifact changes.
+func (r *GitOpsSetReconciler) readArtifactFile(ctx context.Context, source, archiveURL, digest, path string) ([]byte, error) {
+	if r.Fetcher == nil {
+		return nil, errors.New("templates can't be loaded from repositories without an archive fetcher")
+	}
+
+	if b, ok := r.artifactFiles.get(source, digest, path); ok {
+		return b, nil
+	}
+
+	b, err := parser.NewRepositoryParser(log.FromContext(ctx), r.Fetcher).ReadFile(ctx, archiveURL, digest, path)
+	if err != nil {
+		return nil, err
+	}
+	r.artifactFiles.add(source, digest, path, b)
+
+	return b, nil
+}
+
+// artifactFileCache caches the files read from the artifacts of sources.
+//
+// Only the files for the latest digest of each source are kept.
+type artifactFileCache struct {
+	mu      sync.Mutex
+	sources map[string]*artifactFiles
+}
+
+type artifactFiles struct {
+	digest string
+	files  map[string][]byte
+}
+
+func (c *artifactFileCache) get(source, digest, path string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, ok := c.sources[source]
+	if !ok || cached.digest != digest {
+		return nil, false
+	}
+	b, ok := cached.files[path]
+
+	return b, ok
+}
+
+func (c *artifactFileCache) add(source, digest, path string, b []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.sources == nil {
+		c.sources = map[string]*artifactFiles{}
+	}
+
+	cached, ok := c.sources[source]
+	if !ok || cached.digest != digest {
+		cached = &artifactFiles{digest: digest, files: map[string][]byte{}}
+		c.sources[source] = cached
+	}
+	cached.files[path] = b
+}
+
+// splitTemplates splits the YAML documents into separate templates, each
+// converted to JSON in the same way as templates in the GitOpsSet.
+func splitTemplates(b []byte) ([][]byte, error) {
+	reader := yaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(b)))
+
+	var contents [][]byte
+	for {
+		doc, err := reader.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+
+		content, err := syaml.YAMLToJSON(doc)
+		if err != nil {
+			return nil, err
+		}
+
+		if string(content) == "null" {
+			continue
+		}
+		contents = append(contents, content)
+	}
+
+	return contents, nil
+}
+
+func hasTemplateRefs(gs *templatesv1.GitOpsSet) bool {
+	for _, tmpl := range gs.Spec.Templates {
+		if tmpl.TemplateRef != nil {
+			return true
+		}
+	}
+
+	return false
+}
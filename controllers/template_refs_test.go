@@ -0,0 +1,248 @@
+package controllers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fluxcd/pkg/http/fetch"
+	"github.com/fluxcd/pkg/tar"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	sourcev1beta2 "github.com/fluxcd/source-controller/api/v1beta2"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	templatesv1 "github.com/weaveworks/gitopssets-controller/api/v1alpha1"
+	"github.com/weaveworks/gitopssets-controller/test"
+)
+
+func TestResolveTemplateRefs(t *testing.T) {
+	srv := test.StartFakeArchiveServer(t, "testdata/archive")
+	checksum, err := os.ReadFile("testdata/archive/templates.tar.gz.sum")
+	test.AssertNoError(t, err)
+
+	inlineTemplate := templatesv1.GitOpsSetTemplate{
+		Content: runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","kind":"Namespace","metadata":{"name":"{{ .Element.env }}"}}`)},
+	}
+
+	tests := []struct {
+		name      string
+		templates []templatesv1.GitOpsSetTemplate
+		want      []templatesv1.GitOpsSetTemplate
+	}{
+		{
+			name:      "no referenced templates",
+			templates: []templatesv1.GitOpsSetTemplate{inlineTemplate},
+			want:      []templatesv1.GitOpsSetTemplate{inlineTemplate},
+		},
+		{
+			name: "template from a ConfigMap",
+			templates: []templatesv1.GitOpsSetTemplate{
+				inlineTemplate,
+				{
+					When:        "element.public",
+					TemplateRef: &templatesv1.TemplateRef{Kind: "ConfigMap", Name: "templates", Key: "service.yaml"},
+				},
+			},
+			want: []templatesv1.GitOpsSetTemplate{
+				inlineTemplate,
+				{
+					When:    "element.public",
+					Content: runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","kind":"Service","metadata":{"name":"{{ .Element.env }}-demo"}}`)},
+				},
+			},
+		},
+		{
+			name: "templates from a GitRepository",
+			templates: []templatesv1.GitOpsSetTemplate{
+				{
+					TemplateRef: &templatesv1.TemplateRef{Kind: "GitRepository", Name: "templates-repo", Path: "templates/configmaps.yaml"},
+				},
+			},
+			want: []templatesv1.GitOpsSetTemplate{
+				{
+					Content: runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","data":{"env":"{{ .Element.env }}"},"kind":"ConfigMap","metadata":{"name":"{{ .Element.env }}-config"}}`)},
+				},
+				{
+					Content: runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","data":{"team":"{{ .Element.team }}"},"kind":"ConfigMap","metadata":{"name":"{{ .Element.env }}-settings"}}`)},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k8sClient := newTemplateRefsClient(t,
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: "templates", Namespace: "default"},
+					Data: map[string]string{
+						"service.yaml": "apiVersion: v1\nkind: Service\nmetadata:\n  name: \"{{ .Element.env }}-demo\"\n",
+					},
+				},
+				&sourcev1.GitRepository{
+					ObjectMeta: metav1.ObjectMeta{Name: "templates-repo", Namespace: "default"},
+					Status: sourcev1.GitRepositoryStatus{
+						Artifact: &sourcev1.Artifact{
+							URL:    srv.URL + "/templates.tar.gz",
+							Digest: strings.TrimSpace(string(checksum)),
+						},
+					},
+				})
+			r := &GitOpsSetReconciler{
+				Fetcher: fetch.NewArchiveFetcher(1, tar.UnlimitedUntarSize, tar.UnlimitedUntarSize, ""),
+			}
+			gs := &templatesv1.GitOpsSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-set", Namespace: "default"},
+				Spec: templatesv1.GitOpsSetSpec{
+					Templates: tt.templates,
+				},
+			}
+
+			resolved, err := r.resolveTemplateRefs(context.TODO(), k8sClient, gs)
+			test.AssertNoError(t, err)
+
+			if diff := cmp.Diff(tt.want, resolved.Spec.Templates); diff != "" {
+				t.Fatalf("failed to resolve templates:\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.templates, gs.Spec.Templates); diff != "" {
+				t.Fatalf("templates in the GitOpsSet were modified:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestResolveTemplateRefs_errors(t *testing.T) {
+	tests := []struct {
+		name         string
+		ref          *templatesv1.TemplateRef
+		ociTemplates bool
+		wantErr      string
+	}{
+		{
+			name:    "missing ConfigMap",
+			ref:     &templatesv1.TemplateRef{Kind: "ConfigMap", Name: "missing", Key: "service.yaml"},
+			wantErr: `could not load ConfigMap: configmaps "missing" not found`,
+		},
+		{
+			name:    "missing key in the ConfigMap",
+			ref:     &templatesv1.TemplateRef{Kind: "ConfigMap", Name: "templates", Key: "missing.yaml"},
+			wantErr: `ConfigMap default/templates has no key "missing.yaml"`,
+		},
+		{
+			name:    "invalid YAML in the ConfigMap",
+			ref:     &templatesv1.TemplateRef{Kind: "ConfigMap", Name: "templates", Key: "invalid.yaml"},
+			wantErr: `failed to parse template from ConfigMap templates: error converting YAML to JSON`,
+		},
+		{
+			name:    "GitRepository without an artifact",
+			ref:     &templatesv1.TemplateRef{Kind: "GitRepository", Name: "templates-repo", Path: "templates/configmaps.yaml"},
+			wantErr: `no artifact for GitRepository default/templates-repo`,
+		},
+		{
+			name:    "OCIRepository templates not enabled",
+			ref:     &templatesv1.TemplateRef{Kind: "OCIRepository", Name: "templates-repo", Path: "templates/configmaps.yaml"},
+			wantErr: `templateRef default/templates-repo to OCIRepository is not enabled`,
+		},
+		{
+			name:         "missing OCIRepository",
+			ref:          &templatesv1.TemplateRef{Kind: "OCIRepository", Name: "missing", Path: "templates/configmaps.yaml"},
+			ociTemplates: true,
+			wantErr:      `could not load OCIRepository: ocirepositories.source.toolkit.fluxcd.io "missing" not found`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k8sClient := newTemplateRefsClient(t,
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: "templates", Namespace: "default"},
+					Data: map[string]string{
+						"invalid.yaml": "name: {{ .Element.env }}",
+					},
+				},
+				&sourcev1.GitRepository{
+					ObjectMeta: metav1.ObjectMeta{Name: "templates-repo", Namespace: "default"},
+				})
+			r := &GitOpsSetReconciler{OCIRepositoryTemplates: tt.ociTemplates}
+			gs := &templatesv1.GitOpsSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-set", Namespace: "default"},
+				Spec: templatesv1.GitOpsSetSpec{
+					Templates: []templatesv1.GitOpsSetTemplate{{TemplateRef: tt.ref}},
+				},
+			}
+
+			_, err := r.resolveTemplateRefs(context.TODO(), k8sClient, gs)
+
+			test.AssertErrorMatch(t, tt.wantErr, err)
+		})
+	}
+}
+
+func TestResolveTemplateRefs_caches_artifact_files(t *testing.T) {
+	repo := &sourcev1.GitRepository{
+		ObjectMeta: metav1.ObjectMeta{Name: "templates-repo", Namespace: "default"},
+		Status: sourcev1.GitRepositoryStatus{
+			Artifact: &sourcev1.Artifact{
+				URL:    "http://example.com/templates.tar.gz",
+				Digest: "sha256:1",
+			},
+		},
+	}
+	k8sClient := newTemplateRefsClient(t, repo)
+	fetcher := &countingFetcher{}
+	r := &GitOpsSetReconciler{Fetcher: fetcher}
+	gs := &templatesv1.GitOpsSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-set", Namespace: "default"},
+		Spec: templatesv1.GitOpsSetSpec{
+			Templates: []templatesv1.GitOpsSetTemplate{
+				{TemplateRef: &templatesv1.TemplateRef{Kind: "GitRepository", Name: "templates-repo", Path: "template.yaml"}},
+			},
+		},
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err := r.resolveTemplateRefs(context.TODO(), k8sClient, gs)
+		test.AssertNoError(t, err)
+	}
+	if fetcher.fetches != 1 {
+		t.Fatalf("got %d fetches for the same artifact, want 1", fetcher.fetches)
+	}
+
+	repo.Status.Artifact.Digest = "sha256:2"
+	test.AssertNoError(t, k8sClient.Status().Update(context.TODO(), repo))
+
+	_, err := r.resolveTemplateRefs(context.TODO(), k8sClient, gs)
+	test.AssertNoError(t, err)
+	if fetcher.fetches != 2 {
+		t.Fatalf("got %d fetches after the artifact changed, want 2", fetcher.fetches)
+	}
+}
+
+// countingFetcher writes a template to the archive directory and counts the
+// archives that are fetched.
+type countingFetcher struct {
+	fetches int
+}
+
+func (f *countingFetcher) Fetch(archiveURL, checksum, dir string) error {
+	f.fetches++
+
+	return os.WriteFile(filepath.Join(dir, "template.yaml"), []byte("apiVersion: v1\nkind: ConfigMap\n"), 0o600)
+}
+
+func newTemplateRefsClient(t *testing.T, objs ...runtime.Object) client.WithWatch {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	test.AssertNoError(t, clientgoscheme.AddToScheme(scheme))
+	test.AssertNoError(t, sourcev1.AddToScheme(scheme))
+	test.AssertNoError(t, sourcev1beta2.AddToScheme(scheme))
+
+	return fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objs...).WithStatusSubresource(&sourcev1.GitRepository{}).Build()
+}
@@ -3,14 +3,14 @@ package templates
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
 	"text/template"
 
 	"dario.cat/mergo"
-	"github.com/Masterminds/sprig/v3"
-	"github.com/gitops-tools/pkg/sanitize"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	yamlserializer "k8s.io/apimachinery/pkg/runtime/serializer/yaml"
@@ -20,6 +20,9 @@ import (
 
 	templatesv1 "github.com/weaveworks/gitopssets-controller/api/v1alpha1"
 	"github.com/weaveworks/gitopssets-controller/pkg/generators"
+	"github.com/weaveworks/gitopssets-controller/pkg/generators/filter"
+	"github.com/weaveworks/gitopssets-controller/pkg/generators/postprocess"
+	"github.com/weaveworks/gitopssets-controller/pkg/templatefuncs"
 )
 
 // TemplateDelimiterAnnotation can be added to a Template to change the Go
@@ -30,36 +33,87 @@ import (
 // {{ and }}.
 const TemplateDelimiterAnnotation string = "sets.gitops.pro/delimiters"
 
-var templateFuncs template.FuncMap = makeTemplateFunctions()
+var templateFuncs template.FuncMap = templatefuncs.FuncMap()
 
 // Render parses the GitOpsSet and renders the template resources using
 // the configured generators and templates.
 func Render(ctx context.Context, r *templatesv1.GitOpsSet, configuredGenerators map[string]generators.Generator) ([]*unstructured.Unstructured, error) {
+	elements, err := RenderElements(ctx, r, configuredGenerators)
+	if err != nil {
+		return nil, err
+	}
+
 	rendered := []*unstructured.Unstructured{}
+	for _, element := range elements {
+		if element.Err != nil {
+			return nil, element.Err
+		}
+		rendered = append(rendered, element.Resources...)
+	}
 
+	return rendered, nil
+}
+
+// RenderedElement is an element generated for a GitOpsSet, and the resources
+// rendered from it.
+type RenderedElement struct {
+	Element   map[string]any
+	Resources []*unstructured.Unstructured
+
+	// Err is the error rendering the templates for the element when the
+	// GitOpsSet continues on errors, no resources are rendered for the element.
+	Err error
+}
+
+// RenderElements parses the GitOpsSet and renders the template resources for
+// each generated element.
+//
+// If the GitOpsSet continues on errors, the elements from the generators that
+// succeeded are returned with the errors from the generators that failed.
+func RenderElements(ctx context.Context, r *templatesv1.GitOpsSet, configuredGenerators map[string]generators.Generator) ([]RenderedElement, error) {
+	rendered := []RenderedElement{}
+	continueOnError := r.Spec.ErrorPolicy == templatesv1.ErrorPolicyContinue
+
+	var generatorErr error
 	index := 0
 	for _, gen := range r.Spec.Generators {
 		generated, err := generate(ctx, gen, configuredGenerators, r)
 		if err != nil {
-			return nil, fmt.Errorf("failed to generate template for set %s: %w", r.GetName(), err)
+			err = fmt.Errorf("failed to generate template for set %s: %w", r.GetName(), err)
+			if !continueOnError {
+				return nil, err
+			}
+			generatorErr = errors.Join(generatorErr, err)
+			continue
 		}
 
 		for _, params := range generated {
 			for _, param := range params {
+				element := RenderedElement{Element: param}
 				for _, template := range r.Spec.Templates {
 					res, err := renderTemplateParams(index, template, param, *r)
+					index++
 					if err != nil {
-						return nil, fmt.Errorf("failed to render template params for set %s: %w", r.GetName(), err)
+						err = fmt.Errorf("failed to render template params for set %s: %w", r.GetName(), err)
+						if !continueOnError {
+							return nil, err
+						}
+						element.Err = errors.Join(element.Err, err)
+						continue
 					}
 
-					rendered = append(rendered, res...)
-					index++
+					element.Resources = append(element.Resources, res...)
+				}
+
+				if element.Err != nil {
+					element.Resources = nil
 				}
+				rendered = append(rendered, element)
 			}
 		}
 	}
 
-	return rendered, nil
+	return rendered, generatorErr
 }
 
 func repeat(index int, tmpl templatesv1.GitOpsSetTemplate, params map[string]any) ([]map[string]any, error) {
@@ -114,6 +168,18 @@ func repeat(index int, tmpl templatesv1.GitOpsSetTemplate, params map[string]any
 func renderTemplateParams(index int, tmpl templatesv1.GitOpsSetTemplate, params map[string]any, gs templatesv1.GitOpsSet) ([]*unstructured.Unstructured, error) {
 	var objects []*unstructured.Unstructured
 
+	if tmpl.TemplateRef != nil {
+		return nil, fmt.Errorf("template referenced from %s %s has not been loaded", tmpl.TemplateRef.Kind, tmpl.TemplateRef.Name)
+	}
+
+	include, err := when(tmpl, params)
+	if err != nil {
+		return nil, err
+	}
+	if !include {
+		return nil, nil
+	}
+
 	repeatedParams, err := repeat(index, tmpl, params)
 	if err != nil {
 		return nil, err
@@ -188,16 +254,56 @@ func renderTemplateParams(index int, tmpl templatesv1.GitOpsSetTemplate, params
 	return objects, nil
 }
 
+// when evaluates the When expression on the template against the element.
+//
+// If the template has no When expression, it's rendered for all elements.
+func when(tmpl templatesv1.GitOpsSetTemplate, params map[string]any) (bool, error) {
+	if tmpl.When == "" {
+		return true, nil
+	}
+
+	program, err := filter.Compile(tmpl.When)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse when on template: %w", err)
+	}
+
+	out, _, err := program.Eval(map[string]any{filter.ElementVariable: params})
+	if err != nil {
+		return false, fmt.Errorf("failed to evaluate when %q: %w", tmpl.When, err)
+	}
+
+	include, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("when %q must evaluate to a bool, got %s", tmpl.When, out.Type().TypeName())
+	}
+
+	return include, nil
+}
+
 func render(b []byte, params map[string]any, gs templatesv1.GitOpsSet) ([]byte, error) {
-	t, err := template.New(fmt.Sprintf("%s/%s", gs.GetNamespace(), gs.GetName())).
+	t := template.New(fmt.Sprintf("%s/%s", gs.GetNamespace(), gs.GetName())).
 		Option("missingkey=error").
 		Delims(templateDelims(gs)).
-		Funcs(templateFuncs).Parse(string(b))
+		Funcs(templateFuncs)
+	t = t.Funcs(template.FuncMap{"include": includeFunc(t)})
+
+	for _, partial := range gs.Spec.Partials {
+		if _, err := t.New(partial.Name).Parse(partial.Template); err != nil {
+			return nil, fmt.Errorf("failed to parse partial %q: %w", partial.Name, err)
+		}
+	}
+
+	t, err := t.Parse(string(b))
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse template: %w", err)
 	}
 
-	if err := mergo.Merge(&params, templateParams(gs), mergo.WithOverride); err != nil {
+	gsParams, err := templateParams(gs)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := mergo.Merge(&params, gsParams, mergo.WithOverride); err != nil {
 		return nil, fmt.Errorf("failed to generate context when rendering template: %w", err)
 	}
 
@@ -209,13 +315,69 @@ func render(b []byte, params map[string]any, gs templatesv1.GitOpsSet) ([]byte,
 	return out.Bytes(), nil
 }
 
-func templateParams(gs templatesv1.GitOpsSet) map[string]any {
+// maxIncludeDepth is the maximum number of times that a named template can be
+// included while it is being rendered, this stops partials that include
+// themselves from recursing forever.
+const maxIncludeDepth = 1000
+
+// includeFunc returns a template function that renders a named template to a
+// string, so that the output can be piped to other functions e.g.
+// `{{ include "labels" . | nindent 4 }}`.
+func includeFunc(t *template.Template) func(string, any) (string, error) {
+	included := map[string]int{}
+
+	return func(name string, data any) (string, error) {
+		if included[name] >= maxIncludeDepth {
+			return "", includeDepthError{name: name}
+		}
+		included[name]++
+		defer func() { included[name]-- }()
+
+		var out bytes.Buffer
+		if err := t.ExecuteTemplate(&out, name, data); err != nil {
+			// The error is returned without the context from each of the
+			// nested includes, otherwise the message grows with the depth.
+			var depthErr includeDepthError
+			if errors.As(err, &depthErr) {
+				return "", depthErr
+			}
+
+			return "", err
+		}
+
+		return out.String(), nil
+	}
+}
+
+// includeDepthError is returned when a named template is included more than
+// maxIncludeDepth times.
+type includeDepthError struct {
+	name string
+}
+
+func (e includeDepthError) Error() string {
+	return fmt.Sprintf("rendering template has a nested reference name: %s: unable to execute template", e.name)
+}
+
+func templateParams(gs templatesv1.GitOpsSet) (map[string]any, error) {
+	if len(gs.Spec.ValuesFrom) > 0 {
+		return nil, errors.New("values referenced from ConfigMaps and Secrets have not been loaded")
+	}
+
+	values := map[string]any{}
+	if gs.Spec.Values != nil {
+		if err := json.Unmarshal(gs.Spec.Values.Raw, &values); err != nil {
+			return nil, fmt.Errorf("failed to parse values: %w", err)
+		}
+	}
+
 	return map[string]any{
 		"GitOpsSet": map[string]any{
 			"Name":      gs.GetName(),
 			"Namespace": gs.GetNamespace(),
 		},
-	}
+		"Values": values,
+	}, nil
 }
 
 func generate(ctx context.Context, generator templatesv1.GitOpsSetGenerator, allGenerators map[string]generators.Generator, gitopsSet *templatesv1.GitOpsSet) ([][]map[string]any, error) {
@@ -230,40 +392,20 @@ func generate(ctx context.Context, generator templatesv1.GitOpsSetGenerator, all
 			return nil, err
 		}
 
-		generated = append(generated, res)
-	}
-
-	return generated, nil
-}
-
-func makeTemplateFunctions() template.FuncMap {
-	f := sprig.TxtFuncMap()
-	unwanted := []string{
-		"env", "expandenv", "getHostByName", "genPrivateKey", "derivePassword", "sha256sum",
-		"base", "dir", "ext", "clean", "isAbs", "osBase", "osDir", "osExt", "osClean", "osIsAbs"}
-
-	for _, v := range unwanted {
-		delete(f, v)
-	}
-
-	f["sanitize"] = sanitize.SanitizeDNSName
-	f["getordefault"] = func(element map[string]any, key string, def interface{}) interface{} {
-		if v, ok := element[key]; ok {
-			return v
+		res, err = filter.Elements(generator.Filter, res)
+		if err != nil {
+			return nil, err
 		}
 
-		return def
-	}
-	f["toYaml"] = func(v interface{}) string {
-		data, err := syaml.Marshal(v)
+		res, err = postprocess.Elements(generator.PostProcess, res)
 		if err != nil {
-			// Swallow errors inside of a template.
-			return ""
+			return nil, err
 		}
-		return strings.TrimSuffix(string(data), "\n")
+
+		generated = append(generated, res)
 	}
 
-	return f
+	return generated, nil
 }
 
 func templateDelims(gs templatesv1.GitOpsSet) (string, string) {
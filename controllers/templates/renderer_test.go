@@ -259,6 +259,40 @@ func TestRender(t *testing.T) {
 				))),
 			},
 		},
+		{
+			name: "conditional templates",
+			elements: []apiextensionsv1.JSON{
+				{Raw: []byte(`{"env": "engineering-dev","externalIP": "192.168.50.50","public": false}`)},
+				{Raw: []byte(`{"env": "engineering-prod","externalIP": "192.168.100.20","public": true}`)},
+			},
+			setOptions: []func(*templatesv1.GitOpsSet){
+				func(s *templatesv1.GitOpsSet) {
+					s.Spec.Templates = []templatesv1.GitOpsSetTemplate{
+						{
+							Content: runtime.RawExtension{
+								Raw: mustMarshalJSON(t, makeTestService(types.NamespacedName{Name: "{{ .Element.env }}-demo", Namespace: testNS})),
+							},
+						},
+						{
+							When: "element.public",
+							Content: runtime.RawExtension{
+								Raw: mustMarshalJSON(t, makeTestNamespace("{{ .Element.env }}-public")),
+							},
+						},
+					}
+				},
+			},
+			want: []*unstructured.Unstructured{
+				test.ToUnstructured(t, makeTestService(nsn(testNS, "engineering-dev-demo"), setClusterIP("192.168.50.50"),
+					addAnnotations(map[string]string{"app.kubernetes.io/instance": "engineering-dev"}),
+					addLabels[*corev1.Service](map[string]string{"sets.gitops.pro/name": "test-gitops-set", "sets.gitops.pro/namespace": testNS}))),
+				test.ToUnstructured(t, makeTestService(nsn(testNS, "engineering-prod-demo"), setClusterIP("192.168.100.20"),
+					addAnnotations(map[string]string{"app.kubernetes.io/instance": "engineering-prod"}),
+					addLabels[*corev1.Service](map[string]string{"sets.gitops.pro/name": "test-gitops-set", "sets.gitops.pro/namespace": testNS}))),
+				test.ToUnstructured(t, makeTestNamespace("engineering-prod-public",
+					addLabels[*corev1.Namespace](map[string]string{"sets.gitops.pro/name": "test-gitops-set", "sets.gitops.pro/namespace": testNS}))),
+			},
+		},
 		{
 			name: "template with labels merged with default labels",
 			elements: []apiextensionsv1.JSON{
@@ -496,6 +530,80 @@ func TestRender(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "shared values",
+			elements: []apiextensionsv1.JSON{
+				{Raw: []byte(`{"env": "engineering-dev","externalIP": "192.168.50.50"}`)},
+			},
+			setOptions: []func(*templatesv1.GitOpsSet){
+				func(s *templatesv1.GitOpsSet) {
+					s.Spec.Values = &apiextensionsv1.JSON{Raw: []byte(`{"domain":{"suffix":"example.com"}}`)}
+					s.Spec.Templates = []templatesv1.GitOpsSetTemplate{
+						{
+							Content: runtime.RawExtension{
+								Raw: mustMarshalJSON(t, makeTestService(types.NamespacedName{Name: "{{ .Element.env }}-demo", Namespace: testNS},
+									addAnnotations(map[string]string{
+										"app.kubernetes.io/instance": "{{ .Element.env }}",
+										"example.com/hostname":       "{{ .Element.env }}.{{ .Values.domain.suffix }}",
+									}))),
+							},
+						},
+					}
+				},
+			},
+			want: []*unstructured.Unstructured{
+				test.ToUnstructured(t, makeTestService(nsn(testNS, "engineering-dev-demo"), setClusterIP("192.168.50.50"),
+					addAnnotations(map[string]string{"app.kubernetes.io/instance": "engineering-dev", "example.com/hostname": "engineering-dev.example.com"}),
+					addLabels[*corev1.Service](map[string]string{"sets.gitops.pro/name": "test-gitops-set", "sets.gitops.pro/namespace": testNS}))),
+			},
+		},
+		{
+			name: "included partials",
+			elements: []apiextensionsv1.JSON{
+				{Raw: []byte(`{"env":"testing","team":"engineering"}`)},
+			},
+			setOptions: []func(*templatesv1.GitOpsSet){
+				func(s *templatesv1.GitOpsSet) {
+					s.ObjectMeta.Annotations = map[string]string{
+						"sets.gitops.pro/delimiters": "((,))",
+					}
+					s.Spec.Partials = []templatesv1.TemplatePartial{
+						{
+							Name:     "labels",
+							Template: "app.kubernetes.io/instance: (( .Element.env ))\nexample.com/team: (( include \"team\" . ))",
+						},
+						{
+							Name:     "team",
+							Template: "(( .Element.team ))",
+						},
+					}
+					s.Spec.Templates = []templatesv1.GitOpsSetTemplate{
+						{
+							Content: runtime.RawExtension{
+								Raw: []byte(`{"apiVersion":"v1","kind":"Namespace","metadata":{"labels":"(( include \"labels\" . | nindent 4 ))","name":"(( .Element.env ))"}}`),
+							},
+						},
+					}
+				},
+			},
+			want: []*unstructured.Unstructured{
+				{
+					Object: map[string]interface{}{
+						"apiVersion": "v1",
+						"kind":       "Namespace",
+						"metadata": map[string]interface{}{
+							"name": "testing",
+							"labels": map[string]interface{}{
+								"app.kubernetes.io/instance": "testing",
+								"example.com/team":           "engineering",
+								"sets.gitops.pro/name":       "test-gitops-set",
+								"sets.gitops.pro/namespace":  "demo",
+							},
+						},
+					},
+				},
+			},
+		},
 	}
 
 	for _, tt := range generatorTests {
@@ -600,6 +708,95 @@ func TestRender_errors(t *testing.T) {
 			},
 			wantErr: `failed to render template.*at <.element.env>: map has no entry for key "element"`,
 		},
+		{
+			name: "values referenced from ConfigMaps that have not been loaded",
+			setOptions: []func(*templatesv1.GitOpsSet){
+				listElements([]apiextensionsv1.JSON{
+					{Raw: []byte(`{"env": "engineering-dev","externalIP": "192.168.50.50"}`)},
+				}),
+				func(gs *templatesv1.GitOpsSet) {
+					gs.Spec.ValuesFrom = []templatesv1.ValuesReference{{Kind: "ConfigMap", Name: "platform-values"}}
+				},
+			},
+			wantErr: `values referenced from ConfigMaps and Secrets have not been loaded`,
+		},
+		{
+			name: "bad partial",
+			setOptions: []func(*templatesv1.GitOpsSet){
+				listElements([]apiextensionsv1.JSON{
+					{Raw: []byte(`{"env": "engineering-dev","externalIP": "192.168.50.50"}`)},
+				}),
+				func(gs *templatesv1.GitOpsSet) {
+					gs.Spec.Partials = []templatesv1.TemplatePartial{
+						{Name: "labels", Template: "{{ .Element.env | tested }}"},
+					}
+				},
+			},
+			wantErr: `failed to parse partial "labels": template: labels:1: function "tested" not defined`,
+		},
+		{
+			name: "including a missing partial",
+			setOptions: []func(*templatesv1.GitOpsSet){
+				listElements([]apiextensionsv1.JSON{
+					{Raw: []byte(`{"env": "engineering-dev","externalIP": "192.168.50.50"}`)},
+				}),
+				func(gs *templatesv1.GitOpsSet) {
+					gs.Spec.Templates = []templatesv1.GitOpsSetTemplate{
+						{
+							Content: runtime.RawExtension{
+								Raw: mustMarshalJSON(t, makeTestService(types.NamespacedName{Name: `{{ include "name" . }}`})),
+							},
+						},
+					}
+				},
+			},
+			wantErr: `failed to render template.*error calling include: template: no template "name" associated with template`,
+		},
+		{
+			name: "partial that includes itself",
+			setOptions: []func(*templatesv1.GitOpsSet){
+				listElements([]apiextensionsv1.JSON{
+					{Raw: []byte(`{"env": "engineering-dev","externalIP": "192.168.50.50"}`)},
+				}),
+				func(gs *templatesv1.GitOpsSet) {
+					gs.Spec.Partials = []templatesv1.TemplatePartial{
+						{Name: "name", Template: `{{ include "name" . }}`},
+					}
+					gs.Spec.Templates = []templatesv1.GitOpsSetTemplate{
+						{
+							Content: runtime.RawExtension{
+								Raw: mustMarshalJSON(t, makeTestService(types.NamespacedName{Name: `{{ include "name" . }}`})),
+							},
+						},
+					}
+				},
+			},
+			wantErr: `failed to render template.*error calling include: rendering template has a nested reference name: name: unable to execute template$`,
+		},
+		{
+			name: "invalid when expression",
+			setOptions: []func(*templatesv1.GitOpsSet){
+				listElements([]apiextensionsv1.JSON{
+					{Raw: []byte(`{"env": "engineering-dev","externalIP": "192.168.50.50"}`)},
+				}),
+				func(gs *templatesv1.GitOpsSet) {
+					gs.Spec.Templates[0].When = "element.public =="
+				},
+			},
+			wantErr: `failed to parse when on template: failed to compile expression "element.public =="`,
+		},
+		{
+			name: "when expression that isn't a bool",
+			setOptions: []func(*templatesv1.GitOpsSet){
+				listElements([]apiextensionsv1.JSON{
+					{Raw: []byte(`{"env": "engineering-dev","externalIP": "192.168.50.50"}`)},
+				}),
+				func(gs *templatesv1.GitOpsSet) {
+					gs.Spec.Templates[0].When = "element.env"
+				},
+			},
+			wantErr: `when "element.env" must evaluate to a bool, got string`,
+		},
 	}
 	testGenerators := map[string]generators.Generator{
 		"List": list.NewGenerator(logr.Discard()),
@@ -615,6 +812,89 @@ func TestRender_errors(t *testing.T) {
 	}
 }
 
+func TestRenderElements(t *testing.T) {
+	testGenerators := map[string]generators.Generator{
+		"List": list.NewGenerator(logr.Discard()),
+	}
+	gset := makeTestGitOpsSet(t, listElements([]apiextensionsv1.JSON{
+		{Raw: []byte(`{"env": "engineering-dev","externalIP": "192.168.50.50"}`)},
+		{Raw: []byte(`{"env": "engineering-prod","externalIP": "192.168.100.20"}`)},
+	}))
+
+	elements, err := RenderElements(context.TODO(), gset, testGenerators)
+	test.AssertNoError(t, err)
+
+	want := []RenderedElement{
+		{
+			Element: map[string]any{"env": "engineering-dev", "externalIP": "192.168.50.50"},
+			Resources: []*unstructured.Unstructured{
+				test.ToUnstructured(t, makeTestService(nsn(testNS, "engineering-dev-demo"), setClusterIP("192.168.50.50"),
+					addAnnotations(map[string]string{"app.kubernetes.io/instance": "engineering-dev"}),
+					addLabels[*corev1.Service](map[string]string{"sets.gitops.pro/name": "test-gitops-set", "sets.gitops.pro/namespace": testNS}))),
+			},
+		},
+		{
+			Element: map[string]any{"env": "engineering-prod", "externalIP": "192.168.100.20"},
+			Resources: []*unstructured.Unstructured{
+				test.ToUnstructured(t, makeTestService(nsn(testNS, "engineering-prod-demo"), setClusterIP("192.168.100.20"),
+					addAnnotations(map[string]string{"app.kubernetes.io/instance": "engineering-prod"}),
+					addLabels[*corev1.Service](map[string]string{"sets.gitops.pro/name": "test-gitops-set", "sets.gitops.pro/namespace": testNS}))),
+			},
+		},
+	}
+	if diff := cmp.Diff(want, elements); diff != "" {
+		t.Fatalf("failed to render elements:\n%s", diff)
+	}
+}
+
+func TestRenderElements_continue_on_error(t *testing.T) {
+	testGenerators := map[string]generators.Generator{
+		"List": list.NewGenerator(logr.Discard()),
+	}
+	gset := makeTestGitOpsSet(t,
+		listElements([]apiextensionsv1.JSON{
+			{Raw: []byte(`{"env": "engineering-dev","externalIP": "192.168.50.50"}`)},
+			{Raw: []byte(`{"env": "engineering-prod"}`)},
+		}),
+		listElements([]apiextensionsv1.JSON{
+			{Raw: []byte(`{"env": `)},
+		}),
+		func(gs *templatesv1.GitOpsSet) {
+			gs.Spec.ErrorPolicy = templatesv1.ErrorPolicyContinue
+		})
+
+	elements, err := RenderElements(context.TODO(), gset, testGenerators)
+	test.AssertErrorMatch(t, "failed to generate template for set test-gitops-set: error unmarshaling list element", err)
+
+	if l := len(elements); l != 2 {
+		t.Fatalf("got %d elements, want 2", l)
+	}
+	if elements[0].Err != nil || len(elements[0].Resources) != 1 {
+		t.Errorf("got error %v and %d resources for the first element, want 1 resource", elements[0].Err, len(elements[0].Resources))
+	}
+	test.AssertErrorMatch(t, `map has no entry for key "externalIP"`, elements[1].Err)
+	if elements[1].Resources != nil {
+		t.Errorf("got resources for the failed element: %v", elements[1].Resources)
+	}
+}
+
+func TestRenderElements_fail_fast(t *testing.T) {
+	testGenerators := map[string]generators.Generator{
+		"List": list.NewGenerator(logr.Discard()),
+	}
+	gset := makeTestGitOpsSet(t, listElements([]apiextensionsv1.JSON{
+		{Raw: []byte(`{"env": "engineering-dev","externalIP": "192.168.50.50"}`)},
+		{Raw: []byte(`{"env": "engineering-prod"}`)},
+	}))
+
+	elements, err := RenderElements(context.TODO(), gset, testGenerators)
+	test.AssertErrorMatch(t, `map has no entry for key "externalIP"`, err)
+
+	if elements != nil {
+		t.Errorf("got elements %v, want nil", elements)
+	}
+}
+
 func TestRender_disabled(t *testing.T) {
 	gset := makeTestGitOpsSet(t)
 	// no generators available
@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"dario.cat/mergo"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	syaml "sigs.k8s.io/yaml"
+
+	templatesv1 "github.com/weaveworks/gitopssets-controller/api/v1alpha1"
+)
+
+// resolveValues returns a copy of the GitOpsSet with the values referenced
+// from ConfigMaps and Secrets merged into the values.
+//
+// If the GitOpsSet doesn't reference any values, it's returned unchanged.
+func resolveValues(ctx context.Context, c client.Reader, gs *templatesv1.GitOpsSet) (*templatesv1.GitOpsSet, error) {
+	if len(gs.Spec.ValuesFrom) == 0 {
+		return gs, nil
+	}
+
+	values := map[string]any{}
+	for _, ref := range gs.Spec.ValuesFrom {
+		loaded, err := loadValues(ctx, c, gs, ref)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := mergo.Merge(&values, loaded, mergo.WithOverride); err != nil {
+			return nil, fmt.Errorf("failed to merge values from %s %s: %w", ref.Kind, ref.Name, err)
+		}
+	}
+
+	if gs.Spec.Values != nil {
+		inline := map[string]any{}
+		if err := json.Unmarshal(gs.Spec.Values.Raw, &inline); err != nil {
+			return nil, fmt.Errorf("failed to parse values: %w", err)
+		}
+
+		if err := mergo.Merge(&values, inline, mergo.WithOverride); err != nil {
+			return nil, fmt.Errorf("failed to merge values: %w", err)
+		}
+	}
+
+	b, err := json.Marshal(values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge values: %w", err)
+	}
+
+	resolved := gs.DeepCopy()
+	resolved.Spec.Values = &apiextensionsv1.JSON{Raw: b}
+	resolved.Spec.ValuesFrom = nil
+
+	return resolved, nil
+}
+
+func loadValues(ctx context.Context, c client.Reader, gs *templatesv1.GitOpsSet, ref templatesv1.ValuesReference) (map[string]any, error) {
+	name := client.ObjectKey{Name: ref.Name, Namespace: gs.GetNamespace()}
+
+	var data map[string]string
+	switch ref.Kind {
+	case "ConfigMap":
+		var configMap corev1.ConfigMap
+		if err := c.Get(ctx, name, &configMap); err != nil {
+			return nil, fmt.Errorf("could not load ConfigMap: %w", err)
+		}
+		data = configMap.Data
+	case "Secret":
+		var secret corev1.Secret
+		if err := c.Get(ctx, name, &secret); err != nil {
+			return nil, fmt.Errorf("could not load Secret: %w", err)
+		}
+		data = make(map[string]string, len(secret.Data))
+		for k, v := range secret.Data {
+			data[k] = string(v)
+		}
+	default:
+		return nil, fmt.Errorf("unknown valuesFrom kind %q", ref.Kind)
+	}
+
+	values := map[string]any{}
+	if ref.Key == "" {
+		for k, v := range data {
+			values[k] = v
+		}
+
+		return values, nil
+	}
+
+	v, ok := data[ref.Key]
+	if !ok {
+		return nil, fmt.Errorf("%s %s has no key %q", ref.Kind, name, ref.Key)
+	}
+
+	if err := syaml.Unmarshal([]byte(v), &values); err != nil {
+		return nil, fmt.Errorf("failed to parse values from %s %s: %w", ref.Kind, name, err)
+	}
+
+	return values, nil
+}
@@ -0,0 +1,169 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	templatesv1 "github.com/weaveworks/gitopssets-controller/api/v1alpha1"
+	"github.com/weaveworks/gitopssets-controller/test"
+)
+
+func TestResolveValues(t *testing.T) {
+	tests := []struct {
+		name       string
+		values     *apiextensionsv1.JSON
+		valuesFrom []templatesv1.ValuesReference
+		want       map[string]any
+	}{
+		{
+			name: "values from a ConfigMap key",
+			valuesFrom: []templatesv1.ValuesReference{
+				{Kind: "ConfigMap", Name: "platform-values", Key: "values.yaml"},
+			},
+			want: map[string]any{
+				"registry": "registry.example.com",
+				"domain":   map[string]any{"suffix": "example.com"},
+			},
+		},
+		{
+			name: "values from all the keys in a Secret",
+			valuesFrom: []templatesv1.ValuesReference{
+				{Kind: "Secret", Name: "platform-secrets"},
+			},
+			want: map[string]any{
+				"token": "secret-token",
+			},
+		},
+		{
+			name:   "values take precedence over referenced values",
+			values: &apiextensionsv1.JSON{Raw: []byte(`{"domain":{"suffix":"test.example.com"},"team":"platform"}`)},
+			valuesFrom: []templatesv1.ValuesReference{
+				{Kind: "ConfigMap", Name: "platform-values", Key: "values.yaml"},
+				{Kind: "Secret", Name: "platform-secrets"},
+			},
+			want: map[string]any{
+				"registry": "registry.example.com",
+				"domain":   map[string]any{"suffix": "test.example.com"},
+				"token":    "secret-token",
+				"team":     "platform",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k8sClient := newValuesClient(t)
+			gs := &templatesv1.GitOpsSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-set", Namespace: "default"},
+				Spec: templatesv1.GitOpsSetSpec{
+					Values:     tt.values,
+					ValuesFrom: tt.valuesFrom,
+				},
+			}
+
+			resolved, err := resolveValues(context.TODO(), k8sClient, gs)
+			test.AssertNoError(t, err)
+
+			if resolved.Spec.ValuesFrom != nil {
+				t.Errorf("got valuesFrom %v, want nil", resolved.Spec.ValuesFrom)
+			}
+
+			values := map[string]any{}
+			test.AssertNoError(t, json.Unmarshal(resolved.Spec.Values.Raw, &values))
+			if diff := cmp.Diff(tt.want, values); diff != "" {
+				t.Fatalf("failed to resolve values:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestResolveValues_no_references(t *testing.T) {
+	gs := &templatesv1.GitOpsSet{
+		Spec: templatesv1.GitOpsSetSpec{
+			Values: &apiextensionsv1.JSON{Raw: []byte(`{"team":"platform"}`)},
+		},
+	}
+
+	resolved, err := resolveValues(context.TODO(), newValuesClient(t), gs)
+	test.AssertNoError(t, err)
+
+	if resolved != gs {
+		t.Fatal("GitOpsSet without valuesFrom was copied")
+	}
+}
+
+func TestResolveValues_errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		ref     templatesv1.ValuesReference
+		wantErr string
+	}{
+		{
+			name:    "missing ConfigMap",
+			ref:     templatesv1.ValuesReference{Kind: "ConfigMap", Name: "missing"},
+			wantErr: `could not load ConfigMap: configmaps "missing" not found`,
+		},
+		{
+			name:    "missing Secret",
+			ref:     templatesv1.ValuesReference{Kind: "Secret", Name: "missing"},
+			wantErr: `could not load Secret: secrets "missing" not found`,
+		},
+		{
+			name:    "missing key",
+			ref:     templatesv1.ValuesReference{Kind: "ConfigMap", Name: "platform-values", Key: "missing.yaml"},
+			wantErr: `ConfigMap default/platform-values has no key "missing.yaml"`,
+		},
+		{
+			name:    "invalid YAML",
+			ref:     templatesv1.ValuesReference{Kind: "ConfigMap", Name: "platform-values", Key: "invalid.yaml"},
+			wantErr: `failed to parse values from ConfigMap default/platform-values`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gs := &templatesv1.GitOpsSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-set", Namespace: "default"},
+				Spec: templatesv1.GitOpsSetSpec{
+					ValuesFrom: []templatesv1.ValuesReference{tt.ref},
+				},
+			}
+
+			_, err := resolveValues(context.TODO(), newValuesClient(t), gs)
+
+			test.AssertErrorMatch(t, tt.wantErr, err)
+		})
+	}
+}
+
+func newValuesClient(t *testing.T) client.WithWatch {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	test.AssertNoError(t, clientgoscheme.AddToScheme(scheme))
+
+	return fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "platform-values", Namespace: "default"},
+			Data: map[string]string{
+				"values.yaml":  "registry: registry.example.com\ndomain:\n  suffix: example.com\n",
+				"invalid.yaml": "registry: [",
+			},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "platform-secrets", Namespace: "default"},
+			Data: map[string][]byte{
+				"token": []byte("secret-token"),
+			},
+		},
+	).Build()
+}
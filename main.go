@@ -48,6 +48,9 @@ func main() {
 		watchAllNamespaces    bool
 		defaultServiceAccount string
 		enabledGenerators     []string
+		allowClusterScope     bool
+		serverSideApply       bool
+		ociTemplates          bool
 		clientOptions         runtimeclient.Options
 		logOptions            logger.Options
 		eventsAddr            string
@@ -63,6 +66,12 @@ func main() {
 		"Watch for custom resources in all namespaces, if set to false it will only watch the runtime namespace.")
 	flag.StringVar(&defaultServiceAccount, "default-service-account", "", "Default service account used for impersonation.")
 	flag.StringSliceVar(&enabledGenerators, "enabled-generators", setup.DefaultGenerators, "Generators to enable.")
+	flag.BoolVar(&allowClusterScope, "allow-cluster-scoped-resources", false,
+		"Allow the KubernetesResources generator to query cluster-scoped resources and resources in all namespaces.")
+	flag.BoolVar(&serverSideApply, "default-server-side-apply", false,
+		"Apply generated resources with server-side apply unless the GitOpsSet configures this.")
+	flag.BoolVar(&ociTemplates, "enable-oci-repository-templates", false,
+		"Allow templates to be loaded from OCIRepositories when the OCIRepository generator is disabled.")
 
 	logOptions.BindFlags(flag.CommandLine)
 	clientOptions.BindFlags(flag.CommandLine)
@@ -159,15 +168,18 @@ func main() {
 	fetcher := fetch.NewArchiveFetcher(retries, tar.UnlimitedUntarSize, tar.UnlimitedUntarSize, "")
 
 	if err = (&controllers.GitOpsSetReconciler{
-		Client:                mgr.GetClient(),
-		DefaultServiceAccount: defaultServiceAccount,
-		Config:                mgr.GetConfig(),
-		Scheme:                mgr.GetScheme(),
-		Mapper:                mapper,
+		Client:                 mgr.GetClient(),
+		DefaultServiceAccount:  defaultServiceAccount,
+		DefaultServerSideApply: serverSideApply,
+		Config:                 mgr.GetConfig(),
+		Scheme:                 mgr.GetScheme(),
+		Mapper:                 mapper,
 		// TODO: Figure how to configure the DefaultClient.
-		Generators:    setup.GetGenerators(enabledGenerators, fetcher, apiclient.DefaultClientFactory),
-		Metrics:       metricsH,
-		EventRecorder: eventRecorder,
+		Generators:             setup.GetGenerators(enabledGenerators, fetcher, apiclient.DefaultClientFactory, allowClusterScope),
+		Fetcher:                fetcher,
+		OCIRepositoryTemplates: ociTemplates,
+		Metrics:                metricsH,
+		EventRecorder:          eventRecorder,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", controllerName)
 		os.Exit(1)
@@ -194,12 +194,16 @@ func renderGitOpsSet(filename string, enabledGenerators []string, disableCluster
 		fetcher = localFetcher{logger: logger}
 	}
 
-	factories := setup.GetGenerators(enabledGenerators, fetcher, apiclient.DefaultClientFactory)
+	factories := setup.GetGenerators(enabledGenerators, fetcher, apiclient.DefaultClientFactory, true)
 	gens := instantiateGenerators(factories, logger, cl)
 
 	var generated []*unstructured.Unstructured
 
 	for _, set := range gitOpsSets {
+		if err := checkUnsupportedReferences(set); err != nil {
+			return err
+		}
+
 		rendered, err := templates.Render(context.Background(), set, gens)
 		if err != nil {
 			return err
@@ -210,3 +214,20 @@ func renderGitOpsSet(filename string, enabledGenerators []string, disableCluster
 
 	return outputResources(out, generated)
 }
+
+// checkUnsupportedReferences returns an error if the GitOpsSet references
+// templates or values stored outside of the GitOpsSet, these are not loaded
+// when rendering GitOpsSets with the CLI.
+func checkUnsupportedReferences(gs *templatesv1.GitOpsSet) error {
+	for _, tmpl := range gs.Spec.Templates {
+		if tmpl.TemplateRef != nil {
+			return fmt.Errorf("GitOpsSet %s: templateRef is unsupported in the CLI", gs.GetName())
+		}
+	}
+
+	if len(gs.Spec.ValuesFrom) > 0 {
+		return fmt.Errorf("GitOpsSet %s: valuesFrom is unsupported in the CLI", gs.GetName())
+	}
+
+	return nil
+}
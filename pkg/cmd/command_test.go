@@ -92,3 +92,31 @@ spec:
 		t.Fatalf("failed to generate:\n%s", diff)
 	}
 }
+
+func TestRenderGitOpsSet_with_unsupported_references(t *testing.T) {
+	testCases := []struct {
+		filename string
+		wantErr  string
+	}{
+		{
+			filename: "testdata/template_ref_set.yaml",
+			wantErr:  "GitOpsSet template-ref-sample: templateRef is unsupported in the CLI",
+		},
+		{
+			filename: "testdata/values_from_set.yaml",
+			wantErr:  "GitOpsSet values-from-sample: valuesFrom is unsupported in the CLI",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.filename, func(t *testing.T) {
+			var out strings.Builder
+
+			err := renderGitOpsSet(tt.filename, setup.DefaultGenerators, true, "", &out)
+
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("got error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
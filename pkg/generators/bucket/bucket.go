@@ -0,0 +1,116 @@
+package bucket
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	"github.com/go-logr/logr"
+	templatesv1 "github.com/weaveworks/gitopssets-controller/api/v1alpha1"
+	"github.com/weaveworks/gitopssets-controller/pkg/generators"
+	"github.com/weaveworks/gitopssets-controller/pkg/parser"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// BucketGenerator extracts files from Flux Bucket resources.
+type BucketGenerator struct {
+	Client client.Reader
+	logr.Logger
+
+	Fetcher parser.ArchiveFetcher
+}
+
+// GeneratorFactory is a function for creating per-reconciliation generators for
+// the BucketGenerator.
+func GeneratorFactory(fetcher parser.ArchiveFetcher) generators.GeneratorFactory {
+	return func(l logr.Logger, c client.Reader) generators.Generator {
+		return NewGenerator(l, c, fetcher)
+	}
+}
+
+// NewGenerator creates and returns a new Bucket generator.
+func NewGenerator(l logr.Logger, c client.Reader, fetcher parser.ArchiveFetcher) *BucketGenerator {
+	return &BucketGenerator{
+		Client:  c,
+		Logger:  l,
+		Fetcher: fetcher,
+	}
+}
+
+// Generate is an implementation of the Generator interface.
+//
+// If the Bucket generator generates from a list of files, each file is parsed
+// and returned as a generated element.
+func (g *BucketGenerator) Generate(ctx context.Context, sg *templatesv1.GitOpsSetGenerator, ks *templatesv1.GitOpsSet) ([]map[string]any, error) {
+	if sg == nil {
+		return nil, generators.ErrEmptyGitOpsSet
+	}
+	if sg.Bucket == nil {
+		return nil, nil
+	}
+
+	g.Logger.Info("generating params from Bucket generator", "bucket", sg.Bucket.BucketRef)
+
+	if sg.Bucket.Files != nil {
+		return g.generateParamsFromBucketFiles(ctx, sg, ks)
+	}
+
+	if sg.Bucket.Directories != nil {
+		return g.generateParamsFromBucketDirectories(ctx, sg, ks)
+	}
+
+	return nil, generators.ErrEmptyGitOpsSet
+}
+
+func (g *BucketGenerator) generateParamsFromBucketFiles(ctx context.Context, sg *templatesv1.GitOpsSetGenerator, ks *templatesv1.GitOpsSet) ([]map[string]any, error) {
+	bucket, err := g.loadBucket(ctx, sg.Bucket, ks)
+	if err != nil {
+		return nil, err
+	}
+
+	g.Logger.Info("fetching archive URL", "bucketName", bucket.Spec.BucketName, "artifactURL", bucket.Status.Artifact.URL,
+		"digest", bucket.Status.Artifact.Digest, "revision", bucket.Status.Artifact.Revision)
+
+	parser := parser.NewRepositoryParser(g.Logger, g.Fetcher)
+
+	return parser.GenerateFromFiles(ctx, bucket.Status.Artifact.URL, bucket.Status.Artifact.Digest, sg.Bucket.Files)
+}
+
+func (g *BucketGenerator) generateParamsFromBucketDirectories(ctx context.Context, sg *templatesv1.GitOpsSetGenerator, ks *templatesv1.GitOpsSet) ([]map[string]any, error) {
+	bucket, err := g.loadBucket(ctx, sg.Bucket, ks)
+	if err != nil {
+		return nil, err
+	}
+
+	g.Logger.Info("fetching archive URL", "bucketName", bucket.Spec.BucketName, "artifactURL", bucket.Status.Artifact.URL,
+		"digest", bucket.Status.Artifact.Digest, "revision", bucket.Status.Artifact.Revision)
+
+	parser := parser.NewRepositoryParser(g.Logger, g.Fetcher)
+
+	return parser.GenerateFromDirectories(ctx, bucket.Status.Artifact.URL, bucket.Status.Artifact.Digest, sg.Bucket.Directories)
+}
+
+func (g *BucketGenerator) loadBucket(ctx context.Context, gen *templatesv1.BucketGenerator, ks *templatesv1.GitOpsSet) (*sourcev1.Bucket, error) {
+	bucketName := client.ObjectKey{Name: gen.BucketRef, Namespace: ks.GetNamespace()}
+
+	var bucket sourcev1.Bucket
+	if err := g.Client.Get(ctx, bucketName, &bucket); err != nil {
+		return nil, fmt.Errorf("could not load Bucket: %w", err)
+	}
+
+	// No artifact? nothing to generate...
+	if bucket.Status.Artifact == nil {
+		g.Logger.Info("Bucket does not have an artifact", "bucket", bucketName)
+		return nil, generators.ArtifactError("Bucket", bucketName)
+	}
+
+	return &bucket, nil
+}
+
+// Interval is an implementation of the Generator interface.
+//
+// BucketGenerator is driven by watching a Flux Bucket resource.
+func (g *BucketGenerator) Interval(sg *templatesv1.GitOpsSetGenerator) time.Duration {
+	return generators.NoRequeueInterval
+}
@@ -0,0 +1,66 @@
+package filter
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+)
+
+// ElementVariable is the name of the variable that holds each element when the
+// filter expression is evaluated.
+const ElementVariable = "element"
+
+// Elements returns the elements for which the CEL expression evaluates to
+// true.
+//
+// If the expression is empty, all the elements are returned.
+func Elements(expression string, elements []map[string]any) ([]map[string]any, error) {
+	if expression == "" {
+		return elements, nil
+	}
+
+	program, err := Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := []map[string]any{}
+	for _, element := range elements {
+		out, _, err := program.Eval(map[string]any{ElementVariable: element})
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate filter %q: %w", expression, err)
+		}
+
+		keep, ok := out.Value().(bool)
+		if !ok {
+			return nil, fmt.Errorf("filter %q must evaluate to a bool, got %s", expression, out.Type().TypeName())
+		}
+
+		if keep {
+			filtered = append(filtered, element)
+		}
+	}
+
+	return filtered, nil
+}
+
+// Compile compiles a CEL expression that is evaluated against a generated
+// element.
+func Compile(expression string) (cel.Program, error) {
+	env, err := cel.NewEnv(cel.Variable(ElementVariable, cel.MapType(cel.StringType, cel.DynType)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL environment: %w", err)
+	}
+
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("failed to compile expression %q: %w", expression, issues.Err())
+	}
+
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create program for expression %q: %w", expression, err)
+	}
+
+	return program, nil
+}
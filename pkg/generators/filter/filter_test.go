@@ -0,0 +1,91 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/weaveworks/gitopssets-controller/test"
+)
+
+func TestElements(t *testing.T) {
+	elements := []map[string]any{
+		{"Branch": "feature/add-widgets", "Fork": false, "Number": "1"},
+		{"Branch": "main", "Fork": false, "Number": "2"},
+		{"Branch": "feature/from-fork", "Fork": true, "Number": "3"},
+		{"ClusterName": "dev", "ClusterLabels": map[string]string{"env": "dev"}},
+	}
+
+	testCases := []struct {
+		name       string
+		expression string
+		want       []map[string]any
+	}{
+		{
+			name: "no expression",
+			want: elements,
+		},
+		{
+			name:       "filtering by fields",
+			expression: `has(element.Fork) && element.Fork == false && element.Branch.startsWith("feature/")`,
+			want: []map[string]any{
+				{"Branch": "feature/add-widgets", "Fork": false, "Number": "1"},
+			},
+		},
+		{
+			name:       "filtering by nested fields",
+			expression: `has(element.ClusterLabels) && element.ClusterLabels.env == "dev"`,
+			want: []map[string]any{
+				{"ClusterName": "dev", "ClusterLabels": map[string]string{"env": "dev"}},
+			},
+		},
+		{
+			name:       "no matching elements",
+			expression: `false`,
+			want:       []map[string]any{},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			filtered, err := Elements(tt.expression, elements)
+			test.AssertNoError(t, err)
+
+			if diff := cmp.Diff(tt.want, filtered); diff != "" {
+				t.Fatalf("failed to filter elements:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestElements_errors(t *testing.T) {
+	testCases := []struct {
+		name       string
+		expression string
+		wantErr    string
+	}{
+		{
+			name:       "invalid expression",
+			expression: `element.Fork ==`,
+			wantErr:    `failed to compile expression "element.Fork ==": ERROR`,
+		},
+		{
+			name:       "expression that isn't a bool",
+			expression: `element.Branch`,
+			wantErr:    `filter "element.Branch" must evaluate to a bool, got string`,
+		},
+		{
+			name:       "missing field",
+			expression: `element.Missing == "test"`,
+			wantErr:    `failed to evaluate filter "element.Missing == \\"test\\"": no such key: Missing`,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Elements(tt.expression, []map[string]any{{"Branch": "main", "Fork": false}})
+
+			test.AssertErrorMatch(t, tt.wantErr, err)
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package gitopsset
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	templatesv1 "github.com/weaveworks/gitopssets-controller/api/v1alpha1"
+	"github.com/weaveworks/gitopssets-controller/pkg/generators"
+)
+
+// GitOpsSetGenerator generates from the elements published by other
+// GitOpsSets.
+type GitOpsSetGenerator struct {
+	Client client.Reader
+	logr.Logger
+}
+
+// GeneratorFactory is a function for creating per-reconciliation generators for
+// the GitOpsSetGenerator.
+func GeneratorFactory(l logr.Logger, c client.Reader) generators.Generator {
+	return NewGenerator(l, c)
+}
+
+// NewGenerator creates and returns a new GitOpsSet generator.
+func NewGenerator(l logr.Logger, c client.Reader) *GitOpsSetGenerator {
+	return &GitOpsSetGenerator{
+		Client: c,
+		Logger: l,
+	}
+}
+
+// Generate is an implementation of the Generator interface.
+//
+// This returns the elements published in the status of the referenced
+// GitOpsSet.
+func (g *GitOpsSetGenerator) Generate(ctx context.Context, sg *templatesv1.GitOpsSetGenerator, ks *templatesv1.GitOpsSet) ([]map[string]any, error) {
+	if sg == nil {
+		return nil, generators.ErrEmptyGitOpsSet
+	}
+
+	if sg.GitOpsSet == nil {
+		return nil, nil
+	}
+
+	if sg.GitOpsSet.GitOpsSetRef == ks.GetName() {
+		return nil, fmt.Errorf("GitOpsSet %s can't generate from its own elements", ks.GetName())
+	}
+
+	g.Logger.Info("generating params from GitOpsSet generator", "gitOpsSet", sg.GitOpsSet.GitOpsSetRef)
+
+	var source templatesv1.GitOpsSet
+	sourceName := client.ObjectKey{Name: sg.GitOpsSet.GitOpsSetRef, Namespace: ks.GetNamespace()}
+	if err := g.Client.Get(ctx, sourceName, &source); err != nil {
+		return nil, fmt.Errorf("could not load GitOpsSet: %w", err)
+	}
+
+	if !source.Spec.PublishElements {
+		return nil, fmt.Errorf("GitOpsSet %s does not publish its elements", sourceName)
+	}
+
+	if source.Status.PublishedElements == nil {
+		g.Logger.Info("GitOpsSet has not published its elements")
+		return nil, generators.ArtifactError("GitOpsSet", sourceName)
+	}
+
+	result := []map[string]any{}
+	for _, element := range source.Status.PublishedElements.Elements {
+		var v map[string]any
+		if err := json.Unmarshal(element.Raw, &v); err != nil {
+			return nil, fmt.Errorf("failed to parse element published by GitOpsSet %s: %w", sourceName, err)
+		}
+		result = append(result, v)
+	}
+
+	return result, nil
+}
+
+// Interval is an implementation of the Generator interface.
+//
+// GitOpsSetGenerator is driven by watching the referenced GitOpsSets.
+func (g *GitOpsSetGenerator) Interval(sg *templatesv1.GitOpsSetGenerator) time.Duration {
+	return generators.NoRequeueInterval
+}
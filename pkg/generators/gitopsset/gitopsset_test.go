@@ -0,0 +1,179 @@
+package gitopsset
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/google/go-cmp/cmp"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	templatesv1 "github.com/weaveworks/gitopssets-controller/api/v1alpha1"
+	"github.com/weaveworks/gitopssets-controller/pkg/generators"
+	"github.com/weaveworks/gitopssets-controller/test"
+)
+
+var _ generators.Generator = (*GitOpsSetGenerator)(nil)
+
+func TestGenerate_with_no_GitOpsSet(t *testing.T) {
+	gen := GeneratorFactory(logr.Discard(), nil)
+	got, err := gen.Generate(context.TODO(), &templatesv1.GitOpsSetGenerator{}, nil)
+
+	if err != nil {
+		t.Errorf("got an error with no GitOpsSet: %s", err)
+	}
+	if got != nil {
+		t.Errorf("got %v, want %v with no GitOpsSet generator", got, nil)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		objects []runtime.Object
+		want    []map[string]any
+	}{
+		{
+			name: "published elements",
+			objects: []runtime.Object{newGitOpsSet(withPublishedElements(
+				`{"cluster": "dev", "replicas": 1}`,
+				`{"cluster": "prod", "replicas": 5}`,
+			))},
+			want: []map[string]any{
+				{"cluster": "dev", "replicas": 1.0},
+				{"cluster": "prod", "replicas": 5.0},
+			},
+		},
+		{
+			name:    "no published elements",
+			objects: []runtime.Object{newGitOpsSet(withPublishedElements())},
+			want:    []map[string]any{},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			gen := NewGenerator(logr.Discard(), newFakeClient(t, tt.objects...))
+			got, err := gen.Generate(context.TODO(), &templatesv1.GitOpsSetGenerator{
+				GitOpsSet: &templatesv1.GitOpsSetRefGenerator{
+					GitOpsSetRef: "source-set",
+				},
+			}, newConsumer())
+
+			test.AssertNoError(t, err)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("failed to generate from GitOpsSet:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestInterval(t *testing.T) {
+	gen := NewGenerator(logr.Discard(), nil)
+	sg := &templatesv1.GitOpsSetGenerator{
+		GitOpsSet: &templatesv1.GitOpsSetRefGenerator{},
+	}
+
+	d := gen.Interval(sg)
+
+	if d != generators.NoRequeueInterval {
+		t.Fatalf("got %#v want %#v", d, generators.NoRequeueInterval)
+	}
+}
+
+func TestGenerate_errors(t *testing.T) {
+	testCases := []struct {
+		name         string
+		gitOpsSetRef string
+		objects      []runtime.Object
+		wantErr      string
+	}{
+		{
+			name:         "missing GitOpsSet",
+			gitOpsSetRef: "source-set",
+			wantErr:      `could not load GitOpsSet: gitopssets.sets.gitops.pro "source-set" not found`,
+		},
+		{
+			name:         "GitOpsSet that doesn't publish its elements",
+			gitOpsSetRef: "source-set",
+			objects: []runtime.Object{newGitOpsSet(func(gs *templatesv1.GitOpsSet) {
+				gs.Spec.PublishElements = false
+			})},
+			wantErr: `GitOpsSet default/source-set does not publish its elements`,
+		},
+		{
+			name:         "GitOpsSet that hasn't published its elements",
+			gitOpsSetRef: "source-set",
+			objects:      []runtime.Object{newGitOpsSet()},
+			wantErr:      `no artifact for GitOpsSet default/source-set`,
+		},
+		{
+			name:         "referencing itself",
+			gitOpsSetRef: "test-generator",
+			wantErr:      `GitOpsSet test-generator can't generate from its own elements`,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			gen := GeneratorFactory(logr.Discard(), newFakeClient(t, tt.objects...))
+			_, err := gen.Generate(context.TODO(), &templatesv1.GitOpsSetGenerator{
+				GitOpsSet: &templatesv1.GitOpsSetRefGenerator{
+					GitOpsSetRef: tt.gitOpsSetRef,
+				},
+			}, newConsumer())
+
+			test.AssertErrorMatch(t, tt.wantErr, err)
+		})
+	}
+}
+
+func newConsumer() *templatesv1.GitOpsSet {
+	return &templatesv1.GitOpsSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-generator",
+			Namespace: "default",
+		},
+	}
+}
+
+func newGitOpsSet(opts ...func(*templatesv1.GitOpsSet)) *templatesv1.GitOpsSet {
+	gs := &templatesv1.GitOpsSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "source-set",
+			Namespace: "default",
+		},
+		Spec: templatesv1.GitOpsSetSpec{
+			PublishElements: true,
+		},
+	}
+	for _, opt := range opts {
+		opt(gs)
+	}
+
+	return gs
+}
+
+func withPublishedElements(elements ...string) func(*templatesv1.GitOpsSet) {
+	return func(gs *templatesv1.GitOpsSet) {
+		published := &templatesv1.PublishedElements{}
+		for _, element := range elements {
+			published.Elements = append(published.Elements, apiextensionsv1.JSON{Raw: []byte(element)})
+		}
+		gs.Status.PublishedElements = published
+	}
+}
+
+func newFakeClient(t *testing.T, objs ...runtime.Object) client.WithWatch {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := templatesv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	return fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objs...).Build()
+}
@@ -0,0 +1,189 @@
+package gitrefs
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/go-logr/logr"
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/jenkins-x/go-scm/scm/factory"
+	templatesv1 "github.com/weaveworks/gitopssets-controller/api/v1alpha1"
+	"github.com/weaveworks/gitopssets-controller/pkg/generators"
+	"github.com/weaveworks/gitopssets-controller/pkg/generators/scmclient"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	branchRefType = "branch"
+	tagRefType    = "tag"
+)
+
+type clientFactoryFunc func(driver, serverURL, oauthToken string, opts ...factory.ClientOptionFunc) (*scm.Client, error)
+
+// GeneratorFactory is a function for creating per-reconciliation generators for
+// the GitRefsGenerator.
+func GeneratorFactory(l logr.Logger, c client.Reader) generators.Generator {
+	return NewGenerator(l, c)
+}
+
+// GitRefsGenerator generates from the branches or tags in a repository.
+type GitRefsGenerator struct {
+	Client        client.Reader
+	clientFactory clientFactoryFunc
+	logr.Logger
+
+	now   func() time.Time
+	sleep func(context.Context, time.Duration) error
+}
+
+// NewGenerator creates and returns a new git refs generator.
+func NewGenerator(l logr.Logger, c client.Reader) *GitRefsGenerator {
+	return &GitRefsGenerator{
+		Client:        c,
+		Logger:        l,
+		clientFactory: factory.NewClient,
+		now:           time.Now,
+		sleep:         scmclient.SleepWithContext,
+	}
+}
+
+// Generate is an implementation of the Generator interface.
+//
+// Each branch or tag that matches the filters is returned as a generated
+// element with the name of the ref, and the SHA it points at, and optionally
+// the timestamp of the commit.
+func (g *GitRefsGenerator) Generate(ctx context.Context, sg *templatesv1.GitOpsSetGenerator, ks *templatesv1.GitOpsSet) ([]map[string]any, error) {
+	if sg == nil {
+		g.Logger.Info("no generator provided")
+		return nil, generators.ErrEmptyGitOpsSet
+	}
+
+	if sg.GitRefs == nil {
+		g.Logger.Info("git refs configuration is nil")
+		return nil, nil
+	}
+
+	matches, err := refMatcher(sg.GitRefs)
+	if err != nil {
+		return nil, err
+	}
+
+	g.Logger.Info("generating params from GitRefs generator", "repo", sg.GitRefs.Repo)
+	authToken, err := scmclient.AuthToken(ctx, g.Client, ks, sg.GitRefs.SecretRef)
+	if err != nil {
+		return nil, err
+	}
+
+	refType := refTypeFromConfig(sg.GitRefs)
+	g.Logger.Info("querying refs", "repo", sg.GitRefs.Repo, "type", refType, "driver", sg.GitRefs.Driver, "serverURL", sg.GitRefs.ServerURL)
+
+	scmClient, err := g.clientFactory(sg.GitRefs.Driver, sg.GitRefs.ServerURL, authToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+
+	pager := &scmclient.Pager{Logger: g.Logger.WithValues("repo", sg.GitRefs.Repo), Now: g.now, Sleep: g.sleep}
+	refs, err := listRefs(ctx, pager, scmClient, sg.GitRefs.Repo, refType)
+	if err != nil {
+		return nil, err
+	}
+
+	g.Logger.Info("queried refs", "repo", sg.GitRefs.Repo, "type", refType, "count", len(refs))
+	res := []map[string]any{}
+	for _, ref := range refs {
+		if !matches(ref.Name) {
+			continue
+		}
+
+		element := map[string]any{
+			"Name": ref.Name,
+			"SHA":  ref.Sha,
+		}
+
+		if sg.GitRefs.CommitTimestamps {
+			commit, _, err := scmclient.Do(ctx, pager, func(ctx context.Context) (*scm.Commit, *scm.Response, error) {
+				return scmClient.Git.FindCommit(ctx, sg.GitRefs.Repo, ref.Sha)
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to get commit %s for %s %s: %w", ref.Sha, refType, ref.Name, err)
+			}
+			element["Timestamp"] = commit.Committer.Date.UTC().Format(time.RFC3339)
+		}
+
+		res = append(res, element)
+	}
+
+	return res, nil
+}
+
+// Interval is an implementation of the Generator interface.
+func (g *GitRefsGenerator) Interval(sg *templatesv1.GitOpsSetGenerator) time.Duration {
+	return sg.GitRefs.Interval.Duration
+}
+
+// listRefs pages through all the branches or tags in the repository.
+func listRefs(ctx context.Context, pager *scmclient.Pager, scmClient *scm.Client, repo, refType string) ([]*scm.Reference, error) {
+	list, kind := scmClient.Git.ListBranches, "branches"
+	if refType == tagRefType {
+		list, kind = scmClient.Git.ListTags, "tags"
+	}
+
+	refs, err := scmclient.ListAll(ctx, pager, 0, func(ctx context.Context, page, size int) ([]*scm.Reference, *scm.Response, error) {
+		return list(ctx, repo, &scm.ListOptions{Page: page, Size: size})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", kind, err)
+	}
+
+	return refs, nil
+}
+
+func refTypeFromConfig(c *templatesv1.GitRefsGenerator) string {
+	if c.Type == "" {
+		return branchRefType
+	}
+
+	return c.Type
+}
+
+// refMatcher returns a function that reports whether a ref name matches the
+// pattern and semantic version constraint in the configuration.
+func refMatcher(c *templatesv1.GitRefsGenerator) (func(string) bool, error) {
+	var pattern *regexp.Regexp
+	if c.Pattern != "" {
+		compiled, err := regexp.Compile(c.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse pattern %q: %w", c.Pattern, err)
+		}
+		pattern = compiled
+	}
+
+	var constraint *semver.Constraints
+	if c.SemVer != "" {
+		parsed, err := semver.NewConstraint(c.SemVer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse semver constraint %q: %w", c.SemVer, err)
+		}
+		constraint = parsed
+	}
+
+	return func(name string) bool {
+		if pattern != nil && !pattern.MatchString(name) {
+			return false
+		}
+
+		if constraint != nil {
+			v, err := semver.NewVersion(name)
+			if err != nil {
+				return false
+			}
+
+			return constraint.Check(v)
+		}
+
+		return true
+	}, nil
+}
@@ -0,0 +1,355 @@
+package gitrefs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/google/go-cmp/cmp"
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/jenkins-x/go-scm/scm/factory"
+	templatesv1 "github.com/weaveworks/gitopssets-controller/api/v1alpha1"
+	"github.com/weaveworks/gitopssets-controller/pkg/generators"
+	"github.com/weaveworks/gitopssets-controller/test"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ generators.Generator = (*GitRefsGenerator)(nil)
+
+var testCommitTime = time.Date(2024, time.March, 12, 10, 30, 0, 0, time.UTC)
+
+func TestGenerate_with_no_generator(t *testing.T) {
+	gen := GeneratorFactory(logr.Discard(), nil)
+	_, err := gen.Generate(context.TODO(), nil, nil)
+
+	if err != generators.ErrEmptyGitOpsSet {
+		t.Errorf("got error %v", err)
+	}
+}
+
+func TestGenerate_with_no_config(t *testing.T) {
+	gen := GeneratorFactory(logr.Discard(), nil)
+	got, err := gen.Generate(context.TODO(), &templatesv1.GitOpsSetGenerator{}, nil)
+
+	if err != nil {
+		t.Errorf("got an error with no git refs: %s", err)
+	}
+	if got != nil {
+		t.Errorf("got %v, want %v with no GitRefs generator", got, nil)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	testCases := []struct {
+		name             string
+		initObjs         []runtime.Object
+		secretRef        *templatesv1.LocalObjectReference
+		refType          string
+		pattern          string
+		semver           string
+		commitTimestamps bool
+		want             []map[string]any
+	}{
+		{
+			name: "all branches",
+			want: []map[string]any{
+				{"Name": "main", "SHA": "6dcb09b5b57875f334f61aebed695e2e4193db5e"},
+				{"Name": "release-1.0", "SHA": "564254f7170844f40a01315fc571ae45fb8665b7"},
+				{"Name": "release-1.1", "SHA": "f5c3b4a5e2b1dd32bd4e2f8d6a1f2b9a7c3d4e5f"},
+			},
+		},
+		{
+			name:    "branches filtered by pattern",
+			pattern: "^release-",
+			want: []map[string]any{
+				{"Name": "release-1.0", "SHA": "564254f7170844f40a01315fc571ae45fb8665b7"},
+				{"Name": "release-1.1", "SHA": "f5c3b4a5e2b1dd32bd4e2f8d6a1f2b9a7c3d4e5f"},
+			},
+		},
+		{
+			name:    "tags filtered by semver",
+			refType: "tag",
+			semver:  ">= 1.1.0",
+			want: []map[string]any{
+				{"Name": "v1.1.0", "SHA": "f5c3b4a5e2b1dd32bd4e2f8d6a1f2b9a7c3d4e5f"},
+				{"Name": "v2.0.0", "SHA": "6dcb09b5b57875f334f61aebed695e2e4193db5e"},
+			},
+		},
+		{
+			name:    "tags filtered by pattern and semver",
+			refType: "tag",
+			pattern: "^v1\\.",
+			semver:  ">= 1.0.0",
+			want: []map[string]any{
+				{"Name": "v1.0.0", "SHA": "564254f7170844f40a01315fc571ae45fb8665b7"},
+				{"Name": "v1.1.0", "SHA": "f5c3b4a5e2b1dd32bd4e2f8d6a1f2b9a7c3d4e5f"},
+			},
+		},
+		{
+			name:             "branches with commit timestamps",
+			pattern:          "^release-",
+			commitTimestamps: true,
+			want: []map[string]any{
+				{"Name": "release-1.0", "SHA": "564254f7170844f40a01315fc571ae45fb8665b7", "Timestamp": "2024-03-12T10:30:00Z"},
+				{"Name": "release-1.1", "SHA": "f5c3b4a5e2b1dd32bd4e2f8d6a1f2b9a7c3d4e5f", "Timestamp": "2024-03-12T10:30:00Z"},
+			},
+		},
+		{
+			name: "authenticated with a secret",
+			initObjs: []runtime.Object{newSecret(types.NamespacedName{
+				Name:      "test-secret",
+				Namespace: "default",
+			})},
+			secretRef: &templatesv1.LocalObjectReference{
+				Name: "test-secret",
+			},
+			pattern: "^main$",
+			want: []map[string]any{
+				{"Name": "main", "SHA": "6dcb09b5b57875f334f61aebed695e2e4193db5e"},
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			gen := NewGenerator(logr.Discard(), fake.NewFakeClient(tt.initObjs...))
+			scmClient := newFakeSCMClient()
+			gen.clientFactory = defaultClientFactory(scmClient)
+
+			gsg := templatesv1.GitOpsSetGenerator{
+				GitRefs: &templatesv1.GitRefsGenerator{
+					Driver:           "fake",
+					ServerURL:        "https://example.com",
+					Repo:             "test-org/my-repo",
+					SecretRef:        tt.secretRef,
+					Type:             tt.refType,
+					Pattern:          tt.pattern,
+					SemVer:           tt.semver,
+					CommitTimestamps: tt.commitTimestamps,
+				},
+			}
+
+			got, err := gen.Generate(context.TODO(), &gsg, newGitOpsSet(gsg))
+
+			test.AssertNoError(t, err)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("failed to generate git refs:\n%s", diff)
+			}
+			wantCommits := 0
+			if tt.commitTimestamps {
+				wantCommits = len(tt.want)
+			}
+			if commits := scmClient.Git.(*fakeGitService).commits; commits != wantCommits {
+				t.Errorf("got %d commit queries, want %d", commits, wantCommits)
+			}
+		})
+	}
+}
+
+func TestGenerate_errors(t *testing.T) {
+	testCases := []struct {
+		name      string
+		initObjs  []runtime.Object
+		secretRef *templatesv1.LocalObjectReference
+		pattern   string
+		semver    string
+		repo      string
+		wantErr   string
+	}{
+		{
+			name: "generator with missing secret",
+			secretRef: &templatesv1.LocalObjectReference{
+				Name: "test-secret",
+			},
+			wantErr: `failed to load repository generator credentials: secrets "test-secret" not found`,
+		},
+		{
+			name: "generator with missing key in secret",
+			initObjs: []runtime.Object{newSecret(types.NamespacedName{
+				Name:      "test-secret",
+				Namespace: "default",
+			}, func(c *corev1.Secret) {
+				c.Data = map[string][]byte{}
+			})},
+			secretRef: &templatesv1.LocalObjectReference{
+				Name: "test-secret",
+			},
+			wantErr: `secret default/test-secret does not contain required field 'password'`,
+		},
+		{
+			name:    "invalid pattern",
+			pattern: "release-[",
+			wantErr: `failed to parse pattern "release-\[": error parsing regexp`,
+		},
+		{
+			name:    "invalid semver constraint",
+			semver:  ">= one",
+			wantErr: `failed to parse semver constraint ">= one"`,
+		},
+		{
+			name:    "failing to list refs",
+			repo:    "test-org/missing-repo",
+			wantErr: "failed to list branches: repository not found",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			gen := NewGenerator(logr.Discard(), fake.NewFakeClient(tt.initObjs...))
+			gen.clientFactory = defaultClientFactory(newFakeSCMClient())
+
+			repo := "test-org/my-repo"
+			if tt.repo != "" {
+				repo = tt.repo
+			}
+			gsg := templatesv1.GitOpsSetGenerator{
+				GitRefs: &templatesv1.GitRefsGenerator{
+					Driver:    "fake",
+					ServerURL: "https://example.com",
+					Repo:      repo,
+					SecretRef: tt.secretRef,
+					Pattern:   tt.pattern,
+					SemVer:    tt.semver,
+				},
+			}
+
+			_, err := gen.Generate(context.TODO(), &gsg, newGitOpsSet(gsg))
+
+			test.AssertErrorMatch(t, tt.wantErr, err)
+		})
+	}
+}
+
+func TestGitRefsGenerator_GetInterval(t *testing.T) {
+	interval := time.Minute * 10
+	gen := NewGenerator(logr.Discard(), fake.NewFakeClient())
+	sg := &templatesv1.GitOpsSetGenerator{
+		GitRefs: &templatesv1.GitRefsGenerator{
+			Driver:    "fake",
+			ServerURL: "https://example.com",
+			Repo:      "test-org/my-repo",
+			Interval:  metav1.Duration{Duration: interval},
+		},
+	}
+
+	d := gen.Interval(sg)
+
+	if d != interval {
+		t.Fatalf("got %#v want %#v", d, interval)
+	}
+}
+
+// fakeGitService implements the parts of the scm.GitService used by the
+// generator, the refs are returned a page at a time.
+type fakeGitService struct {
+	scm.GitService
+
+	repo     string
+	branches []*scm.Reference
+	tags     []*scm.Reference
+	pageSize int
+	commits  int
+}
+
+func (s *fakeGitService) ListBranches(ctx context.Context, repo string, opts *scm.ListOptions) ([]*scm.Reference, *scm.Response, error) {
+	return s.listPage(repo, s.branches, opts)
+}
+
+func (s *fakeGitService) ListTags(ctx context.Context, repo string, opts *scm.ListOptions) ([]*scm.Reference, *scm.Response, error) {
+	return s.listPage(repo, s.tags, opts)
+}
+
+func (s *fakeGitService) FindCommit(ctx context.Context, repo, ref string) (*scm.Commit, *scm.Response, error) {
+	s.commits++
+
+	return &scm.Commit{
+		Sha: ref,
+		Committer: scm.Signature{
+			Date: testCommitTime,
+		},
+	}, &scm.Response{}, nil
+}
+
+func (s *fakeGitService) listPage(repo string, refs []*scm.Reference, opts *scm.ListOptions) ([]*scm.Reference, *scm.Response, error) {
+	if repo != s.repo {
+		return nil, nil, errors.New("repository not found")
+	}
+
+	start := (opts.Page - 1) * s.pageSize
+	end := min(start+s.pageSize, len(refs))
+	res := &scm.Response{}
+	if end < len(refs) {
+		res.Page.Next = opts.Page + 1
+	}
+
+	return refs[start:end], res, nil
+}
+
+func newFakeSCMClient() *scm.Client {
+	return &scm.Client{
+		Git: &fakeGitService{
+			repo:     "test-org/my-repo",
+			pageSize: 2,
+			branches: []*scm.Reference{
+				{Name: "main", Sha: "6dcb09b5b57875f334f61aebed695e2e4193db5e"},
+				{Name: "release-1.0", Sha: "564254f7170844f40a01315fc571ae45fb8665b7"},
+				{Name: "release-1.1", Sha: "f5c3b4a5e2b1dd32bd4e2f8d6a1f2b9a7c3d4e5f"},
+			},
+			tags: []*scm.Reference{
+				{Name: "v1.0.0", Sha: "564254f7170844f40a01315fc571ae45fb8665b7"},
+				{Name: "v1.1.0", Sha: "f5c3b4a5e2b1dd32bd4e2f8d6a1f2b9a7c3d4e5f"},
+				{Name: "v2.0.0", Sha: "6dcb09b5b57875f334f61aebed695e2e4193db5e"},
+				{Name: "nightly", Sha: "6dcb09b5b57875f334f61aebed695e2e4193db5e"},
+			},
+		},
+	}
+}
+
+func newGitOpsSet(gsg templatesv1.GitOpsSetGenerator) *templatesv1.GitOpsSet {
+	return &templatesv1.GitOpsSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "demo-set",
+			Namespace: "default",
+		},
+		Spec: templatesv1.GitOpsSetSpec{
+			Generators: []templatesv1.GitOpsSetGenerator{
+				gsg,
+			},
+		},
+	}
+}
+
+func newSecret(name types.NamespacedName, opts ...func(*corev1.Secret)) *corev1.Secret {
+	s := &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Secret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name.Name,
+			Namespace: name.Namespace,
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			"password": []byte("top-secret"),
+		},
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+func defaultClientFactory(c *scm.Client) clientFactoryFunc {
+	return func(_, _, _ string, opts ...factory.ClientOptionFunc) (*scm.Client, error) {
+		return c, nil
+	}
+}
@@ -0,0 +1,209 @@
+package kubernetesresources
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	templatesv1 "github.com/weaveworks/gitopssets-controller/api/v1alpha1"
+	"github.com/weaveworks/gitopssets-controller/pkg/generators"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/jsonpath"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ClusterScopeNotAllowedError is returned when a generator queries resources
+// outside of the namespace of the GitOpsSet and this is not enabled in the
+// controller.
+type ClusterScopeNotAllowedError struct {
+	GroupVersionKind schema.GroupVersionKind
+}
+
+func (e ClusterScopeNotAllowedError) Error() string {
+	return fmt.Sprintf("querying %s outside of the GitOpsSet namespace is not enabled", e.GroupVersionKind)
+}
+
+// KubernetesResourcesGenerator generates from resources in the cluster.
+type KubernetesResourcesGenerator struct {
+	Client client.Reader
+	logr.Logger
+
+	// AllowClusterScope enables querying cluster-scoped resources and
+	// resources in all namespaces.
+	AllowClusterScope bool
+}
+
+// GeneratorFactory is a function for creating per-reconciliation generators for
+// the KubernetesResourcesGenerator.
+func GeneratorFactory(allowClusterScope bool) generators.GeneratorFactory {
+	return func(l logr.Logger, c client.Reader) generators.Generator {
+		return NewGenerator(l, c, allowClusterScope)
+	}
+}
+
+// NewGenerator creates and returns a new Kubernetes resources generator.
+func NewGenerator(l logr.Logger, c client.Reader, allowClusterScope bool) *KubernetesResourcesGenerator {
+	return &KubernetesResourcesGenerator{
+		Client:            c,
+		Logger:            l,
+		AllowClusterScope: allowClusterScope,
+	}
+}
+
+// Generate is an implementation of the Generator interface.
+//
+// Each resource that is matched by the generator is generated as an element.
+func (g *KubernetesResourcesGenerator) Generate(ctx context.Context, sg *templatesv1.GitOpsSetGenerator, ks *templatesv1.GitOpsSet) ([]map[string]any, error) {
+	if sg == nil {
+		return nil, generators.ErrEmptyGitOpsSet
+	}
+
+	if sg.KubernetesResources == nil {
+		return nil, nil
+	}
+
+	gen := sg.KubernetesResources
+	g.Logger.Info("generating params from KubernetesResources generator", "apiVersion", gen.APIVersion, "kind", gen.Kind)
+
+	gvk, err := GroupVersionKind(gen)
+	if err != nil {
+		return nil, err
+	}
+
+	listOptions, err := g.listOptions(gvk, gen, ks)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	if err := g.Client.List(ctx, list, listOptions); err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", gvk, err)
+	}
+
+	g.Logger.Info("queried resources", "kind", gvk, "count", len(list.Items))
+
+	result := []map[string]any{}
+	for i := range list.Items {
+		element, err := project(list.Items[i].Object, gen.JSONPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate element from %s %s: %w", gvk.Kind, client.ObjectKeyFromObject(&list.Items[i]), err)
+		}
+
+		result = append(result, element)
+	}
+
+	return result, nil
+}
+
+// Interval is an implementation of the Generator interface.
+//
+// KubernetesResourcesGenerator is driven by watching the queried resources.
+func (g *KubernetesResourcesGenerator) Interval(sg *templatesv1.GitOpsSetGenerator) time.Duration {
+	return generators.NoRequeueInterval
+}
+
+// GroupVersionKind parses the APIVersion and Kind from the generator.
+func GroupVersionKind(gen *templatesv1.KubernetesResourcesGenerator) (schema.GroupVersionKind, error) {
+	gv, err := schema.ParseGroupVersion(gen.APIVersion)
+	if err != nil {
+		return schema.GroupVersionKind{}, fmt.Errorf("failed to parse apiVersion %q: %w", gen.APIVersion, err)
+	}
+
+	if gen.Kind == "" {
+		return schema.GroupVersionKind{}, fmt.Errorf("no kind provided for apiVersion %q", gen.APIVersion)
+	}
+
+	return gv.WithKind(gen.Kind), nil
+}
+
+func (g *KubernetesResourcesGenerator) listOptions(gvk schema.GroupVersionKind, gen *templatesv1.KubernetesResourcesGenerator, ks *templatesv1.GitOpsSet) (*client.ListOptions, error) {
+	listOptions := &client.ListOptions{}
+
+	if gen.Selector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(gen.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("unable to convert selector: %w", err)
+		}
+		listOptions.LabelSelector = selector
+	}
+
+	if gen.FieldSelector != "" {
+		selector, err := fields.ParseSelector(gen.FieldSelector)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse field selector %q: %w", gen.FieldSelector, err)
+		}
+		listOptions.FieldSelector = selector
+	}
+
+	namespaced, err := g.isNamespaced(gvk)
+	if err != nil {
+		return nil, err
+	}
+
+	if !namespaced || gen.AllNamespaces {
+		if !g.AllowClusterScope {
+			return nil, ClusterScopeNotAllowedError{GroupVersionKind: gvk}
+		}
+
+		return listOptions, nil
+	}
+
+	listOptions.Namespace = ks.GetNamespace()
+
+	return listOptions, nil
+}
+
+// isNamespaced uses the client to determine whether or not the Kind is
+// namespaced.
+//
+// If the client can't determine this, the Kind is assumed to be namespaced.
+func (g *KubernetesResourcesGenerator) isNamespaced(gvk schema.GroupVersionKind) (bool, error) {
+	c, ok := g.Client.(interface {
+		IsObjectNamespaced(runtime.Object) (bool, error)
+	})
+	if !ok {
+		return true, nil
+	}
+
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(gvk)
+	namespaced, err := c.IsObjectNamespaced(u)
+	if err != nil {
+		return false, fmt.Errorf("failed to determine the scope of %s: %w", gvk, err)
+	}
+
+	return namespaced, nil
+}
+
+func project(obj map[string]any, jsonPath string) (map[string]any, error) {
+	if jsonPath == "" {
+		return obj, nil
+	}
+
+	jp := jsonpath.New("kubernetesresources")
+	if err := jp.Parse(jsonPath); err != nil {
+		return nil, fmt.Errorf("failed to parse JSONPath %q: %w", jsonPath, err)
+	}
+
+	results, err := jp.FindResults(obj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find results from expression %s: %w", jsonPath, err)
+	}
+
+	if len(results) != 1 || len(results[0]) != 1 {
+		return nil, fmt.Errorf("expression %s did not generate a single result", jsonPath)
+	}
+
+	element, ok := results[0][0].Interface().(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("expression %s did not generate an object", jsonPath)
+	}
+
+	return element, nil
+}
@@ -0,0 +1,242 @@
+package kubernetesresources
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	templatesv1 "github.com/weaveworks/gitopssets-controller/api/v1alpha1"
+	"github.com/weaveworks/gitopssets-controller/pkg/generators"
+	"github.com/weaveworks/gitopssets-controller/test"
+)
+
+var _ generators.Generator = (*KubernetesResourcesGenerator)(nil)
+
+func TestGenerate_with_no_generator(t *testing.T) {
+	gen := GeneratorFactory(false)(logr.Discard(), nil)
+	_, err := gen.Generate(context.TODO(), nil, nil)
+
+	if err != generators.ErrEmptyGitOpsSet {
+		t.Errorf("got error %v", err)
+	}
+}
+
+func TestGenerate_with_no_config(t *testing.T) {
+	gen := GeneratorFactory(false)(logr.Discard(), nil)
+	got, err := gen.Generate(context.TODO(), &templatesv1.GitOpsSetGenerator{}, nil)
+
+	if err != nil {
+		t.Errorf("got an error with no resources: %s", err)
+	}
+	if got != nil {
+		t.Errorf("got %v, want %v with no KubernetesResources generator", got, nil)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	testCases := []struct {
+		name              string
+		generator         *templatesv1.KubernetesResourcesGenerator
+		allowClusterScope bool
+		objects           []runtime.Object
+		want              []map[string]any
+	}{
+		{
+			name: "resources in the GitOpsSet namespace",
+			generator: &templatesv1.KubernetesResourcesGenerator{
+				APIVersion: "v1",
+				Kind:       "ConfigMap",
+				JSONPath:   "{.data}",
+			},
+			objects: []runtime.Object{
+				newConfigMap("test-ns", "cm-1", nil, map[string]string{"env": "dev"}),
+				newConfigMap("test-ns", "cm-2", nil, map[string]string{"env": "staging"}),
+				newConfigMap("other-ns", "cm-3", nil, map[string]string{"env": "production"}),
+			},
+			want: []map[string]any{
+				{"env": "dev"},
+				{"env": "staging"},
+			},
+		},
+		{
+			name: "resources filtered by label selector",
+			generator: &templatesv1.KubernetesResourcesGenerator{
+				APIVersion: "v1",
+				Kind:       "ConfigMap",
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{"example.com/generate": "true"},
+				},
+				JSONPath: "{.data}",
+			},
+			objects: []runtime.Object{
+				newConfigMap("test-ns", "cm-1", map[string]string{"example.com/generate": "true"}, map[string]string{"env": "dev"}),
+				newConfigMap("test-ns", "cm-2", nil, map[string]string{"env": "staging"}),
+			},
+			want: []map[string]any{
+				{"env": "dev"},
+			},
+		},
+		{
+			name: "resources in all namespaces",
+			generator: &templatesv1.KubernetesResourcesGenerator{
+				APIVersion:    "v1",
+				Kind:          "ConfigMap",
+				AllNamespaces: true,
+				JSONPath:      "{.metadata.labels}",
+			},
+			allowClusterScope: true,
+			objects: []runtime.Object{
+				newConfigMap("test-ns", "cm-1", map[string]string{"env": "dev"}, nil),
+				newConfigMap("other-ns", "cm-2", map[string]string{"env": "production"}, nil),
+			},
+			want: []map[string]any{
+				{"env": "production"},
+				{"env": "dev"},
+			},
+		},
+		{
+			name: "cluster-scoped resources",
+			generator: &templatesv1.KubernetesResourcesGenerator{
+				APIVersion: "v1",
+				Kind:       "Namespace",
+			},
+			allowClusterScope: true,
+			objects: []runtime.Object{
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:   "team-a",
+						Labels: map[string]string{"team": "a"},
+					},
+				},
+			},
+			want: []map[string]any{
+				{
+					"apiVersion": "v1",
+					"kind":       "Namespace",
+					"metadata": map[string]any{
+						"name":              "team-a",
+						"labels":            map[string]any{"team": "a"},
+						"resourceVersion":   "999",
+						"creationTimestamp": nil,
+					},
+					"spec":   map[string]any{},
+					"status": map[string]any{},
+				},
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			gen := NewGenerator(logr.Discard(), newFakeClient(t, tt.objects...), tt.allowClusterScope)
+			got, err := gen.Generate(context.TODO(), &templatesv1.GitOpsSetGenerator{
+				KubernetesResources: tt.generator,
+			}, newGitOpsSet())
+			test.AssertNoError(t, err)
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("failed to generate resources:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGenerate_errors(t *testing.T) {
+	testCases := []struct {
+		name      string
+		generator *templatesv1.KubernetesResourcesGenerator
+		objects   []runtime.Object
+		wantErr   string
+	}{
+		{
+			name: "cluster-scoped resources are not allowed",
+			generator: &templatesv1.KubernetesResourcesGenerator{
+				APIVersion: "v1",
+				Kind:       "Namespace",
+			},
+			wantErr: "querying /v1, Kind=Namespace outside of the GitOpsSet namespace is not enabled",
+		},
+		{
+			name: "all namespaces are not allowed",
+			generator: &templatesv1.KubernetesResourcesGenerator{
+				APIVersion:    "v1",
+				Kind:          "ConfigMap",
+				AllNamespaces: true,
+			},
+			wantErr: "querying /v1, Kind=ConfigMap outside of the GitOpsSet namespace is not enabled",
+		},
+		{
+			name: "invalid apiVersion",
+			generator: &templatesv1.KubernetesResourcesGenerator{
+				APIVersion: "example.com/v1/test",
+				Kind:       "ConfigMap",
+			},
+			wantErr: `failed to parse apiVersion "example.com/v1/test"`,
+		},
+		{
+			name: "JSONPath does not generate an object",
+			generator: &templatesv1.KubernetesResourcesGenerator{
+				APIVersion: "v1",
+				Kind:       "ConfigMap",
+				JSONPath:   "{.metadata.name}",
+			},
+			objects: []runtime.Object{
+				newConfigMap("test-ns", "cm-1", nil, nil),
+			},
+			wantErr: "expression {.metadata.name} did not generate an object",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			gen := NewGenerator(logr.Discard(), newFakeClient(t, tt.objects...), false)
+			_, err := gen.Generate(context.TODO(), &templatesv1.GitOpsSetGenerator{
+				KubernetesResources: tt.generator,
+			}, newGitOpsSet())
+
+			test.AssertErrorMatch(t, tt.wantErr, err)
+		})
+	}
+}
+
+func newGitOpsSet() *templatesv1.GitOpsSet {
+	return &templatesv1.GitOpsSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "demo-set",
+			Namespace: "test-ns",
+		},
+	}
+}
+
+func newConfigMap(namespace, name string, labels, data map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    labels,
+		},
+		Data: data,
+	}
+}
+
+func newFakeClient(t *testing.T, objs ...runtime.Object) client.WithWatch {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	test.AssertNoError(t, clientgoscheme.AddToScheme(scheme))
+	test.AssertNoError(t, templatesv1.AddToScheme(scheme))
+
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("Namespace"), meta.RESTScopeRoot)
+
+	return fake.NewClientBuilder().WithScheme(scheme).WithRESTMapper(mapper).WithRuntimeObjects(objs...).Build()
+}
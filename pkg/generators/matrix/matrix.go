@@ -12,6 +12,8 @@ import (
 	"github.com/go-logr/logr"
 	templatesv1 "github.com/weaveworks/gitopssets-controller/api/v1alpha1"
 	"github.com/weaveworks/gitopssets-controller/pkg/generators"
+	"github.com/weaveworks/gitopssets-controller/pkg/generators/filter"
+	"github.com/weaveworks/gitopssets-controller/pkg/generators/postprocess"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -49,10 +51,14 @@ func (mg *MatrixGenerator) Generate(ctx context.Context, sg *templatesv1.GitOpsS
 		return nil, nil
 	}
 
+	if err := ValidateNesting(sg.Matrix.Generators); err != nil {
+		return nil, err
+	}
+
 	allGenerators := map[string]generators.Generator{}
 
 	for name, factory := range mg.generatorsMap {
-		g := factory(mg.Logger, mg.Client)
+		g := factory(mg.Logger, generators.ReaderFor(name, mg.Client))
 		allGenerators[name] = g
 	}
 
@@ -78,22 +84,28 @@ func (g *MatrixGenerator) Interval(sg *templatesv1.GitOpsSetGenerator) time.Dura
 	allGenerators := map[string]generators.Generator{}
 
 	for name, factory := range g.generatorsMap {
-		g := factory(g.Logger, g.Client)
+		g := factory(g.Logger, generators.ReaderFor(name, g.Client))
 		allGenerators[name] = g
 	}
 
+	return NestedInterval(g.Logger, sg.Matrix.Generators, allGenerators)
+}
+
+// NestedInterval returns the lowest requeue interval of the nested
+// generators.
+func NestedInterval(logger logr.Logger, nested []templatesv1.GitOpsSetNestedGenerator, allGenerators map[string]generators.Generator) time.Duration {
 	res := []time.Duration{}
-	for _, mg := range sg.Matrix.Generators {
+	for _, mg := range nested {
 		relevantGenerators, err := generators.FindRelevantGenerators(mg, allGenerators)
 		if err != nil {
-			g.Logger.Error(err, "failed to find relevant generators, defaulting to no requeue")
+			logger.Error(err, "failed to find relevant generators, defaulting to no requeue")
 			return generators.NoRequeueInterval
 		}
 
 		for _, rg := range relevantGenerators {
 			gs, err := makeGitOpsSetGenerator(&mg)
 			if err != nil {
-				g.Logger.Error(err, "failed to calculate requeue interval, defaulting to no requeue")
+				logger.Error(err, "failed to calculate requeue interval, defaulting to no requeue")
 				return generators.NoRequeueInterval
 			}
 
@@ -126,24 +138,14 @@ func generate(ctx context.Context, generator templatesv1.GitOpsSetGenerator, all
 	generated := []generatedElements{}
 
 	for _, mg := range generator.Matrix.Generators {
-		name := mg.Name
-		relevantGenerators, err := generators.FindRelevantGenerators(mg, allGenerators)
+		nested, err := GenerateNested(ctx, mg, allGenerators, gitopsSet)
 		if err != nil {
 			return nil, err
 		}
-		for _, g := range relevantGenerators {
-			gs, err := makeGitOpsSetGenerator(&mg)
-			if err != nil {
-				return nil, err
-			}
-
-			res, err := g.Generate(ctx, gs, gitopsSet)
-			if err != nil {
-				return nil, err
-			}
 
+		for _, res := range nested {
 			if len(res) > 0 {
-				generated = append(generated, generatedElements{name: name, elements: res})
+				generated = append(generated, generatedElements{name: mg.Name, elements: res})
 			}
 		}
 	}
@@ -151,6 +153,71 @@ func generate(ctx context.Context, generator templatesv1.GitOpsSetGenerator, all
 	return generated, nil
 }
 
+// GenerateNested generates the elements for each of the generators configured
+// in a nested generator, the elements are filtered and post-processed.
+func GenerateNested(ctx context.Context, mg templatesv1.GitOpsSetNestedGenerator, allGenerators map[string]generators.Generator, gitopsSet *templatesv1.GitOpsSet) ([][]map[string]any, error) {
+	generated := [][]map[string]any{}
+	relevantGenerators, err := generators.FindRelevantGenerators(mg, allGenerators)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, g := range relevantGenerators {
+		gs, err := makeGitOpsSetGenerator(&mg)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := g.Generate(ctx, gs, gitopsSet)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err = filter.Elements(mg.Filter, res)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err = postprocess.Elements(mg.PostProcess, res)
+		if err != nil {
+			return nil, err
+		}
+
+		generated = append(generated, res)
+	}
+
+	return generated, nil
+}
+
+// ValidateNesting returns an error if the Matrix and Merge generators are
+// nested more than templatesv1.MaxNestingDepth levels deep.
+func ValidateNesting(nested []templatesv1.GitOpsSetNestedGenerator) error {
+	return validateNesting(nested, 1)
+}
+
+func validateNesting(nested []templatesv1.GitOpsSetNestedGenerator, depth int) error {
+	for _, mg := range nested {
+		if mg.Matrix == nil && mg.Merge == nil {
+			continue
+		}
+
+		if depth >= templatesv1.MaxNestingDepth {
+			return fmt.Errorf("generators can't be nested more than %d levels deep", templatesv1.MaxNestingDepth)
+		}
+
+		children, err := mg.NestedGenerators()
+		if err != nil {
+			return err
+		}
+
+		if err := validateNesting(children, depth+1); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // makeGitOpsSetGenerator converts a GitOpsSetNestedGenerator struct to a GitOpsSetGenerator struct.
 // This is needed because MatrixGenerator includes GitOpsSetNestedGenerator struct,
 // but the Generate function of the Generator interface expects a GitOpsSetGenerator struct.
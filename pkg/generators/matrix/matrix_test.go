@@ -2,6 +2,7 @@ package matrix
 
 import (
 	"context"
+	"encoding/json"
 	"testing"
 	"time"
 
@@ -20,6 +21,7 @@ import (
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
@@ -240,6 +242,73 @@ func TestMatrixGenerator_Generate(t *testing.T) {
 					"list2": map[string]any{"key2": "value4"}},
 			},
 		},
+		{
+			name: "filtering nested elements",
+			sg: &templatesv1.GitOpsSetGenerator{
+				Matrix: &templatesv1.MatrixGenerator{
+					Generators: []templatesv1.GitOpsSetNestedGenerator{
+						{
+							List: &templatesv1.ListGenerator{
+								Elements: []apiextensionsv1.JSON{
+									{Raw: []byte(`{"cluster": "dev"}`)},
+									{Raw: []byte(`{"cluster": "prod"}`)},
+								},
+							},
+							Filter: `element.cluster != "prod"`,
+						},
+						{
+							List: &templatesv1.ListGenerator{
+								Elements: []apiextensionsv1.JSON{
+									{Raw: []byte(`{"app": "frontend"}`)},
+									{Raw: []byte(`{"app": "backend"}`)},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedMatrix: []map[string]any{
+				{"cluster": "dev", "app": "frontend"},
+				{"cluster": "dev", "app": "backend"},
+			},
+		},
+		{
+			name: "post-processing nested elements",
+			sg: &templatesv1.GitOpsSetGenerator{
+				Matrix: &templatesv1.MatrixGenerator{
+					Generators: []templatesv1.GitOpsSetNestedGenerator{
+						{
+							List: &templatesv1.ListGenerator{
+								Elements: []apiextensionsv1.JSON{
+									{Raw: []byte(`{"cluster": "prod"}`)},
+									{Raw: []byte(`{"cluster": "dev"}`)},
+									{Raw: []byte(`{"cluster": "dev"}`)},
+								},
+							},
+							PostProcess: []templatesv1.PostProcessStep{
+								{SortBy: "element.cluster"},
+								{DedupeBy: "element.cluster"},
+							},
+						},
+						{
+							List: &templatesv1.ListGenerator{
+								Elements: []apiextensionsv1.JSON{
+									{Raw: []byte(`{"app": "frontend"}`)},
+									{Raw: []byte(`{"app": "backend"}`)},
+								},
+							},
+							PostProcess: []templatesv1.PostProcessStep{
+								{Limit: ptr.To(1)},
+							},
+						},
+					},
+				},
+			},
+			expectedMatrix: []map[string]any{
+				{"cluster": "dev", "app": "frontend"},
+				{"cluster": "prod", "app": "frontend"},
+			},
+		},
 		{
 			name: "naming nested elements with three generators",
 			sg: &templatesv1.GitOpsSetGenerator{
@@ -343,6 +412,161 @@ func TestMatrixGenerator_Generate(t *testing.T) {
 	}
 }
 
+func TestMatrixGenerator_nested(t *testing.T) {
+	nestedMatrix := `{
+	"matrix": {
+		"generators": [
+			{"list": {"elements": [{"app": "frontend"}, {"app": "backend"}]}},
+			{"list": {"elements": [{"version": "v1"}]}}
+		]
+	}
+}`
+
+	tests := []struct {
+		name             string
+		sg               *templatesv1.GitOpsSetGenerator
+		expectedMatrix   []map[string]any
+		expectedErrorStr string
+	}{
+		{
+			name: "matrix nested in a matrix",
+			sg: &templatesv1.GitOpsSetGenerator{
+				Matrix: &templatesv1.MatrixGenerator{
+					Generators: []templatesv1.GitOpsSetNestedGenerator{
+						{
+							List: &templatesv1.ListGenerator{
+								Elements: []apiextensionsv1.JSON{
+									{Raw: []byte(`{"cluster": "dev"}`)},
+									{Raw: []byte(`{"cluster": "prod"}`)},
+								},
+							},
+						},
+						parseNestedGenerator(t, nestedMatrix),
+					},
+				},
+			},
+			expectedMatrix: []map[string]any{
+				{"cluster": "dev", "app": "frontend", "version": "v1"},
+				{"cluster": "dev", "app": "backend", "version": "v1"},
+				{"cluster": "prod", "app": "frontend", "version": "v1"},
+				{"cluster": "prod", "app": "backend", "version": "v1"},
+			},
+		},
+		{
+			name: "named matrix nested in a matrix",
+			sg: &templatesv1.GitOpsSetGenerator{
+				Matrix: &templatesv1.MatrixGenerator{
+					Generators: []templatesv1.GitOpsSetNestedGenerator{
+						{
+							List: &templatesv1.ListGenerator{
+								Elements: []apiextensionsv1.JSON{
+									{Raw: []byte(`{"cluster": "dev"}`)},
+								},
+							},
+						},
+						parseNestedGenerator(t, `{
+	"name": "apps",
+	"matrix": {
+		"singleElement": true,
+		"generators": [
+			{"name": "apps", "list": {"elements": [{"app": "frontend"}, {"app": "backend"}]}}
+		]
+	}
+}`),
+					},
+				},
+			},
+			expectedMatrix: []map[string]any{
+				{
+					"cluster": "dev",
+					"apps": map[string]any{
+						"apps": []map[string]any{{"app": "frontend"}, {"app": "backend"}},
+					},
+				},
+			},
+		},
+		{
+			name: "three levels of nesting",
+			sg: &templatesv1.GitOpsSetGenerator{
+				Matrix: &templatesv1.MatrixGenerator{
+					Generators: []templatesv1.GitOpsSetNestedGenerator{
+						{
+							List: &templatesv1.ListGenerator{
+								Elements: []apiextensionsv1.JSON{
+									{Raw: []byte(`{"cluster": "dev"}`)},
+								},
+							},
+						},
+						parseNestedGenerator(t, `{
+	"matrix": {
+		"generators": [
+			{"list": {"elements": [{"region": "eu-west-1"}]}},
+			`+nestedMatrix+`
+		]
+	}
+}`),
+					},
+				},
+			},
+			expectedMatrix: []map[string]any{
+				{"cluster": "dev", "region": "eu-west-1", "app": "frontend", "version": "v1"},
+				{"cluster": "dev", "region": "eu-west-1", "app": "backend", "version": "v1"},
+			},
+		},
+		{
+			name: "nested too deeply",
+			sg: &templatesv1.GitOpsSetGenerator{
+				Matrix: &templatesv1.MatrixGenerator{
+					Generators: []templatesv1.GitOpsSetNestedGenerator{
+						parseNestedGenerator(t, `{
+	"matrix": {
+		"generators": [
+			{"matrix": {"generators": [`+nestedMatrix+`]}}
+		]
+	}
+}`),
+					},
+				},
+			},
+			expectedErrorStr: "generators can't be nested more than 3 levels deep",
+		},
+		{
+			name: "invalid nested generator",
+			sg: &templatesv1.GitOpsSetGenerator{
+				Matrix: &templatesv1.MatrixGenerator{
+					Generators: []templatesv1.GitOpsSetNestedGenerator{
+						{
+							Matrix: &templatesv1.NestedMatrixGenerator{
+								Generators: []apiextensionsv1.JSON{
+									{Raw: []byte(`{"list": "unknown"}`)},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedErrorStr: "failed to parse nested generator",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			factories := map[string]generators.GeneratorFactory{
+				"List": list.GeneratorFactory,
+			}
+			factories["Matrix"] = GeneratorFactory(factories)
+			g := NewGenerator(logr.Discard(), nil, factories)
+
+			matrix, err := g.Generate(context.TODO(), tt.sg, &templatesv1.GitOpsSet{})
+			test.AssertErrorMatch(t, tt.expectedErrorStr, err)
+
+			if diff := cmp.Diff(tt.expectedMatrix, matrix); diff != "" {
+				t.Errorf("matrix mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestDisabledGenerators(t *testing.T) {
 	gen := NewGenerator(logr.Discard(), nil, map[string]generators.GeneratorFactory{
 		"List": list.GeneratorFactory,
@@ -578,3 +802,13 @@ func newFakeClient(t *testing.T, objs ...runtime.Object) client.WithWatch {
 
 	return fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objs...).Build()
 }
+
+func parseNestedGenerator(t *testing.T, s string) templatesv1.GitOpsSetNestedGenerator {
+	t.Helper()
+	var generator templatesv1.GitOpsSetNestedGenerator
+	if err := json.Unmarshal([]byte(s), &generator); err != nil {
+		t.Fatal(err)
+	}
+
+	return generator
+}
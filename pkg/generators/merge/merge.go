@@ -0,0 +1,175 @@
+package merge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"dario.cat/mergo"
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	templatesv1 "github.com/weaveworks/gitopssets-controller/api/v1alpha1"
+	"github.com/weaveworks/gitopssets-controller/pkg/generators"
+	"github.com/weaveworks/gitopssets-controller/pkg/generators/matrix"
+)
+
+var errNoMergeKeys = errors.New("no mergeKeys provided")
+
+// MergeGenerator is a generator that merges the results of multiple
+// generators by a set of keys.
+type MergeGenerator struct {
+	Client client.Reader
+	logr.Logger
+	generatorsMap map[string]generators.GeneratorFactory
+}
+
+// GeneratorFactory is a function for creating per-reconciliation generators for
+// the MergeGenerator.
+func GeneratorFactory(generatorsMap map[string]generators.GeneratorFactory) generators.GeneratorFactory {
+	return func(l logr.Logger, c client.Reader) generators.Generator {
+		return NewGenerator(l, c, generatorsMap)
+	}
+}
+
+// NewGenerator creates and returns a new merge generator.
+func NewGenerator(l logr.Logger, c client.Reader, g map[string]generators.GeneratorFactory) *MergeGenerator {
+	return &MergeGenerator{
+		Client:        c,
+		Logger:        l,
+		generatorsMap: g,
+	}
+}
+
+func (mg *MergeGenerator) Generate(ctx context.Context, sg *templatesv1.GitOpsSetGenerator, ks *templatesv1.GitOpsSet) ([]map[string]any, error) {
+	if sg == nil {
+		return nil, generators.ErrEmptyGitOpsSet
+	}
+
+	if sg.Merge == nil {
+		return nil, nil
+	}
+
+	if len(sg.Merge.MergeKeys) == 0 {
+		return nil, errNoMergeKeys
+	}
+
+	if err := matrix.ValidateNesting(sg.Merge.Generators); err != nil {
+		return nil, err
+	}
+
+	allGenerators := map[string]generators.Generator{}
+	for name, factory := range mg.generatorsMap {
+		g := factory(mg.Logger, generators.ReaderFor(name, mg.Client))
+		allGenerators[name] = g
+	}
+
+	generated := [][]map[string]any{}
+	for _, nested := range sg.Merge.Generators {
+		if nested.Name != "" {
+			return nil, fmt.Errorf("merged generators can't be named, got %q", nested.Name)
+		}
+
+		res, err := matrix.GenerateNested(ctx, nested, allGenerators, ks)
+		if err != nil {
+			return nil, err
+		}
+
+		elements := []map[string]any{}
+		for _, r := range res {
+			elements = append(elements, r...)
+		}
+		generated = append(generated, elements)
+	}
+
+	merged, err := merge(sg.Merge.MergeKeys, generated)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge generators: %w", err)
+	}
+
+	return merged, nil
+}
+
+// Interval is an implementation of the Generator interface.
+func (mg *MergeGenerator) Interval(sg *templatesv1.GitOpsSetGenerator) time.Duration {
+	allGenerators := map[string]generators.Generator{}
+	for name, factory := range mg.generatorsMap {
+		g := factory(mg.Logger, generators.ReaderFor(name, mg.Client))
+		allGenerators[name] = g
+	}
+
+	return matrix.NestedInterval(mg.Logger, sg.Merge.Generators, allGenerators)
+}
+
+// merge left-joins the elements of each generator onto the elements of the
+// first generator.
+//
+// The values from the later generators override the values in the first.
+func merge(mergeKeys []string, generated [][]map[string]any) ([]map[string]any, error) {
+	if len(generated) == 0 {
+		return []map[string]any{}, nil
+	}
+
+	base, err := elementsByKey(mergeKeys, generated[0])
+	if err != nil {
+		return nil, err
+	}
+
+	merged := make([]map[string]any, len(generated[0]))
+	for i, element := range generated[0] {
+		merged[i] = map[string]any{}
+		if err := mergo.Merge(&merged[i], element); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, elements := range generated[1:] {
+		byKey, err := elementsByKey(mergeKeys, elements)
+		if err != nil {
+			return nil, err
+		}
+
+		for key, i := range base {
+			j, ok := byKey[key]
+			if !ok {
+				continue
+			}
+
+			if err := mergo.Merge(&merged[i], elements[j], mergo.WithOverride); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return merged, nil
+}
+
+// elementsByKey indexes the elements by the values of the merge keys.
+func elementsByKey(mergeKeys []string, elements []map[string]any) (map[string]int, error) {
+	byKey := map[string]int{}
+	for i, element := range elements {
+		values := make([]any, len(mergeKeys))
+		for j, mergeKey := range mergeKeys {
+			v, ok := element[mergeKey]
+			if !ok {
+				return nil, fmt.Errorf("element is missing merge key %q", mergeKey)
+			}
+			values[j] = v
+		}
+
+		b, err := json.Marshal(values)
+		if err != nil {
+			return nil, fmt.Errorf("failed to calculate merge key: %w", err)
+		}
+		key := string(b)
+
+		if _, ok := byKey[key]; ok {
+			return nil, fmt.Errorf("duplicate elements for merge key %s", key)
+		}
+		byKey[key] = i
+	}
+
+	return byKey, nil
+}
@@ -0,0 +1,342 @@
+package merge
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/google/go-cmp/cmp"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	templatesv1 "github.com/weaveworks/gitopssets-controller/api/v1alpha1"
+	"github.com/weaveworks/gitopssets-controller/pkg/generators"
+	"github.com/weaveworks/gitopssets-controller/pkg/generators/list"
+	"github.com/weaveworks/gitopssets-controller/pkg/generators/matrix"
+	"github.com/weaveworks/gitopssets-controller/pkg/generators/pullrequests"
+	"github.com/weaveworks/gitopssets-controller/test"
+)
+
+func TestMergeGenerator_Generate(t *testing.T) {
+	clusters := templatesv1.GitOpsSetNestedGenerator{
+		List: &templatesv1.ListGenerator{
+			Elements: []apiextensionsv1.JSON{
+				{Raw: []byte(`{"cluster": "dev", "replicas": 1, "region": "eu-west-1"}`)},
+				{Raw: []byte(`{"cluster": "staging", "replicas": 1, "region": "eu-west-1"}`)},
+				{Raw: []byte(`{"cluster": "prod", "replicas": 1, "region": "eu-west-1"}`)},
+			},
+		},
+	}
+
+	tests := []struct {
+		name             string
+		sg               *templatesv1.GitOpsSetGenerator
+		expectedElements []map[string]any
+		expectedErrorStr string
+	}{
+		{
+			name:             "nil sg",
+			sg:               nil,
+			expectedErrorStr: "GitOpsSet is empty",
+		},
+		{
+			name: "nil merge",
+			sg:   &templatesv1.GitOpsSetGenerator{},
+		},
+		{
+			name: "no merge keys",
+			sg: &templatesv1.GitOpsSetGenerator{
+				Merge: &templatesv1.MergeGenerator{
+					Generators: []templatesv1.GitOpsSetNestedGenerator{clusters},
+				},
+			},
+			expectedErrorStr: "no mergeKeys provided",
+		},
+		{
+			name: "overriding values by key",
+			sg: &templatesv1.GitOpsSetGenerator{
+				Merge: &templatesv1.MergeGenerator{
+					MergeKeys: []string{"cluster"},
+					Generators: []templatesv1.GitOpsSetNestedGenerator{
+						clusters,
+						{
+							List: &templatesv1.ListGenerator{
+								Elements: []apiextensionsv1.JSON{
+									{Raw: []byte(`{"cluster": "prod", "replicas": 5}`)},
+									{Raw: []byte(`{"cluster": "test", "replicas": 2}`)},
+								},
+							},
+						},
+						{
+							List: &templatesv1.ListGenerator{
+								Elements: []apiextensionsv1.JSON{
+									{Raw: []byte(`{"cluster": "prod", "region": "us-east-1"}`)},
+									{Raw: []byte(`{"cluster": "staging", "region": "eu-central-1"}`)},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedElements: []map[string]any{
+				{"cluster": "dev", "replicas": 1.0, "region": "eu-west-1"},
+				{"cluster": "staging", "replicas": 1.0, "region": "eu-central-1"},
+				{"cluster": "prod", "replicas": 5.0, "region": "us-east-1"},
+			},
+		},
+		{
+			name: "merging by multiple keys",
+			sg: &templatesv1.GitOpsSetGenerator{
+				Merge: &templatesv1.MergeGenerator{
+					MergeKeys: []string{"cluster", "region"},
+					Generators: []templatesv1.GitOpsSetNestedGenerator{
+						clusters,
+						{
+							List: &templatesv1.ListGenerator{
+								Elements: []apiextensionsv1.JSON{
+									{Raw: []byte(`{"cluster": "dev", "region": "eu-west-1", "replicas": 2}`)},
+									{Raw: []byte(`{"cluster": "prod", "region": "us-east-1", "replicas": 5}`)},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedElements: []map[string]any{
+				{"cluster": "dev", "replicas": 2.0, "region": "eu-west-1"},
+				{"cluster": "staging", "replicas": 1.0, "region": "eu-west-1"},
+				{"cluster": "prod", "replicas": 1.0, "region": "eu-west-1"},
+			},
+		},
+		{
+			name: "filtering merged generators",
+			sg: &templatesv1.GitOpsSetGenerator{
+				Merge: &templatesv1.MergeGenerator{
+					MergeKeys: []string{"cluster"},
+					Generators: []templatesv1.GitOpsSetNestedGenerator{
+						clusters,
+						{
+							List: &templatesv1.ListGenerator{
+								Elements: []apiextensionsv1.JSON{
+									{Raw: []byte(`{"cluster": "dev", "replicas": 2}`)},
+									{Raw: []byte(`{"cluster": "prod", "replicas": 5}`)},
+								},
+							},
+							Filter: `element.cluster == "prod"`,
+						},
+					},
+				},
+			},
+			expectedElements: []map[string]any{
+				{"cluster": "dev", "replicas": 1.0, "region": "eu-west-1"},
+				{"cluster": "staging", "replicas": 1.0, "region": "eu-west-1"},
+				{"cluster": "prod", "replicas": 5.0, "region": "eu-west-1"},
+			},
+		},
+		{
+			name: "base generator generates no elements",
+			sg: &templatesv1.GitOpsSetGenerator{
+				Merge: &templatesv1.MergeGenerator{
+					MergeKeys: []string{"cluster"},
+					Generators: []templatesv1.GitOpsSetNestedGenerator{
+						{List: &templatesv1.ListGenerator{}},
+						clusters,
+					},
+				},
+			},
+			expectedElements: []map[string]any{},
+		},
+		{
+			name: "missing merge key",
+			sg: &templatesv1.GitOpsSetGenerator{
+				Merge: &templatesv1.MergeGenerator{
+					MergeKeys:  []string{"name"},
+					Generators: []templatesv1.GitOpsSetNestedGenerator{clusters},
+				},
+			},
+			expectedErrorStr: `failed to merge generators: element is missing merge key "name"`,
+		},
+		{
+			name: "duplicate merge keys",
+			sg: &templatesv1.GitOpsSetGenerator{
+				Merge: &templatesv1.MergeGenerator{
+					MergeKeys:  []string{"region"},
+					Generators: []templatesv1.GitOpsSetNestedGenerator{clusters},
+				},
+			},
+			expectedErrorStr: `failed to merge generators: duplicate elements for merge key \["eu-west-1"\]`,
+		},
+		{
+			name: "named generators",
+			sg: &templatesv1.GitOpsSetGenerator{
+				Merge: &templatesv1.MergeGenerator{
+					MergeKeys: []string{"cluster"},
+					Generators: []templatesv1.GitOpsSetNestedGenerator{
+						{Name: "clusters", List: clusters.List},
+					},
+				},
+			},
+			expectedErrorStr: `merged generators can't be named, got "clusters"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGenerator(logr.Discard(), nil, map[string]generators.GeneratorFactory{
+				"List": list.GeneratorFactory,
+			})
+			merged, err := g.Generate(context.TODO(), tt.sg, &templatesv1.GitOpsSet{})
+			test.AssertErrorMatch(t, tt.expectedErrorStr, err)
+
+			if diff := cmp.Diff(tt.expectedElements, merged); diff != "" {
+				t.Errorf("merge mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMergeGenerator_nested(t *testing.T) {
+	factories := map[string]generators.GeneratorFactory{
+		"List": list.GeneratorFactory,
+	}
+	factories["Matrix"] = matrix.GeneratorFactory(factories)
+	factories["Merge"] = GeneratorFactory(factories)
+
+	t.Run("merge nested in a matrix", func(t *testing.T) {
+		g := matrix.NewGenerator(logr.Discard(), nil, factories)
+		sg := &templatesv1.GitOpsSetGenerator{
+			Matrix: &templatesv1.MatrixGenerator{
+				Generators: []templatesv1.GitOpsSetNestedGenerator{
+					{
+						List: &templatesv1.ListGenerator{
+							Elements: []apiextensionsv1.JSON{
+								{Raw: []byte(`{"app": "frontend"}`)},
+							},
+						},
+					},
+					{
+						Merge: &templatesv1.NestedMergeGenerator{
+							MergeKeys: []string{"cluster"},
+							Generators: []apiextensionsv1.JSON{
+								{Raw: []byte(`{"list": {"elements": [{"cluster": "dev", "replicas": 1}, {"cluster": "prod", "replicas": 1}]}}`)},
+								{Raw: []byte(`{"list": {"elements": [{"cluster": "prod", "replicas": 5}]}}`)},
+							},
+						},
+					},
+				},
+			},
+		}
+
+		generated, err := g.Generate(context.TODO(), sg, &templatesv1.GitOpsSet{})
+		test.AssertNoError(t, err)
+
+		want := []map[string]any{
+			{"app": "frontend", "cluster": "dev", "replicas": 1.0},
+			{"app": "frontend", "cluster": "prod", "replicas": 5.0},
+		}
+		if diff := cmp.Diff(want, generated); diff != "" {
+			t.Errorf("matrix mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("matrix nested in a merge", func(t *testing.T) {
+		g := NewGenerator(logr.Discard(), nil, factories)
+		sg := &templatesv1.GitOpsSetGenerator{
+			Merge: &templatesv1.MergeGenerator{
+				MergeKeys: []string{"cluster", "app"},
+				Generators: []templatesv1.GitOpsSetNestedGenerator{
+					{
+						Matrix: &templatesv1.NestedMatrixGenerator{
+							Generators: []apiextensionsv1.JSON{
+								{Raw: []byte(`{"list": {"elements": [{"cluster": "dev"}, {"cluster": "prod"}]}}`)},
+								{Raw: []byte(`{"list": {"elements": [{"app": "frontend", "replicas": 1}]}}`)},
+							},
+						},
+					},
+					{
+						List: &templatesv1.ListGenerator{
+							Elements: []apiextensionsv1.JSON{
+								{Raw: []byte(`{"cluster": "prod", "app": "frontend", "replicas": 5}`)},
+							},
+						},
+					},
+				},
+			},
+		}
+
+		generated, err := g.Generate(context.TODO(), sg, &templatesv1.GitOpsSet{})
+		test.AssertNoError(t, err)
+
+		want := []map[string]any{
+			{"app": "frontend", "cluster": "dev", "replicas": 1.0},
+			{"app": "frontend", "cluster": "prod", "replicas": 5.0},
+		}
+		if diff := cmp.Diff(want, generated); diff != "" {
+			t.Errorf("merge mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestDisabledGenerators(t *testing.T) {
+	gen := NewGenerator(logr.Discard(), nil, map[string]generators.GeneratorFactory{
+		"List": list.GeneratorFactory,
+	})
+
+	sg := &templatesv1.GitOpsSetGenerator{
+		Merge: &templatesv1.MergeGenerator{
+			MergeKeys: []string{"cluster"},
+			Generators: []templatesv1.GitOpsSetNestedGenerator{
+				{
+					List: &templatesv1.ListGenerator{
+						Elements: []apiextensionsv1.JSON{
+							{Raw: []byte(`{"cluster": "cluster","url": "url"}`)},
+						},
+					},
+				},
+				// Not actually used as it is disabled
+				{GitRepository: &templatesv1.GitRepositoryGenerator{}},
+			},
+		},
+	}
+
+	_, err := gen.Generate(context.TODO(), sg, &templatesv1.GitOpsSet{})
+	test.AssertErrorMatch(t, `generator GitRepository not enabled`, err)
+}
+
+func TestInterval(t *testing.T) {
+	gen := NewGenerator(logr.Discard(), nil, map[string]generators.GeneratorFactory{
+		"List":         list.GeneratorFactory,
+		"PullRequests": pullrequests.GeneratorFactory,
+	})
+
+	interval := time.Minute * 30
+	sg := &templatesv1.GitOpsSetGenerator{
+		Merge: &templatesv1.MergeGenerator{
+			MergeKeys: []string{"cluster"},
+			Generators: []templatesv1.GitOpsSetNestedGenerator{
+				{
+					List: &templatesv1.ListGenerator{
+						Elements: []apiextensionsv1.JSON{
+							{Raw: []byte(`{"cluster": "cluster","url": "url"}`)},
+						},
+					},
+				},
+				{
+					PullRequests: &templatesv1.PullRequestGenerator{
+						Driver:    "fake",
+						ServerURL: "https://example.com",
+						Repo:      "test-org/my-repo",
+						Interval:  metav1.Duration{Duration: interval},
+					},
+				},
+			},
+		},
+	}
+
+	d := gen.Interval(sg)
+
+	if d != interval {
+		t.Fatalf("got %#v want %#v", d, interval)
+	}
+}
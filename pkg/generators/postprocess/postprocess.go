@@ -0,0 +1,231 @@
+package postprocess
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"text/template"
+
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	templatesv1 "github.com/weaveworks/gitopssets-controller/api/v1alpha1"
+	"github.com/weaveworks/gitopssets-controller/pkg/generators/filter"
+	"github.com/weaveworks/gitopssets-controller/pkg/templatefuncs"
+)
+
+var templateFuncs template.FuncMap = templatefuncs.FuncMap()
+
+// Elements applies the steps in order to the generated elements.
+//
+// The elements are not modified, transformed elements are copied.
+func Elements(steps []templatesv1.PostProcessStep, elements []map[string]any) ([]map[string]any, error) {
+	var err error
+	for i, step := range steps {
+		elements, err = processStep(step, elements)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply postProcess step %d: %w", i, err)
+		}
+	}
+
+	return elements, nil
+}
+
+func processStep(step templatesv1.PostProcessStep, elements []map[string]any) ([]map[string]any, error) {
+	if err := validateStep(step); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case step.SortBy != "":
+		return sortElements(step.SortBy, elements)
+	case step.DedupeBy != "":
+		return dedupeElements(step.DedupeBy, elements)
+	case step.Limit != nil:
+		if *step.Limit < len(elements) {
+			return elements[:*step.Limit], nil
+		}
+		return elements, nil
+	case len(step.Transform) > 0:
+		return transformElements(step.Transform, elements)
+	}
+
+	return elements, nil
+}
+
+// validateStep returns an error if the step doesn't have exactly one of the
+// fields set, or the limit is negative.
+func validateStep(step templatesv1.PostProcessStep) error {
+	set := 0
+	for _, isSet := range []bool{step.SortBy != "", step.DedupeBy != "", step.Limit != nil, len(step.Transform) > 0} {
+		if isSet {
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("step must have exactly one of sortBy, dedupeBy, limit or transform, got %d", set)
+	}
+
+	if step.Limit != nil && *step.Limit < 0 {
+		return fmt.Errorf("limit must not be negative, got %d", *step.Limit)
+	}
+
+	return nil
+}
+
+// sortElements sorts the elements by the result of evaluating the expression,
+// elements with equal results keep their generated order.
+func sortElements(expression string, elements []map[string]any) ([]map[string]any, error) {
+	keys, err := evaluate(expression, elements)
+	if err != nil {
+		return nil, err
+	}
+
+	indexes := make([]int, len(elements))
+	for i := range indexes {
+		indexes[i] = i
+	}
+
+	var sortErr error
+	sort.SliceStable(indexes, func(i, j int) bool {
+		comparer, ok := keys[indexes[i]].(traits.Comparer)
+		if !ok {
+			sortErr = fmt.Errorf("failed to sort by %q: %s values can't be compared", expression, keys[indexes[i]].Type().TypeName())
+			return false
+		}
+
+		result := comparer.Compare(keys[indexes[j]])
+		if types.IsError(result) {
+			sortErr = fmt.Errorf("failed to sort by %q: %v", expression, result)
+			return false
+		}
+
+		return result == types.IntNegOne
+	})
+	if sortErr != nil {
+		return nil, sortErr
+	}
+
+	sorted := make([]map[string]any, len(elements))
+	for i, index := range indexes {
+		sorted[i] = elements[index]
+	}
+
+	return sorted, nil
+}
+
+// dedupeElements keeps the first element for each result of evaluating the
+// expression.
+func dedupeElements(expression string, elements []map[string]any) ([]map[string]any, error) {
+	keys, err := evaluate(expression, elements)
+	if err != nil {
+		return nil, err
+	}
+
+	deduped := []map[string]any{}
+	seen := []ref.Val{}
+	for i, key := range keys {
+		if containsKey(seen, key) {
+			continue
+		}
+		seen = append(seen, key)
+		deduped = append(deduped, elements[i])
+	}
+
+	return deduped, nil
+}
+
+func containsKey(keys []ref.Val, key ref.Val) bool {
+	for _, k := range keys {
+		if k.Equal(key) == types.True {
+			return true
+		}
+	}
+
+	return false
+}
+
+// transformElements sets the fields on copies of the elements, each transform
+// can refer to the fields set by the transforms before it.
+func transformElements(transforms []templatesv1.ElementTransform, elements []map[string]any) ([]map[string]any, error) {
+	transformed := make([]map[string]any, len(elements))
+	for i, element := range elements {
+		transformed[i] = make(map[string]any, len(element)+len(transforms))
+		for k, v := range element {
+			transformed[i][k] = v
+		}
+	}
+
+	for _, transform := range transforms {
+		values, err := transformValues(transform, transformed)
+		if err != nil {
+			return nil, fmt.Errorf("failed to transform field %q: %w", transform.Field, err)
+		}
+
+		for i := range transformed {
+			transformed[i][transform.Field] = values[i]
+		}
+	}
+
+	return transformed, nil
+}
+
+func transformValues(transform templatesv1.ElementTransform, elements []map[string]any) ([]any, error) {
+	values := make([]any, len(elements))
+	switch {
+	case transform.Expression != "":
+		results, err := evaluate(transform.Expression, elements)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, result := range results {
+			native, err := result.ConvertToNative(reflect.TypeOf(&structpb.Value{}))
+			if err != nil {
+				return nil, err
+			}
+			values[i] = native.(*structpb.Value).AsInterface()
+		}
+	case transform.Template != "":
+		tmpl, err := template.New(transform.Field).
+			Option("missingkey=error").
+			Funcs(templateFuncs).Parse(transform.Template)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template: %w", err)
+		}
+
+		for i, element := range elements {
+			var out bytes.Buffer
+			if err := tmpl.Execute(&out, map[string]any{"Element": element}); err != nil {
+				return nil, fmt.Errorf("failed to render template: %w", err)
+			}
+			values[i] = out.String()
+		}
+	default:
+		return nil, errors.New("transform must have an expression or a template")
+	}
+
+	return values, nil
+}
+
+func evaluate(expression string, elements []map[string]any) ([]ref.Val, error) {
+	program, err := filter.Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]ref.Val, len(elements))
+	for i, element := range elements {
+		out, _, err := program.Eval(map[string]any{filter.ElementVariable: element})
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate expression %q: %w", expression, err)
+		}
+		results[i] = out
+	}
+
+	return results, nil
+}
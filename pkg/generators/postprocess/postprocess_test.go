@@ -0,0 +1,202 @@
+package postprocess
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/utils/ptr"
+
+	templatesv1 "github.com/weaveworks/gitopssets-controller/api/v1alpha1"
+	"github.com/weaveworks/gitopssets-controller/test"
+)
+
+func TestElements(t *testing.T) {
+	elements := []map[string]any{
+		{"Branch": "feature/widgets", "Number": 3.0},
+		{"Branch": "main", "Number": 1.0},
+		{"Branch": "feature/widgets", "Number": 2.0},
+		{"Branch": "feature/gadgets", "Number": 4.0},
+	}
+
+	testCases := []struct {
+		name  string
+		steps []templatesv1.PostProcessStep
+		want  []map[string]any
+	}{
+		{
+			name: "no steps",
+			want: elements,
+		},
+		{
+			name:  "sorting",
+			steps: []templatesv1.PostProcessStep{{SortBy: "element.Number"}},
+			want: []map[string]any{
+				{"Branch": "main", "Number": 1.0},
+				{"Branch": "feature/widgets", "Number": 2.0},
+				{"Branch": "feature/widgets", "Number": 3.0},
+				{"Branch": "feature/gadgets", "Number": 4.0},
+			},
+		},
+		{
+			name:  "sorting is stable",
+			steps: []templatesv1.PostProcessStep{{SortBy: "element.Branch"}},
+			want: []map[string]any{
+				{"Branch": "feature/gadgets", "Number": 4.0},
+				{"Branch": "feature/widgets", "Number": 3.0},
+				{"Branch": "feature/widgets", "Number": 2.0},
+				{"Branch": "main", "Number": 1.0},
+			},
+		},
+		{
+			name:  "deduping",
+			steps: []templatesv1.PostProcessStep{{DedupeBy: "element.Branch"}},
+			want: []map[string]any{
+				{"Branch": "feature/widgets", "Number": 3.0},
+				{"Branch": "main", "Number": 1.0},
+				{"Branch": "feature/gadgets", "Number": 4.0},
+			},
+		},
+		{
+			name:  "limiting",
+			steps: []templatesv1.PostProcessStep{{Limit: ptr.To(2)}},
+			want: []map[string]any{
+				{"Branch": "feature/widgets", "Number": 3.0},
+				{"Branch": "main", "Number": 1.0},
+			},
+		},
+		{
+			name:  "limiting to more than the elements",
+			steps: []templatesv1.PostProcessStep{{Limit: ptr.To(10)}},
+			want:  elements,
+		},
+		{
+			name: "transforming",
+			steps: []templatesv1.PostProcessStep{
+				{
+					Transform: []templatesv1.ElementTransform{
+						{Field: "Feature", Expression: `element.Branch.startsWith("feature/")`},
+						{Field: "Name", Template: `pr-{{ .Element.Number }}-{{ .Element.Branch | sanitize }}`},
+						{Field: "Labels", Expression: `{"feature": string(element.Feature)}`},
+					},
+				},
+				{Limit: ptr.To(2)},
+			},
+			want: []map[string]any{
+				{
+					"Branch": "feature/widgets", "Number": 3.0, "Feature": true,
+					"Name": "pr-3-featurewidgets", "Labels": map[string]any{"feature": "true"},
+				},
+				{
+					"Branch": "main", "Number": 1.0, "Feature": false,
+					"Name": "pr-1-main", "Labels": map[string]any{"feature": "false"},
+				},
+			},
+		},
+		{
+			name: "steps are applied in order",
+			steps: []templatesv1.PostProcessStep{
+				{SortBy: "element.Number"},
+				{DedupeBy: "element.Branch"},
+				{Limit: ptr.To(2)},
+			},
+			want: []map[string]any{
+				{"Branch": "main", "Number": 1.0},
+				{"Branch": "feature/widgets", "Number": 2.0},
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			processed, err := Elements(tt.steps, elements)
+			test.AssertNoError(t, err)
+
+			if diff := cmp.Diff(tt.want, processed); diff != "" {
+				t.Fatalf("failed to process elements:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestElements_does_not_modify_elements(t *testing.T) {
+	elements := []map[string]any{{"Branch": "main"}}
+
+	_, err := Elements([]templatesv1.PostProcessStep{
+		{Transform: []templatesv1.ElementTransform{{Field: "Name", Template: "{{ .Element.Branch }}"}}},
+	}, elements)
+	test.AssertNoError(t, err)
+
+	if diff := cmp.Diff([]map[string]any{{"Branch": "main"}}, elements); diff != "" {
+		t.Fatalf("elements were modified:\n%s", diff)
+	}
+}
+
+func TestElements_errors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		steps   []templatesv1.PostProcessStep
+		wantErr string
+	}{
+		{
+			name:    "step without any fields",
+			steps:   []templatesv1.PostProcessStep{{}},
+			wantErr: `failed to apply postProcess step 0: step must have exactly one of sortBy, dedupeBy, limit or transform, got 0`,
+		},
+		{
+			name:    "step with more than one field",
+			steps:   []templatesv1.PostProcessStep{{SortBy: "element.Branch", Limit: ptr.To(1)}},
+			wantErr: `failed to apply postProcess step 0: step must have exactly one of sortBy, dedupeBy, limit or transform, got 2`,
+		},
+		{
+			name:    "negative limit",
+			steps:   []templatesv1.PostProcessStep{{Limit: ptr.To(1)}, {Limit: ptr.To(-1)}},
+			wantErr: `failed to apply postProcess step 1: limit must not be negative, got -1`,
+		},
+		{
+			name:    "invalid sort expression",
+			steps:   []templatesv1.PostProcessStep{{SortBy: "element.Number +"}},
+			wantErr: `failed to apply postProcess step 0: failed to compile expression "element.Number \+"`,
+		},
+		{
+			name:    "sorting by mixed types",
+			steps:   []templatesv1.PostProcessStep{{SortBy: "element.Number"}},
+			wantErr: `failed to apply postProcess step 0: failed to sort by "element.Number": no such overload`,
+		},
+		{
+			name:    "sorting by values that can't be compared",
+			steps:   []templatesv1.PostProcessStep{{SortBy: "element"}},
+			wantErr: `failed to apply postProcess step 0: failed to sort by "element": map values can't be compared`,
+		},
+		{
+			name:    "missing field",
+			steps:   []templatesv1.PostProcessStep{{DedupeBy: "element.Missing"}},
+			wantErr: `failed to apply postProcess step 0: failed to evaluate expression "element.Missing": no such key: Missing`,
+		},
+		{
+			name: "transform without an expression or template",
+			steps: []templatesv1.PostProcessStep{
+				{Transform: []templatesv1.ElementTransform{{Field: "Name"}}},
+			},
+			wantErr: `failed to apply postProcess step 0: failed to transform field "Name": transform must have an expression or a template`,
+		},
+		{
+			name: "template referring to a missing field",
+			steps: []templatesv1.PostProcessStep{
+				{Transform: []templatesv1.ElementTransform{{Field: "Name", Template: "{{ .Element.Missing }}"}}},
+			},
+			wantErr: `failed to apply postProcess step 0: failed to transform field "Name": failed to render template: .*map has no entry for key "Missing"`,
+		},
+	}
+
+	elements := []map[string]any{
+		{"Branch": "main", "Number": 1.0},
+		{"Branch": "feature/widgets", "Number": "2"},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Elements(tt.steps, elements)
+
+			test.AssertErrorMatch(t, tt.wantErr, err)
+		})
+	}
+}
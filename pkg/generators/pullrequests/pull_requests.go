@@ -2,7 +2,9 @@ package pullrequests
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"regexp"
 	"strconv"
 	"time"
 
@@ -11,11 +13,15 @@ import (
 	"github.com/jenkins-x/go-scm/scm/factory"
 	templatesv1 "github.com/weaveworks/gitopssets-controller/api/v1alpha1"
 	"github.com/weaveworks/gitopssets-controller/pkg/generators"
-	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/types"
+	"github.com/weaveworks/gitopssets-controller/pkg/generators/scmclient"
+	"golang.org/x/exp/slices"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// defaultMaxPullRequests is the maximum number of open pull requests queried
+// when the generator doesn't configure a maximum.
+const defaultMaxPullRequests = 500
+
 type clientFactoryFunc func(driver, serverURL, oauthToken string, opts ...factory.ClientOptionFunc) (*scm.Client, error)
 
 // GeneratorFactory is a function for creating per-reconciliation generators for
@@ -29,6 +35,9 @@ type PullRequestGenerator struct {
 	Client        client.Reader
 	clientFactory clientFactoryFunc
 	logr.Logger
+
+	now   func() time.Time
+	sleep func(context.Context, time.Duration) error
 }
 
 // NewGenerator creates and returns a new pull request generator.
@@ -37,6 +46,8 @@ func NewGenerator(l logr.Logger, c client.Reader) *PullRequestGenerator {
 		Client:        c,
 		Logger:        l,
 		clientFactory: factory.NewClient,
+		now:           time.Now,
+		sleep:         scmclient.SleepWithContext,
 	}
 }
 
@@ -51,26 +62,19 @@ func (g *PullRequestGenerator) Generate(ctx context.Context, sg *templatesv1.Git
 		return nil, nil
 	}
 
-	g.Logger.Info("generating params from PullRequest generator", "repo", sg.PullRequests.Repo)
-	authToken := ""
-	if sg.PullRequests.SecretRef != nil {
-		secretName := types.NamespacedName{
-			Namespace: ks.GetNamespace(),
-			Name:      sg.PullRequests.SecretRef.Name,
-		}
-
-		var secret corev1.Secret
-		if err := g.Client.Get(ctx, secretName, &secret); err != nil {
-			return nil, fmt.Errorf("failed to load repository generator credentials: %w", err)
-		}
-		// See https://github.com/fluxcd/source-controller/blob/main/pkg/git/options.go#L100
-		// for details of the standard flux Git repository secret.
-		data, ok := secret.Data["password"]
-		if !ok {
-			return nil, fmt.Errorf("secret %s does not contain required field 'password'", secretName)
+	var titlePattern *regexp.Regexp
+	if sg.PullRequests.TitlePattern != "" {
+		compiled, err := regexp.Compile(sg.PullRequests.TitlePattern)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse titlePattern %q: %w", sg.PullRequests.TitlePattern, err)
 		}
+		titlePattern = compiled
+	}
 
-		authToken = string(data)
+	g.Logger.Info("generating params from PullRequest generator", "repo", sg.PullRequests.Repo)
+	authToken, err := scmclient.AuthToken(ctx, g.Client, ks, sg.PullRequests.SecretRef)
+	if err != nil {
+		return nil, err
 	}
 
 	g.Logger.Info("querying pull requests", "repo", sg.PullRequests.Repo, "driver", sg.PullRequests.Driver, "serverURL", sg.PullRequests.ServerURL)
@@ -80,9 +84,9 @@ func (g *PullRequestGenerator) Generate(ctx context.Context, sg *templatesv1.Git
 		return nil, fmt.Errorf("failed to create client: %w", err)
 	}
 
-	prs, _, err := scmClient.PullRequests.List(ctx, sg.PullRequests.Repo, listOptionsFromConfig(sg.PullRequests))
+	prs, err := g.listPullRequests(ctx, scmClient, sg.PullRequests)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list pull requests: %w", err)
+		return nil, err
 	}
 
 	g.Logger.Info("queried pull requests", "repo", sg.PullRequests.Repo, "count", len(prs))
@@ -97,19 +101,32 @@ func (g *PullRequestGenerator) Generate(ctx context.Context, sg *templatesv1.Git
 		if !sg.PullRequests.Forks && isFork {
 			continue
 		}
-		// TODO: This should provide additional fields, including the
-		// destination branch ...etc.
-		// It should also sanitise the branches, for example, a branch can
-		// contain a `/` or do we delegate this to the `sanitize` function in
-		// the template rendering?
-		res = append(res, map[string]any{
-			"Number":      strconv.Itoa(pr.Number),
-			"Branch":      pr.Head.Ref,
-			"HeadSHA":     pr.Head.Sha,
-			"CloneURL":    pr.Head.Repo.Clone,
-			"CloneSSHURL": pr.Head.Repo.CloneSSH,
-			"Fork":        isFork,
-		})
+
+		if !prMatchesFilters(pr, sg.PullRequests, titlePattern) {
+			continue
+		}
+
+		element := map[string]any{
+			"Number":       strconv.Itoa(pr.Number),
+			"Branch":       pr.Head.Ref,
+			"HeadSHA":      pr.Head.Sha,
+			"CloneURL":     pr.Head.Repo.Clone,
+			"CloneSSHURL":  pr.Head.Repo.CloneSSH,
+			"Fork":         isFork,
+			"Title":        pr.Title,
+			"Author":       pr.Author.Login,
+			"TargetBranch": pr.Base.Ref,
+			"Labels":       labelNames(pr.Labels),
+			"Draft":        pr.Draft,
+			"CreatedAt":    formatTime(pr.Created),
+			"URL":          pr.Link,
+		}
+
+		if sg.PullRequests.UpdatedTimestamps {
+			element["UpdatedAt"] = formatTime(pr.Updated)
+		}
+
+		res = append(res, element)
 	}
 
 	return res, nil
@@ -120,19 +137,85 @@ func (g *PullRequestGenerator) Interval(sg *templatesv1.GitOpsSetGenerator) time
 	return sg.PullRequests.Interval.Duration
 }
 
+// listPullRequests pages through all the open pull requests in the repository.
+//
+// An error is returned if any page can't be queried, or if there are more
+// open pull requests than the configured maximum, generating from a partial
+// list would remove the resources for pull requests that are still open.
+func (g *PullRequestGenerator) listPullRequests(ctx context.Context, scmClient *scm.Client, c *templatesv1.PullRequestGenerator) ([]*scm.PullRequest, error) {
+	maxPullRequests := c.MaxPullRequests
+	if maxPullRequests == 0 {
+		maxPullRequests = defaultMaxPullRequests
+	}
+
+	pager := &scmclient.Pager{Logger: g.Logger.WithValues("repo", c.Repo), Now: g.now, Sleep: g.sleep}
+	prs, err := scmclient.ListAll(ctx, pager, maxPullRequests, func(ctx context.Context, page, size int) ([]*scm.PullRequest, *scm.Response, error) {
+		opts := listOptionsFromConfig(c)
+		opts.Page, opts.Size = page, size
+
+		return scmClient.PullRequests.List(ctx, c.Repo, opts)
+	})
+	if errors.Is(err, scmclient.ErrTooManyItems) {
+		return nil, fmt.Errorf("repository %s has more than %d open pull requests", c.Repo, maxPullRequests)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pull requests: %w", err)
+	}
+
+	return prs, nil
+}
+
 // label filtering is only supported by GitLab (that I'm aware of)
 // The fetched PRs are filtered on labels across all providers, but providing
 // the labels optimises the load from GitLab.
-//
-// TODO: How should we apply pagination/limiting of fetched PRs?
 func listOptionsFromConfig(c *templatesv1.PullRequestGenerator) *scm.PullRequestListOptions {
 	return &scm.PullRequestListOptions{
-		Size:   20,
+		Page:   1,
+		Size:   scmclient.PageSize,
 		Labels: c.Labels,
 		Open:   true,
 	}
 }
 
+// prMatchesFilters returns true if the pull request matches the target
+// branches, title pattern, authors and draft state in the configuration.
+func prMatchesFilters(pr *scm.PullRequest, c *templatesv1.PullRequestGenerator, titlePattern *regexp.Regexp) bool {
+	if len(c.TargetBranches) > 0 && !slices.Contains(c.TargetBranches, pr.Base.Ref) {
+		return false
+	}
+
+	if titlePattern != nil && !titlePattern.MatchString(pr.Title) {
+		return false
+	}
+
+	if len(c.Authors) > 0 && !slices.Contains(c.Authors, pr.Author.Login) {
+		return false
+	}
+
+	if c.Draft != nil && *c.Draft != pr.Draft {
+		return false
+	}
+
+	return true
+}
+
+func labelNames(labels []*scm.Label) []any {
+	names := []any{}
+	for _, label := range labels {
+		names = append(names, label.Name)
+	}
+
+	return names
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
+
 func prMatchesLabels(pr *scm.PullRequest, labels []string) bool {
 	if len(labels) == 0 {
 		return true
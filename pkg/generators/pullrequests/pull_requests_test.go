@@ -2,7 +2,10 @@ package pullrequests
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"strconv"
 	"testing"
 	"time"
 
@@ -13,6 +16,7 @@ import (
 	"github.com/jenkins-x/go-scm/scm/factory"
 	templatesv1 "github.com/weaveworks/gitopssets-controller/api/v1alpha1"
 	"github.com/weaveworks/gitopssets-controller/pkg/generators"
+	"github.com/weaveworks/gitopssets-controller/pkg/generators/scmclient"
 	"github.com/weaveworks/gitopssets-controller/test"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -81,12 +85,19 @@ func TestGenerate(t *testing.T) {
 			clientFactory: defaultClientFactory,
 			want: []map[string]any{
 				{
-					"Number":      "1",
-					"Branch":      "new-topic",
-					"HeadSHA":     "6dcb09b5b57875f334f61aebed695e2e4193db5e",
-					"CloneSSHURL": "git@github.com:test-org/my-repo.git",
-					"CloneURL":    "https://github.com/test-org/my-repo.git",
-					"Fork":        false,
+					"Number":       "1",
+					"Branch":       "new-topic",
+					"HeadSHA":      "6dcb09b5b57875f334f61aebed695e2e4193db5e",
+					"CloneSSHURL":  "git@github.com:test-org/my-repo.git",
+					"CloneURL":     "https://github.com/test-org/my-repo.git",
+					"Fork":         false,
+					"Title":        "",
+					"Author":       "",
+					"TargetBranch": "main",
+					"Labels":       []any{},
+					"Draft":        false,
+					"CreatedAt":    "",
+					"URL":          "",
 				},
 			},
 		},
@@ -136,12 +147,19 @@ func TestGenerate(t *testing.T) {
 			clientFactory: defaultClientFactory,
 			want: []map[string]any{
 				{
-					"Number":      "2",
-					"Branch":      "new-topic",
-					"HeadSHA":     "6dcb09b5b57875f334f61aebed695e2e4193db5e",
-					"CloneSSHURL": "git@github.com:test-org/my-repo.git",
-					"CloneURL":    "https://github.com/test-org/my-repo.git",
-					"Fork":        false,
+					"Number":       "2",
+					"Branch":       "new-topic",
+					"HeadSHA":      "6dcb09b5b57875f334f61aebed695e2e4193db5e",
+					"CloneSSHURL":  "git@github.com:test-org/my-repo.git",
+					"CloneURL":     "https://github.com/test-org/my-repo.git",
+					"Fork":         false,
+					"Title":        "",
+					"Author":       "",
+					"TargetBranch": "main",
+					"Labels":       []any{"testing"},
+					"Draft":        false,
+					"CreatedAt":    "",
+					"URL":          "",
 				},
 			},
 		},
@@ -188,12 +206,19 @@ func TestGenerate(t *testing.T) {
 			},
 			want: []map[string]any{
 				{
-					"Number":      "1",
-					"Branch":      "new-topic",
-					"HeadSHA":     "6dcb09b5b57875f334f61aebed695e2e4193db5e",
-					"CloneSSHURL": "git@github.com:test-org/my-repo.git",
-					"CloneURL":    "https://github.com/test-org/my-repo.git",
-					"Fork":        false,
+					"Number":       "1",
+					"Branch":       "new-topic",
+					"HeadSHA":      "6dcb09b5b57875f334f61aebed695e2e4193db5e",
+					"CloneSSHURL":  "git@github.com:test-org/my-repo.git",
+					"CloneURL":     "https://github.com/test-org/my-repo.git",
+					"Fork":         false,
+					"Title":        "",
+					"Author":       "",
+					"TargetBranch": "main",
+					"Labels":       []any{},
+					"Draft":        false,
+					"CreatedAt":    "",
+					"URL":          "",
 				},
 			},
 		},
@@ -223,12 +248,19 @@ func TestGenerate(t *testing.T) {
 			clientFactory: defaultClientFactory,
 			want: []map[string]any{
 				{
-					"Number":      "1",
-					"Branch":      "new-topic",
-					"HeadSHA":     "6dcb09b5b57875f334f61aebed695e2e4193db5e",
-					"CloneSSHURL": "git@github.com:test-org/my-repo.git",
-					"CloneURL":    "https://github.com/test-org/my-repo.git",
-					"Fork":        true,
+					"Number":       "1",
+					"Branch":       "new-topic",
+					"HeadSHA":      "6dcb09b5b57875f334f61aebed695e2e4193db5e",
+					"CloneSSHURL":  "git@github.com:test-org/my-repo.git",
+					"CloneURL":     "https://github.com/test-org/my-repo.git",
+					"Fork":         true,
+					"Title":        "",
+					"Author":       "",
+					"TargetBranch": "main",
+					"Labels":       []any{},
+					"Draft":        false,
+					"CreatedAt":    "",
+					"URL":          "",
 				},
 			},
 		},
@@ -277,12 +309,19 @@ func TestGenerate(t *testing.T) {
 			clientFactory: defaultClientFactory,
 			want: []map[string]any{
 				{
-					"Number":      "2",
-					"Branch":      "new-topic",
-					"HeadSHA":     "6dcb09b5b57875f334f61aebed695e2e4193db5e",
-					"CloneSSHURL": "git@github.com:test-org/my-repo.git",
-					"CloneURL":    "https://github.com/test-org/my-repo.git",
-					"Fork":        false,
+					"Number":       "2",
+					"Branch":       "new-topic",
+					"HeadSHA":      "6dcb09b5b57875f334f61aebed695e2e4193db5e",
+					"CloneSSHURL":  "git@github.com:test-org/my-repo.git",
+					"CloneURL":     "https://github.com/test-org/my-repo.git",
+					"Fork":         false,
+					"Title":        "",
+					"Author":       "",
+					"TargetBranch": "main",
+					"Labels":       []any{"testing"},
+					"Draft":        false,
+					"CreatedAt":    "",
+					"URL":          "",
 				},
 			},
 		},
@@ -327,14 +366,255 @@ func TestGenerate(t *testing.T) {
 	}
 }
 
+func TestGenerate_filters(t *testing.T) {
+	createdAt := time.Date(2024, time.March, 12, 10, 30, 0, 0, time.UTC)
+	updatedAt := time.Date(2024, time.March, 14, 16, 45, 0, 0, time.UTC)
+	draft := true
+	notDraft := false
+
+	testCases := []struct {
+		name              string
+		targetBranches    []string
+		titlePattern      string
+		authors           []string
+		draft             *bool
+		updatedTimestamps bool
+		want              []map[string]any
+	}{
+		{
+			name:           "filtering by target branch",
+			targetBranches: []string{"release-1.0", "release-1.1"},
+			want: []map[string]any{
+				{
+					"Number":       "2",
+					"Branch":       "fix-release",
+					"HeadSHA":      "564254f7170844f40a01315fc571ae45fb8665b7",
+					"CloneSSHURL":  "git@github.com:test-org/my-repo.git",
+					"CloneURL":     "https://github.com/test-org/my-repo.git",
+					"Fork":         false,
+					"Title":        "Fix the release",
+					"Author":       "release-manager",
+					"TargetBranch": "release-1.0",
+					"Labels":       []any{"bug", "backport"},
+					"Draft":        false,
+					"CreatedAt":    "2024-03-12T10:30:00Z",
+					"URL":          "https://github.com/test-org/my-repo/pull/2",
+				},
+			},
+		},
+		{
+			name:         "filtering by title",
+			titlePattern: "^\\[preview\\]",
+			want: []map[string]any{
+				{
+					"Number":       "1",
+					"Branch":       "new-topic",
+					"HeadSHA":      "6dcb09b5b57875f334f61aebed695e2e4193db5e",
+					"CloneSSHURL":  "git@github.com:test-org/my-repo.git",
+					"CloneURL":     "https://github.com/test-org/my-repo.git",
+					"Fork":         false,
+					"Title":        "[preview] Add a new topic",
+					"Author":       "developer",
+					"TargetBranch": "main",
+					"Labels":       []any{},
+					"Draft":        true,
+					"CreatedAt":    "2024-03-12T10:30:00Z",
+					"URL":          "https://github.com/test-org/my-repo/pull/1",
+				},
+			},
+		},
+		{
+			name:    "filtering by author",
+			authors: []string{"release-manager"},
+			want: []map[string]any{
+				{
+					"Number":       "2",
+					"Branch":       "fix-release",
+					"HeadSHA":      "564254f7170844f40a01315fc571ae45fb8665b7",
+					"CloneSSHURL":  "git@github.com:test-org/my-repo.git",
+					"CloneURL":     "https://github.com/test-org/my-repo.git",
+					"Fork":         false,
+					"Title":        "Fix the release",
+					"Author":       "release-manager",
+					"TargetBranch": "release-1.0",
+					"Labels":       []any{"bug", "backport"},
+					"Draft":        false,
+					"CreatedAt":    "2024-03-12T10:30:00Z",
+					"URL":          "https://github.com/test-org/my-repo/pull/2",
+				},
+			},
+		},
+		{
+			name:  "only draft pull requests",
+			draft: &draft,
+			want: []map[string]any{
+				{
+					"Number":       "1",
+					"Branch":       "new-topic",
+					"HeadSHA":      "6dcb09b5b57875f334f61aebed695e2e4193db5e",
+					"CloneSSHURL":  "git@github.com:test-org/my-repo.git",
+					"CloneURL":     "https://github.com/test-org/my-repo.git",
+					"Fork":         false,
+					"Title":        "[preview] Add a new topic",
+					"Author":       "developer",
+					"TargetBranch": "main",
+					"Labels":       []any{},
+					"Draft":        true,
+					"CreatedAt":    "2024-03-12T10:30:00Z",
+					"URL":          "https://github.com/test-org/my-repo/pull/1",
+				},
+			},
+		},
+		{
+			name:    "excluding draft pull requests",
+			draft:   &notDraft,
+			authors: []string{"developer", "release-manager"},
+			want: []map[string]any{
+				{
+					"Number":       "2",
+					"Branch":       "fix-release",
+					"HeadSHA":      "564254f7170844f40a01315fc571ae45fb8665b7",
+					"CloneSSHURL":  "git@github.com:test-org/my-repo.git",
+					"CloneURL":     "https://github.com/test-org/my-repo.git",
+					"Fork":         false,
+					"Title":        "Fix the release",
+					"Author":       "release-manager",
+					"TargetBranch": "release-1.0",
+					"Labels":       []any{"bug", "backport"},
+					"Draft":        false,
+					"CreatedAt":    "2024-03-12T10:30:00Z",
+					"URL":          "https://github.com/test-org/my-repo/pull/2",
+				},
+			},
+		},
+		{
+			name:              "with updated timestamps",
+			authors:           []string{"release-manager"},
+			updatedTimestamps: true,
+			want: []map[string]any{
+				{
+					"Number":       "2",
+					"Branch":       "fix-release",
+					"HeadSHA":      "564254f7170844f40a01315fc571ae45fb8665b7",
+					"CloneSSHURL":  "git@github.com:test-org/my-repo.git",
+					"CloneURL":     "https://github.com/test-org/my-repo.git",
+					"Fork":         false,
+					"Title":        "Fix the release",
+					"Author":       "release-manager",
+					"TargetBranch": "release-1.0",
+					"Labels":       []any{"bug", "backport"},
+					"Draft":        false,
+					"CreatedAt":    "2024-03-12T10:30:00Z",
+					"UpdatedAt":    "2024-03-14T16:45:00Z",
+					"URL":          "https://github.com/test-org/my-repo/pull/2",
+				},
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			gen := NewGenerator(logr.Discard(), fake.NewFakeClient())
+			client, data := fakescm.NewDefault()
+			data.PullRequests[1] = &scm.PullRequest{
+				Number: 1,
+				Title:  "[preview] Add a new topic",
+				Draft:  true,
+				Author: scm.User{Login: "developer"},
+				Base: scm.PullRequestBranch{
+					Ref: "main",
+					Repo: scm.Repository{
+						FullName: "test-org/my-repo",
+					},
+				},
+				Head: scm.PullRequestBranch{
+					Ref: "new-topic",
+					Sha: "6dcb09b5b57875f334f61aebed695e2e4193db5e",
+					Repo: scm.Repository{
+						Clone:    "https://github.com/test-org/my-repo.git",
+						CloneSSH: "git@github.com:test-org/my-repo.git",
+					},
+				},
+				Fork:    "test-org/my-repo",
+				Link:    "https://github.com/test-org/my-repo/pull/1",
+				Created: createdAt,
+				Updated: updatedAt,
+			}
+			data.PullRequests[2] = &scm.PullRequest{
+				Number: 2,
+				Title:  "Fix the release",
+				Author: scm.User{Login: "release-manager"},
+				Base: scm.PullRequestBranch{
+					Ref: "release-1.0",
+					Repo: scm.Repository{
+						FullName: "test-org/my-repo",
+					},
+				},
+				Head: scm.PullRequestBranch{
+					Ref: "fix-release",
+					Sha: "564254f7170844f40a01315fc571ae45fb8665b7",
+					Repo: scm.Repository{
+						Clone:    "https://github.com/test-org/my-repo.git",
+						CloneSSH: "git@github.com:test-org/my-repo.git",
+					},
+				},
+				Fork:    "test-org/my-repo",
+				Labels:  []*scm.Label{{Name: "bug"}, {Name: "backport"}},
+				Link:    "https://github.com/test-org/my-repo/pull/2",
+				Created: createdAt,
+				Updated: updatedAt,
+			}
+			gen.clientFactory = defaultClientFactory(client)
+
+			gsg := templatesv1.GitOpsSetGenerator{
+				PullRequests: &templatesv1.PullRequestGenerator{
+					Driver:            "fake",
+					ServerURL:         "https://example.com",
+					Repo:              "test-org/my-repo",
+					TargetBranches:    tt.targetBranches,
+					TitlePattern:      tt.titlePattern,
+					Authors:           tt.authors,
+					Draft:             tt.draft,
+					UpdatedTimestamps: tt.updatedTimestamps,
+				},
+			}
+
+			got, err := gen.Generate(context.TODO(), &gsg,
+				&templatesv1.GitOpsSet{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "demo-set",
+						Namespace: "default",
+					},
+					Spec: templatesv1.GitOpsSetSpec{
+						Generators: []templatesv1.GitOpsSetGenerator{
+							gsg,
+						},
+					},
+				})
+
+			test.AssertNoError(t, err)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("failed to generate pull requests:\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestGenerate_errors(t *testing.T) {
 	testCases := []struct {
 		name          string
 		initObjs      []runtime.Object
 		secretRef     *templatesv1.LocalObjectReference
+		titlePattern  string
 		clientFactory func(*scm.Client) clientFactoryFunc
 		wantErr       string
 	}{
+		{
+			name:          "generator with invalid title pattern",
+			clientFactory: defaultClientFactory,
+			titlePattern:  "[preview",
+			wantErr:       `failed to parse titlePattern "\[preview": error parsing regexp`,
+		},
 		{
 			name:          "generator with missing secret",
 			clientFactory: defaultClientFactory,
@@ -367,10 +647,11 @@ func TestGenerate_errors(t *testing.T) {
 
 			gsg := templatesv1.GitOpsSetGenerator{
 				PullRequests: &templatesv1.PullRequestGenerator{
-					Driver:    "fake",
-					ServerURL: "https://example.com",
-					Repo:      "test-org/my-repo",
-					SecretRef: tt.secretRef,
+					Driver:       "fake",
+					ServerURL:    "https://example.com",
+					Repo:         "test-org/my-repo",
+					SecretRef:    tt.secretRef,
+					TitlePattern: tt.titlePattern,
 				},
 			}
 
@@ -392,6 +673,172 @@ func TestGenerate_errors(t *testing.T) {
 	}
 }
 
+func TestGenerate_pagination(t *testing.T) {
+	testCases := []struct {
+		name            string
+		responses       []fakeListResponse
+		maxPullRequests int
+		want            []string
+		wantSleeps      []time.Duration
+		wantErr         string
+	}{
+		{
+			name: "all the pages are queried",
+			responses: []fakeListResponse{
+				{prs: newPullRequests(1, 2), resp: newListResponse(2)},
+				{prs: newPullRequests(3, 4), resp: newListResponse(3)},
+				{prs: newPullRequests(5), resp: newListResponse(0)},
+			},
+			want: []string{"1", "2", "3", "4", "5"},
+		},
+		{
+			name: "more pull requests than the maximum",
+			responses: []fakeListResponse{
+				{prs: newPullRequests(1, 2), resp: newListResponse(2)},
+				{prs: newPullRequests(3, 4), resp: newListResponse(0)},
+			},
+			maxPullRequests: 3,
+			wantErr:         "repository test-org/my-repo has more than 3 open pull requests",
+		},
+		{
+			name: "full page without the next page",
+			responses: []fakeListResponse{
+				{prs: newPullRequests(pullRequestNumbers(1, scmclient.PageSize)...), resp: newListResponse(0)},
+				{prs: newPullRequests(scmclient.PageSize + 1), resp: newListResponse(0)},
+			},
+			want: pullRequestStrings(1, scmclient.PageSize+1),
+		},
+		{
+			name: "exact multiple of the page size without the next page",
+			responses: []fakeListResponse{
+				{prs: newPullRequests(pullRequestNumbers(1, scmclient.PageSize)...), resp: newListResponse(0)},
+				{prs: newPullRequests(pullRequestNumbers(scmclient.PageSize+1, scmclient.PageSize*2)...), resp: newListResponse(0)},
+				{prs: []*scm.PullRequest{}, resp: newListResponse(0)},
+			},
+			want: pullRequestStrings(1, scmclient.PageSize*2),
+		},
+		{
+			name: "short page without the next page",
+			responses: []fakeListResponse{
+				{prs: newPullRequests(pullRequestNumbers(1, scmclient.PageSize-1)...), resp: newListResponse(0)},
+			},
+			want: pullRequestStrings(1, scmclient.PageSize-1),
+		},
+		{
+			name: "failing to query a page",
+			responses: []fakeListResponse{
+				{prs: newPullRequests(1, 2), resp: newListResponse(2)},
+				{err: errors.New("server error"), resp: &scm.Response{Status: http.StatusInternalServerError}},
+			},
+			wantErr: "failed to list pull requests: server error",
+		},
+		{
+			name: "rate limited request is retried when the limit resets",
+			responses: []fakeListResponse{
+				{err: errors.New("API rate limit exceeded"), resp: newRateLimitedResponse(http.StatusForbidden, testNow.Add(10*time.Second))},
+				{prs: newPullRequests(1, 2), resp: newListResponse(0)},
+			},
+			want:       []string{"1", "2"},
+			wantSleeps: []time.Duration{10 * time.Second},
+		},
+		{
+			name: "rate limited request without a reset time is retried with backoff",
+			responses: []fakeListResponse{
+				{err: errors.New("too many requests"), resp: &scm.Response{Status: http.StatusTooManyRequests}},
+				{err: errors.New("too many requests"), resp: &scm.Response{Status: http.StatusTooManyRequests}},
+				{prs: newPullRequests(1), resp: newListResponse(0)},
+			},
+			want:       []string{"1"},
+			wantSleeps: []time.Duration{time.Second, 2 * time.Second},
+		},
+		{
+			name: "waiting for the rate limit before the next page",
+			responses: []fakeListResponse{
+				{prs: newPullRequests(1, 2), resp: withRate(newListResponse(2), 5000, 0, testNow.Add(30*time.Second))},
+				{prs: newPullRequests(3), resp: newListResponse(0)},
+			},
+			want:       []string{"1", "2", "3"},
+			wantSleeps: []time.Duration{30 * time.Second},
+		},
+		{
+			name: "rate limited until after the maximum wait",
+			responses: []fakeListResponse{
+				{err: errors.New("API rate limit exceeded"), resp: newRateLimitedResponse(http.StatusForbidden, testNow.Add(time.Hour))},
+			},
+			wantErr: "failed to list pull requests: rate limited until 2024-03-12T11:30:00Z",
+		},
+		{
+			name: "rate limited after retrying",
+			responses: []fakeListResponse{
+				{err: errors.New("too many requests"), resp: &scm.Response{Status: http.StatusTooManyRequests}},
+				{err: errors.New("too many requests"), resp: &scm.Response{Status: http.StatusTooManyRequests}},
+				{err: errors.New("too many requests"), resp: &scm.Response{Status: http.StatusTooManyRequests}},
+				{err: errors.New("too many requests"), resp: &scm.Response{Status: http.StatusTooManyRequests}},
+			},
+			wantSleeps: []time.Duration{time.Second, 2 * time.Second, 4 * time.Second},
+			wantErr:    "failed to list pull requests: rate limited after 3 retries: too many requests",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			var sleeps []time.Duration
+			gen := NewGenerator(logr.Discard(), fake.NewFakeClient())
+			gen.now = func() time.Time {
+				return testNow
+			}
+			gen.sleep = func(_ context.Context, d time.Duration) error {
+				sleeps = append(sleeps, d)
+				return nil
+			}
+			prService := &fakePullRequestService{responses: tt.responses}
+			gen.clientFactory = defaultClientFactory(&scm.Client{PullRequests: prService})
+
+			gsg := templatesv1.GitOpsSetGenerator{
+				PullRequests: &templatesv1.PullRequestGenerator{
+					Driver:          "fake",
+					ServerURL:       "https://example.com",
+					Repo:            "test-org/my-repo",
+					MaxPullRequests: tt.maxPullRequests,
+				},
+			}
+
+			got, err := gen.Generate(context.TODO(), &gsg,
+				&templatesv1.GitOpsSet{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "demo-set",
+						Namespace: "default",
+					},
+					Spec: templatesv1.GitOpsSetSpec{
+						Generators: []templatesv1.GitOpsSetGenerator{
+							gsg,
+						},
+					},
+				})
+
+			if tt.wantErr != "" {
+				test.AssertErrorMatch(t, tt.wantErr, err)
+				if got != nil {
+					t.Errorf("got %v, want no elements when generation fails", got)
+				}
+			} else {
+				test.AssertNoError(t, err)
+				var numbers []string
+				for _, element := range got {
+					numbers = append(numbers, element["Number"].(string))
+				}
+				if diff := cmp.Diff(tt.want, numbers); diff != "" {
+					t.Fatalf("failed to generate pull requests:\n%s", diff)
+				}
+			}
+
+			if diff := cmp.Diff(tt.wantSleeps, sleeps); diff != "" {
+				t.Fatalf("failed to wait for rate limits:\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestPullRequestGenerator_GetInterval(t *testing.T) {
 	interval := time.Minute * 10
 	gen := NewGenerator(logr.Discard(), fake.NewFakeClient())
@@ -439,3 +886,88 @@ func defaultClientFactory(c *scm.Client) clientFactoryFunc {
 		return c, nil
 	}
 }
+
+var testNow = time.Date(2024, time.March, 12, 10, 30, 0, 0, time.UTC)
+
+type fakeListResponse struct {
+	prs  []*scm.PullRequest
+	resp *scm.Response
+	err  error
+}
+
+// fakePullRequestService implements the parts of the scm.PullRequestService
+// used by the generator, each request returns the next response.
+type fakePullRequestService struct {
+	scm.PullRequestService
+
+	responses []fakeListResponse
+	requests  int
+}
+
+func (s *fakePullRequestService) List(ctx context.Context, repo string, opts *scm.PullRequestListOptions) ([]*scm.PullRequest, *scm.Response, error) {
+	if s.requests >= len(s.responses) {
+		return nil, nil, fmt.Errorf("unexpected request for page %d", opts.Page)
+	}
+	r := s.responses[s.requests]
+	s.requests++
+
+	return r.prs, r.resp, r.err
+}
+
+func newPullRequests(numbers ...int) []*scm.PullRequest {
+	var prs []*scm.PullRequest
+	for _, n := range numbers {
+		prs = append(prs, &scm.PullRequest{
+			Number: n,
+			Base: scm.PullRequestBranch{
+				Ref: "main",
+			},
+			Head: scm.PullRequestBranch{
+				Ref: fmt.Sprintf("topic-%d", n),
+			},
+			Fork: "test-org/my-repo",
+		})
+	}
+
+	return prs
+}
+
+// pullRequestNumbers returns the numbers from first to last.
+func pullRequestNumbers(first, last int) []int {
+	var numbers []int
+	for n := first; n <= last; n++ {
+		numbers = append(numbers, n)
+	}
+
+	return numbers
+}
+
+func pullRequestStrings(first, last int) []string {
+	var numbers []string
+	for _, n := range pullRequestNumbers(first, last) {
+		numbers = append(numbers, strconv.Itoa(n))
+	}
+
+	return numbers
+}
+
+func newListResponse(next int) *scm.Response {
+	return &scm.Response{
+		Status: http.StatusOK,
+		Page:   scm.Page{Next: next},
+	}
+}
+
+func newRateLimitedResponse(status int, reset time.Time) *scm.Response {
+	return withRate(&scm.Response{Status: status}, 5000, 0, reset)
+}
+
+func withRate(resp *scm.Response, limit, remaining int, reset time.Time) *scm.Response {
+	resp.Rate = scm.Rate{
+		Limit:     limit,
+		Remaining: remaining,
+		Reset:     reset.Unix(),
+	}
+
+	return resp
+}
@@ -0,0 +1,31 @@
+package generators
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ServiceAccountReader is the client.Reader used by the controller, with the
+// client.Reader for the GitOpsSet's service account.
+//
+// Generators that query arbitrary resources use the service account's client,
+// so that they can only read the resources that the service account can read.
+type ServiceAccountReader struct {
+	client.Reader
+
+	ServiceAccount client.Reader
+}
+
+// ReaderFor returns the client.Reader that should be used by the named
+// generator.
+//
+// The KubernetesResources generator gets the service account's client, if
+// there is one, all other generators get the client unchanged, so that
+// generators that nest other generators can pass it on.
+func ReaderFor(name string, c client.Reader) client.Reader {
+	sa, ok := c.(*ServiceAccountReader)
+	if !ok || name != "KubernetesResources" {
+		return c
+	}
+
+	return sa.ServiceAccount
+}
@@ -0,0 +1,48 @@
+package generators
+
+import (
+	"testing"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReaderFor(t *testing.T) {
+	controllerClient := fake.NewClientBuilder().Build()
+	serviceAccountClient := fake.NewClientBuilder().Build()
+	readers := &ServiceAccountReader{Reader: controllerClient, ServiceAccount: serviceAccountClient}
+
+	testCases := []struct {
+		name      string
+		generator string
+		c         client.Reader
+		want      client.Reader
+	}{
+		{
+			name:      "kubernetes resources with a service account",
+			generator: "KubernetesResources",
+			c:         readers,
+			want:      serviceAccountClient,
+		},
+		{
+			name:      "other generators with a service account",
+			generator: "Matrix",
+			c:         readers,
+			want:      readers,
+		},
+		{
+			name:      "kubernetes resources without a service account",
+			generator: "KubernetesResources",
+			c:         controllerClient,
+			want:      controllerClient,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ReaderFor(tt.generator, tt.c); got != tt.want {
+				t.Fatalf("got reader %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
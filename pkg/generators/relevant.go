@@ -26,7 +26,8 @@ func FindRelevantGenerators(setGenerator any, enabledGenerators map[string]Gener
 	for i := 0; i < v.NumField(); i++ {
 		field := v.Field(i)
 		fieldName := v.Type().Field(i).Name
-		if !field.CanInterface() || fieldName == "Name" {
+		// Only the generator fields are pointers.
+		if !field.CanInterface() || field.Kind() != reflect.Pointer {
 			continue
 		}
 
@@ -57,6 +57,16 @@ func TestFindRelevantGenerators(t *testing.T) {
 				&matrix.MatrixGenerator{},
 			},
 		},
+		{
+			name: "generator with a filter",
+			set: templatesv1.GitOpsSetGenerator{
+				List:   &templatesv1.ListGenerator{},
+				Filter: "element.enabled",
+			},
+			want: []generators.Generator{
+				&list.ListGenerator{},
+			},
+		},
 	}
 
 	for _, tt := range tests {
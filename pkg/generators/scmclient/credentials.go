@@ -0,0 +1,40 @@
+package scmclient
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	templatesv1 "github.com/weaveworks/gitopssets-controller/api/v1alpha1"
+)
+
+// AuthToken loads the token for querying the Git provider from the "password"
+// field of the referenced Secret in the same namespace as the GitOpsSet.
+//
+// If no Secret is referenced, the token is empty.
+func AuthToken(ctx context.Context, c client.Reader, gs *templatesv1.GitOpsSet, ref *templatesv1.LocalObjectReference) (string, error) {
+	if ref == nil {
+		return "", nil
+	}
+
+	secretName := types.NamespacedName{
+		Namespace: gs.GetNamespace(),
+		Name:      ref.Name,
+	}
+
+	var secret corev1.Secret
+	if err := c.Get(ctx, secretName, &secret); err != nil {
+		return "", fmt.Errorf("failed to load repository generator credentials: %w", err)
+	}
+	// See https://github.com/fluxcd/source-controller/blob/main/pkg/git/options.go#L100
+	// for details of the standard flux Git repository secret.
+	data, ok := secret.Data["password"]
+	if !ok {
+		return "", fmt.Errorf("secret %s does not contain required field 'password'", secretName)
+	}
+
+	return string(data), nil
+}
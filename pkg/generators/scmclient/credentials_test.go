@@ -0,0 +1,74 @@
+package scmclient
+
+import (
+	"context"
+	"testing"
+
+	templatesv1 "github.com/weaveworks/gitopssets-controller/api/v1alpha1"
+	"github.com/weaveworks/gitopssets-controller/test"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestAuthToken(t *testing.T) {
+	testCases := []struct {
+		name      string
+		initObjs  []runtime.Object
+		secretRef *templatesv1.LocalObjectReference
+		want      string
+		wantErr   string
+	}{
+		{
+			name: "no secret",
+		},
+		{
+			name:      "secret with a password",
+			initObjs:  []runtime.Object{newSecret(map[string][]byte{"password": []byte("top-secret")})},
+			secretRef: &templatesv1.LocalObjectReference{Name: "test-secret"},
+			want:      "top-secret",
+		},
+		{
+			name:      "missing secret",
+			secretRef: &templatesv1.LocalObjectReference{Name: "test-secret"},
+			wantErr:   `failed to load repository generator credentials: secrets "test-secret" not found`,
+		},
+		{
+			name:      "secret without a password",
+			initObjs:  []runtime.Object{newSecret(map[string][]byte{"username": []byte("test")})},
+			secretRef: &templatesv1.LocalObjectReference{Name: "test-secret"},
+			wantErr:   `secret default/test-secret does not contain required field 'password'`,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			gs := &templatesv1.GitOpsSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-generator",
+					Namespace: "default",
+				},
+			}
+			c := fake.NewClientBuilder().WithRuntimeObjects(tt.initObjs...).Build()
+
+			token, err := AuthToken(context.TODO(), c, gs, tt.secretRef)
+
+			test.AssertErrorMatch(t, tt.wantErr, err)
+			if token != tt.want {
+				t.Fatalf("got token %q, want %q", token, tt.want)
+			}
+		})
+	}
+}
+
+func newSecret(data map[string][]byte) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-secret",
+			Namespace: "default",
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: data,
+	}
+}
@@ -0,0 +1,156 @@
+// Package scmclient provides the parts that are common to the generators that
+// query Git hosting providers with go-scm.
+package scmclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/jenkins-x/go-scm/scm"
+)
+
+const (
+	// PageSize is the number of items requested per page, this is the maximum
+	// supported by GitHub and GitLab.
+	PageSize = 100
+
+	// maxRateLimitRetries is the number of times a rate limited request is
+	// retried before giving up.
+	maxRateLimitRetries = 3
+
+	// maxRateLimitWait is the longest time to wait for a rate limit to reset,
+	// beyond this the generation fails and will be retried by the controller.
+	maxRateLimitWait = time.Minute
+
+	// rateLimitBackoff is the initial time to wait when a request is rate
+	// limited and the provider doesn't indicate when the limit resets.
+	rateLimitBackoff = time.Second
+)
+
+// ErrTooManyItems is returned when listing more than the maximum number of
+// items.
+var ErrTooManyItems = errors.New("too many items")
+
+// Pager makes requests to a Git hosting provider, waiting for the rate limits
+// of the provider.
+type Pager struct {
+	logr.Logger
+
+	Now   func() time.Time
+	Sleep func(context.Context, time.Duration) error
+}
+
+// NewPager creates and returns a new Pager.
+func NewPager(l logr.Logger) *Pager {
+	return &Pager{
+		Logger: l,
+		Now:    time.Now,
+		Sleep:  SleepWithContext,
+	}
+}
+
+// ListFunc queries a page of items.
+type ListFunc[T any] func(ctx context.Context, page, size int) ([]T, *scm.Response, error)
+
+// ListAll pages through all the items, if maxItems is greater than zero, and
+// more items are listed, ErrTooManyItems is returned.
+//
+// Some drivers don't report the next page, so if a full page is returned
+// without the next page, the following page is requested until a page that
+// isn't full is returned, generating from a partial list would remove the
+// resources for the items that weren't listed.
+func ListAll[T any](ctx context.Context, p *Pager, maxItems int, list ListFunc[T]) ([]T, error) {
+	items := []T{}
+	for pageNumber := 1; ; {
+		page, resp, err := Do(ctx, p, func(ctx context.Context) ([]T, *scm.Response, error) {
+			return list(ctx, pageNumber, PageSize)
+		})
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page...)
+
+		if maxItems > 0 && len(items) > maxItems {
+			return nil, ErrTooManyItems
+		}
+
+		switch {
+		case resp != nil && resp.Page.Next > pageNumber:
+			pageNumber = resp.Page.Next
+		case len(page) >= PageSize:
+			pageNumber++
+		default:
+			return items, nil
+		}
+
+		// Wait before requesting the next page if the rate limit has been
+		// used up.
+		if resp != nil && resp.Rate.Limit > 0 && resp.Rate.Remaining == 0 {
+			if err := p.waitForRateLimit(ctx, resp, 0); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// Do makes a request, retrying the request if it's rate limited.
+func Do[T any](ctx context.Context, p *Pager, request func(context.Context) (T, *scm.Response, error)) (T, *scm.Response, error) {
+	for attempt := 0; ; attempt++ {
+		result, resp, err := request(ctx)
+		if err == nil || !isRateLimited(resp) {
+			return result, resp, err
+		}
+
+		var empty T
+		if attempt >= maxRateLimitRetries {
+			return empty, nil, fmt.Errorf("rate limited after %d retries: %w", attempt, err)
+		}
+
+		if err := p.waitForRateLimit(ctx, resp, attempt); err != nil {
+			return empty, nil, err
+		}
+	}
+}
+
+// waitForRateLimit waits until the rate limit resets, or with an exponential
+// backoff if the provider doesn't report when the limit resets.
+func (p *Pager) waitForRateLimit(ctx context.Context, resp *scm.Response, attempt int) error {
+	wait := rateLimitBackoff << attempt
+	if resp.Rate.Reset > 0 {
+		reset := time.Unix(resp.Rate.Reset, 0)
+		wait = max(reset.Sub(p.Now()), rateLimitBackoff)
+		if wait > maxRateLimitWait {
+			return fmt.Errorf("rate limited until %s", reset.UTC().Format(time.RFC3339))
+		}
+	}
+
+	p.Logger.Info("rate limited by the provider", "wait", wait)
+
+	return p.Sleep(ctx, wait)
+}
+
+func isRateLimited(resp *scm.Response) bool {
+	if resp == nil {
+		return false
+	}
+
+	return resp.Status == http.StatusTooManyRequests ||
+		(resp.Status == http.StatusForbidden && resp.Rate.Limit > 0 && resp.Rate.Remaining == 0)
+}
+
+// SleepWithContext waits for the duration, or until the context is done.
+func SleepWithContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
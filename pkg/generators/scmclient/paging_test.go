@@ -0,0 +1,263 @@
+package scmclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/google/go-cmp/cmp"
+	"github.com/jenkins-x/go-scm/scm"
+
+	"github.com/weaveworks/gitopssets-controller/test"
+)
+
+var testNow = time.Date(2024, time.March, 12, 10, 30, 0, 0, time.UTC)
+
+func TestListAll(t *testing.T) {
+	testCases := []struct {
+		name       string
+		pages      []fakePage
+		maxItems   int
+		want       []int
+		wantPages  []int
+		wantSleeps []time.Duration
+		wantErr    string
+	}{
+		{
+			name: "all the pages are listed",
+			pages: []fakePage{
+				{items: []int{1, 2}, resp: newResponse(2)},
+				{items: []int{3}, resp: newResponse(0)},
+			},
+			want:      []int{1, 2, 3},
+			wantPages: []int{1, 2},
+		},
+		{
+			name: "full page without a next page",
+			pages: []fakePage{
+				{items: makeItems(PageSize), resp: newResponse(0)},
+				{items: []int{}, resp: newResponse(0)},
+			},
+			want:      makeItems(PageSize),
+			wantPages: []int{1, 2},
+		},
+		{
+			name: "full page without a response",
+			pages: []fakePage{
+				{items: makeItems(PageSize)},
+				{items: []int{PageSize + 1}},
+			},
+			want:      makeItems(PageSize + 1),
+			wantPages: []int{1, 2},
+		},
+		{
+			name: "exact multiple of the page size without a next page",
+			pages: []fakePage{
+				{items: makeItems(PageSize), resp: newResponse(0)},
+				{items: makeItems(PageSize * 2)[PageSize:], resp: newResponse(0)},
+				{items: []int{}, resp: newResponse(0)},
+			},
+			want:      makeItems(PageSize * 2),
+			wantPages: []int{1, 2, 3},
+		},
+		{
+			name: "more items than the maximum without a next page",
+			pages: []fakePage{
+				{items: makeItems(PageSize), resp: newResponse(0)},
+				{items: makeItems(PageSize * 2)[PageSize:], resp: newResponse(0)},
+			},
+			maxItems: PageSize + 1,
+			wantErr:  "too many items",
+		},
+		{
+			name: "more items than the maximum",
+			pages: []fakePage{
+				{items: []int{1, 2}, resp: newResponse(2)},
+				{items: []int{3, 4}, resp: newResponse(0)},
+			},
+			maxItems: 3,
+			wantErr:  "too many items",
+		},
+		{
+			name: "waiting for the rate limit before the next page",
+			pages: []fakePage{
+				{items: []int{1, 2}, resp: withRate(newResponse(2), 5000, 0, testNow.Add(30*time.Second))},
+				{items: []int{3}, resp: newResponse(0)},
+			},
+			want:       []int{1, 2, 3},
+			wantPages:  []int{1, 2},
+			wantSleeps: []time.Duration{30 * time.Second},
+		},
+		{
+			name: "rate limited request is retried",
+			pages: []fakePage{
+				{err: errors.New("too many requests"), resp: &scm.Response{Status: http.StatusTooManyRequests}},
+				{items: []int{1}, resp: newResponse(0)},
+			},
+			want:       []int{1},
+			wantPages:  []int{1, 1},
+			wantSleeps: []time.Duration{time.Second},
+		},
+		{
+			name: "failing to list a page",
+			pages: []fakePage{
+				{items: []int{1, 2}, resp: newResponse(2)},
+				{err: errors.New("server error"), resp: &scm.Response{Status: http.StatusInternalServerError}},
+			},
+			wantErr: "server error",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			pager, sleeps := newTestPager()
+			lister := &fakeLister{pages: tt.pages}
+
+			got, err := ListAll(context.TODO(), pager, tt.maxItems, lister.list)
+
+			if tt.wantErr != "" {
+				test.AssertErrorMatch(t, tt.wantErr, err)
+				return
+			}
+			test.AssertNoError(t, err)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("failed to list items:\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantPages, lister.requestedPages); diff != "" {
+				t.Fatalf("failed to request pages:\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantSleeps, *sleeps); diff != "" {
+				t.Fatalf("failed to wait for rate limits:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDo(t *testing.T) {
+	testCases := []struct {
+		name       string
+		responses  []fakePage
+		wantSleeps []time.Duration
+		wantErr    string
+	}{
+		{
+			name: "request that isn't rate limited",
+			responses: []fakePage{
+				{resp: newResponse(0)},
+			},
+		},
+		{
+			name: "rate limited request is retried when the limit resets",
+			responses: []fakePage{
+				{err: errors.New("API rate limit exceeded"), resp: withRate(&scm.Response{Status: http.StatusForbidden}, 5000, 0, testNow.Add(10*time.Second))},
+				{resp: newResponse(0)},
+			},
+			wantSleeps: []time.Duration{10 * time.Second},
+		},
+		{
+			name: "rate limited until after the maximum wait",
+			responses: []fakePage{
+				{err: errors.New("API rate limit exceeded"), resp: withRate(&scm.Response{Status: http.StatusForbidden}, 5000, 0, testNow.Add(time.Hour))},
+			},
+			wantErr: "rate limited until 2024-03-12T11:30:00Z",
+		},
+		{
+			name: "rate limited after retrying",
+			responses: []fakePage{
+				{err: errors.New("too many requests"), resp: &scm.Response{Status: http.StatusTooManyRequests}},
+				{err: errors.New("too many requests"), resp: &scm.Response{Status: http.StatusTooManyRequests}},
+				{err: errors.New("too many requests"), resp: &scm.Response{Status: http.StatusTooManyRequests}},
+				{err: errors.New("too many requests"), resp: &scm.Response{Status: http.StatusTooManyRequests}},
+			},
+			wantSleeps: []time.Duration{time.Second, 2 * time.Second, 4 * time.Second},
+			wantErr:    "rate limited after 3 retries: too many requests",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			pager, sleeps := newTestPager()
+			lister := &fakeLister{pages: tt.responses}
+
+			_, _, err := Do(context.TODO(), pager, func(ctx context.Context) ([]int, *scm.Response, error) {
+				return lister.list(ctx, 1, PageSize)
+			})
+
+			test.AssertErrorMatch(t, tt.wantErr, err)
+			if diff := cmp.Diff(tt.wantSleeps, *sleeps); diff != "" {
+				t.Fatalf("failed to wait for rate limits:\n%s", diff)
+			}
+		})
+	}
+}
+
+func newTestPager() (*Pager, *[]time.Duration) {
+	var sleeps []time.Duration
+	pager := &Pager{
+		Logger: logr.Discard(),
+		Now: func() time.Time {
+			return testNow
+		},
+		Sleep: func(_ context.Context, d time.Duration) error {
+			sleeps = append(sleeps, d)
+			return nil
+		},
+	}
+
+	return pager, &sleeps
+}
+
+type fakePage struct {
+	items []int
+	resp  *scm.Response
+	err   error
+}
+
+// fakeLister returns the next page for each request, and records the pages
+// that are requested.
+type fakeLister struct {
+	pages          []fakePage
+	requests       int
+	requestedPages []int
+}
+
+func (l *fakeLister) list(ctx context.Context, page, size int) ([]int, *scm.Response, error) {
+	if l.requests >= len(l.pages) {
+		return nil, nil, fmt.Errorf("unexpected request for page %d", page)
+	}
+	p := l.pages[l.requests]
+	l.requests++
+	l.requestedPages = append(l.requestedPages, page)
+
+	return p.items, p.resp, p.err
+}
+
+func makeItems(n int) []int {
+	items := make([]int, n)
+	for i := range items {
+		items[i] = i + 1
+	}
+
+	return items
+}
+
+func newResponse(next int) *scm.Response {
+	return &scm.Response{
+		Status: http.StatusOK,
+		Page:   scm.Page{Next: next},
+	}
+}
+
+func withRate(resp *scm.Response, limit, remaining int, reset time.Time) *scm.Response {
+	resp.Rate = scm.Rate{
+		Limit:     limit,
+		Remaining: remaining,
+		Reset:     reset.Unix(),
+	}
+
+	return resp
+}
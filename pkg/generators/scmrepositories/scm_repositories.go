@@ -0,0 +1,183 @@
+package scmrepositories
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/jenkins-x/go-scm/scm/factory"
+	templatesv1 "github.com/weaveworks/gitopssets-controller/api/v1alpha1"
+	"github.com/weaveworks/gitopssets-controller/pkg/generators"
+	"github.com/weaveworks/gitopssets-controller/pkg/generators/scmclient"
+	"golang.org/x/exp/slices"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	archivedInclude = "include"
+	archivedOnly    = "only"
+)
+
+type clientFactoryFunc func(driver, serverURL, oauthToken string, opts ...factory.ClientOptionFunc) (*scm.Client, error)
+
+// GeneratorFactory is a function for creating per-reconciliation generators for
+// the SCMRepositoriesGenerator.
+func GeneratorFactory(l logr.Logger, c client.Reader) generators.Generator {
+	return NewGenerator(l, c)
+}
+
+// SCMRepositoriesGenerator generates from the repositories in an organization.
+type SCMRepositoriesGenerator struct {
+	Client        client.Reader
+	clientFactory clientFactoryFunc
+	logr.Logger
+
+	now   func() time.Time
+	sleep func(context.Context, time.Duration) error
+}
+
+// NewGenerator creates and returns a new SCM repositories generator.
+func NewGenerator(l logr.Logger, c client.Reader) *SCMRepositoriesGenerator {
+	return &SCMRepositoriesGenerator{
+		Client:        c,
+		Logger:        l,
+		clientFactory: factory.NewClient,
+		now:           time.Now,
+		sleep:         scmclient.SleepWithContext,
+	}
+}
+
+// Generate is an implementation of the Generator interface.
+//
+// Each repository in the organization that matches the filters is returned as
+// a generated element.
+func (g *SCMRepositoriesGenerator) Generate(ctx context.Context, sg *templatesv1.GitOpsSetGenerator, ks *templatesv1.GitOpsSet) ([]map[string]any, error) {
+	if sg == nil {
+		g.Logger.Info("no generator provided")
+		return nil, generators.ErrEmptyGitOpsSet
+	}
+
+	if sg.SCMRepositories == nil {
+		g.Logger.Info("scm repositories configuration is nil")
+		return nil, nil
+	}
+
+	var pattern *regexp.Regexp
+	if sg.SCMRepositories.Pattern != "" {
+		compiled, err := regexp.Compile(sg.SCMRepositories.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse pattern %q: %w", sg.SCMRepositories.Pattern, err)
+		}
+		pattern = compiled
+	}
+
+	g.Logger.Info("generating params from SCMRepositories generator", "organization", sg.SCMRepositories.Organization)
+	authToken, err := scmclient.AuthToken(ctx, g.Client, ks, sg.SCMRepositories.SecretRef)
+	if err != nil {
+		return nil, err
+	}
+
+	g.Logger.Info("querying repositories", "organization", sg.SCMRepositories.Organization, "driver", sg.SCMRepositories.Driver, "serverURL", sg.SCMRepositories.ServerURL)
+
+	scmClient, err := g.clientFactory(sg.SCMRepositories.Driver, sg.SCMRepositories.ServerURL, authToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+
+	pager := &scmclient.Pager{Logger: g.Logger.WithValues("organization", sg.SCMRepositories.Organization), Now: g.now, Sleep: g.sleep}
+	repos, err := listRepositories(ctx, pager, scmClient, sg.SCMRepositories.Organization)
+	if err != nil {
+		return nil, err
+	}
+
+	g.Logger.Info("queried repositories", "organization", sg.SCMRepositories.Organization, "count", len(repos))
+	res := []map[string]any{}
+	for _, repo := range repos {
+		if !archivedMatches(repo, sg.SCMRepositories.Archived) {
+			continue
+		}
+
+		if pattern != nil && !pattern.MatchString(repo.Name) {
+			continue
+		}
+
+		if !repoMatchesTopics(repo, sg.SCMRepositories.Topics) {
+			continue
+		}
+
+		res = append(res, map[string]any{
+			"Name":          repo.Name,
+			"FullName":      repo.FullName,
+			"CloneURL":      repo.Clone,
+			"CloneSSHURL":   repo.CloneSSH,
+			"DefaultBranch": repo.Branch,
+			"Visibility":    visibility(repo),
+			"Archived":      repo.Archived,
+		})
+	}
+
+	return res, nil
+}
+
+// Interval is an implementation of the Generator interface.
+func (g *SCMRepositoriesGenerator) Interval(sg *templatesv1.GitOpsSetGenerator) time.Duration {
+	return sg.SCMRepositories.Interval.Duration
+}
+
+// listRepositories pages through all the repositories in the organization.
+func listRepositories(ctx context.Context, pager *scmclient.Pager, scmClient *scm.Client, org string) ([]*scm.Repository, error) {
+	repos, err := scmclient.ListAll(ctx, pager, 0, func(ctx context.Context, page, size int) ([]*scm.Repository, *scm.Response, error) {
+		return scmClient.Repositories.ListOrganisation(ctx, org, &scm.ListOptions{Page: page, Size: size})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repositories: %w", err)
+	}
+
+	return repos, nil
+}
+
+// archivedMatches reports whether a repository is included by the archived
+// filter, archived repositories are excluded if the filter isn't set.
+func archivedMatches(repo *scm.Repository, archived string) bool {
+	switch archived {
+	case archivedInclude:
+		return true
+	case archivedOnly:
+		return repo.Archived
+	}
+
+	return !repo.Archived
+}
+
+func repoMatchesTopics(repo *scm.Repository, topics []string) bool {
+	for _, v := range topics {
+		if !slices.Contains(repo.Topics, v) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// visibility returns the visibility reported by the driver, e.g. GitLab's
+// `internal`, falling back to whether or not the repository is private for
+// drivers that don't report it.
+func visibility(repo *scm.Repository) string {
+	switch repo.Visibility {
+	case scm.VisibilityPublic:
+		return "public"
+	case scm.VisibilityInternal:
+		return "internal"
+	case scm.VisibilityPrivate:
+		return "private"
+	}
+
+	if repo.Private {
+		return "private"
+	}
+
+	return "public"
+}
@@ -0,0 +1,365 @@
+package scmrepositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/google/go-cmp/cmp"
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/jenkins-x/go-scm/scm/factory"
+	templatesv1 "github.com/weaveworks/gitopssets-controller/api/v1alpha1"
+	"github.com/weaveworks/gitopssets-controller/pkg/generators"
+	"github.com/weaveworks/gitopssets-controller/test"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ generators.Generator = (*SCMRepositoriesGenerator)(nil)
+
+func TestGenerate_with_no_generator(t *testing.T) {
+	gen := GeneratorFactory(logr.Discard(), nil)
+	_, err := gen.Generate(context.TODO(), nil, nil)
+
+	if err != generators.ErrEmptyGitOpsSet {
+		t.Errorf("got error %v", err)
+	}
+}
+
+func TestGenerate_with_no_config(t *testing.T) {
+	gen := GeneratorFactory(logr.Discard(), nil)
+	got, err := gen.Generate(context.TODO(), &templatesv1.GitOpsSetGenerator{}, nil)
+
+	if err != nil {
+		t.Errorf("got an error with no scm repositories: %s", err)
+	}
+	if got != nil {
+		t.Errorf("got %v, want %v with no SCMRepositories generator", got, nil)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	testCases := []struct {
+		name      string
+		initObjs  []runtime.Object
+		secretRef *templatesv1.LocalObjectReference
+		topics    []string
+		pattern   string
+		archived  string
+		want      []map[string]any
+	}{
+		{
+			name: "unfiltered repositories exclude archived repositories",
+			want: []map[string]any{
+				newElement("service-billing", "main", "private"),
+				newElement("service-payments", "main", "public"),
+				newElement("docs", "master", "public"),
+				newElement("handbook", "main", "internal"),
+			},
+		},
+		{
+			name:     "excluding archived repositories",
+			archived: "exclude",
+			want: []map[string]any{
+				newElement("service-billing", "main", "private"),
+				newElement("service-payments", "main", "public"),
+				newElement("docs", "master", "public"),
+				newElement("handbook", "main", "internal"),
+			},
+		},
+		{
+			name:     "including archived repositories",
+			archived: "include",
+			want: []map[string]any{
+				newElement("service-billing", "main", "private"),
+				newElement("service-payments", "main", "public"),
+				newElement("docs", "master", "public"),
+				newElement("handbook", "main", "internal"),
+				withArchived(newElement("service-legacy", "master", "private")),
+			},
+		},
+		{
+			name:     "only archived repositories",
+			archived: "only",
+			want: []map[string]any{
+				withArchived(newElement("service-legacy", "master", "private")),
+			},
+		},
+		{
+			name:    "filtering by pattern",
+			pattern: "^service-",
+			want: []map[string]any{
+				newElement("service-billing", "main", "private"),
+				newElement("service-payments", "main", "public"),
+			},
+		},
+		{
+			name:   "filtering by topics",
+			topics: []string{"service", "payments"},
+			want: []map[string]any{
+				newElement("service-payments", "main", "public"),
+			},
+		},
+		{
+			name: "authenticated with a secret",
+			initObjs: []runtime.Object{newSecret(types.NamespacedName{
+				Name:      "test-secret",
+				Namespace: "default",
+			})},
+			secretRef: &templatesv1.LocalObjectReference{
+				Name: "test-secret",
+			},
+			pattern: "^docs$",
+			want: []map[string]any{
+				newElement("docs", "master", "public"),
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			gen := NewGenerator(logr.Discard(), fake.NewFakeClient(tt.initObjs...))
+			gen.clientFactory = defaultClientFactory(newFakeSCMClient())
+
+			gsg := templatesv1.GitOpsSetGenerator{
+				SCMRepositories: &templatesv1.SCMRepositoriesGenerator{
+					Driver:       "fake",
+					ServerURL:    "https://example.com",
+					Organization: "test-org",
+					SecretRef:    tt.secretRef,
+					Topics:       tt.topics,
+					Pattern:      tt.pattern,
+					Archived:     tt.archived,
+				},
+			}
+
+			got, err := gen.Generate(context.TODO(), &gsg, newGitOpsSet(gsg))
+
+			test.AssertNoError(t, err)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("failed to generate repositories:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGenerate_errors(t *testing.T) {
+	testCases := []struct {
+		name         string
+		initObjs     []runtime.Object
+		secretRef    *templatesv1.LocalObjectReference
+		organization string
+		pattern      string
+		wantErr      string
+	}{
+		{
+			name:         "generator with missing secret",
+			organization: "test-org",
+			secretRef: &templatesv1.LocalObjectReference{
+				Name: "test-secret",
+			},
+			wantErr: `failed to load repository generator credentials: secrets "test-secret" not found`,
+		},
+		{
+			name:         "generator with missing key in secret",
+			organization: "test-org",
+			initObjs: []runtime.Object{newSecret(types.NamespacedName{
+				Name:      "test-secret",
+				Namespace: "default",
+			}, func(c *corev1.Secret) {
+				c.Data = map[string][]byte{}
+			})},
+			secretRef: &templatesv1.LocalObjectReference{
+				Name: "test-secret",
+			},
+			wantErr: `secret default/test-secret does not contain required field 'password'`,
+		},
+		{
+			name:         "invalid pattern",
+			organization: "test-org",
+			pattern:      "service-[",
+			wantErr:      `failed to parse pattern "service-\[": error parsing regexp`,
+		},
+		{
+			name:         "failing to list repositories",
+			organization: "missing-org",
+			wantErr:      "failed to list repositories: organization not found",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			gen := NewGenerator(logr.Discard(), fake.NewFakeClient(tt.initObjs...))
+			gen.clientFactory = defaultClientFactory(newFakeSCMClient())
+
+			gsg := templatesv1.GitOpsSetGenerator{
+				SCMRepositories: &templatesv1.SCMRepositoriesGenerator{
+					Driver:       "fake",
+					ServerURL:    "https://example.com",
+					Organization: tt.organization,
+					SecretRef:    tt.secretRef,
+					Pattern:      tt.pattern,
+				},
+			}
+
+			_, err := gen.Generate(context.TODO(), &gsg, newGitOpsSet(gsg))
+
+			test.AssertErrorMatch(t, tt.wantErr, err)
+		})
+	}
+}
+
+func TestSCMRepositoriesGenerator_GetInterval(t *testing.T) {
+	interval := time.Minute * 10
+	gen := NewGenerator(logr.Discard(), fake.NewFakeClient())
+	sg := &templatesv1.GitOpsSetGenerator{
+		SCMRepositories: &templatesv1.SCMRepositoriesGenerator{
+			Driver:       "fake",
+			ServerURL:    "https://example.com",
+			Organization: "test-org",
+			Interval:     metav1.Duration{Duration: interval},
+		},
+	}
+
+	d := gen.Interval(sg)
+
+	if d != interval {
+		t.Fatalf("got %#v want %#v", d, interval)
+	}
+}
+
+// fakeRepositoryService implements the parts of the scm.RepositoryService used
+// by the generator, the repositories are returned a page at a time.
+type fakeRepositoryService struct {
+	scm.RepositoryService
+
+	org      string
+	repos    []*scm.Repository
+	pageSize int
+}
+
+func (s *fakeRepositoryService) ListOrganisation(ctx context.Context, org string, opts *scm.ListOptions) ([]*scm.Repository, *scm.Response, error) {
+	if org != s.org {
+		return nil, nil, errors.New("organization not found")
+	}
+
+	start := (opts.Page - 1) * s.pageSize
+	end := min(start+s.pageSize, len(s.repos))
+	res := &scm.Response{}
+	if end < len(s.repos) {
+		res.Page.Next = opts.Page + 1
+	}
+
+	return s.repos[start:end], res, nil
+}
+
+func newFakeSCMClient() *scm.Client {
+	return &scm.Client{
+		Repositories: &fakeRepositoryService{
+			org:      "test-org",
+			pageSize: 2,
+			repos: []*scm.Repository{
+				newRepository("service-billing", "main", func(r *scm.Repository) {
+					r.Private = true
+					r.Topics = []string{"service", "billing"}
+				}),
+				newRepository("service-payments", "main", func(r *scm.Repository) {
+					r.Topics = []string{"service", "payments"}
+				}),
+				newRepository("docs", "master"),
+				newRepository("handbook", "main", func(r *scm.Repository) {
+					r.Private = true
+					r.Visibility = scm.VisibilityInternal
+				}),
+				newRepository("service-legacy", "master", func(r *scm.Repository) {
+					r.Private = true
+					r.Archived = true
+					r.Topics = []string{"service", "payments"}
+				}),
+			},
+		},
+	}
+}
+
+func newRepository(name, branch string, opts ...func(*scm.Repository)) *scm.Repository {
+	r := &scm.Repository{
+		Namespace: "test-org",
+		Name:      name,
+		FullName:  "test-org/" + name,
+		Branch:    branch,
+		Clone:     "https://github.com/test-org/" + name + ".git",
+		CloneSSH:  "git@github.com:test-org/" + name + ".git",
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+func newElement(name, branch, visibility string) map[string]any {
+	return map[string]any{
+		"Name":          name,
+		"FullName":      "test-org/" + name,
+		"CloneURL":      "https://github.com/test-org/" + name + ".git",
+		"CloneSSHURL":   "git@github.com:test-org/" + name + ".git",
+		"DefaultBranch": branch,
+		"Visibility":    visibility,
+		"Archived":      false,
+	}
+}
+
+func withArchived(element map[string]any) map[string]any {
+	element["Archived"] = true
+
+	return element
+}
+
+func newGitOpsSet(gsg templatesv1.GitOpsSetGenerator) *templatesv1.GitOpsSet {
+	return &templatesv1.GitOpsSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "demo-set",
+			Namespace: "default",
+		},
+		Spec: templatesv1.GitOpsSetSpec{
+			Generators: []templatesv1.GitOpsSetGenerator{
+				gsg,
+			},
+		},
+	}
+}
+
+func newSecret(name types.NamespacedName, opts ...func(*corev1.Secret)) *corev1.Secret {
+	s := &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Secret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name.Name,
+			Namespace: name.Namespace,
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			"password": []byte("top-secret"),
+		},
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+func defaultClientFactory(c *scm.Client) clientFactoryFunc {
+	return func(_, _, _ string, opts ...factory.ClientOptionFunc) (*scm.Client, error) {
+		return c, nil
+	}
+}
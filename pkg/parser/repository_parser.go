@@ -70,6 +70,34 @@ func (p *RepositoryParser) GenerateFromFiles(ctx context.Context, archiveURL, ch
 	return result, nil
 }
 
+// ReadFile extracts the archive and returns the contents of a file.
+func (p *RepositoryParser) ReadFile(ctx context.Context, archiveURL, checksum, path string) ([]byte, error) {
+	tempDir, err := os.MkdirTemp("", "parsing")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory when parsing artifacts: %w", err)
+	}
+	defer func() {
+		if err := os.RemoveAll(tempDir); err != nil {
+			p.Logger.Error(err, "failed to remove temporary archive directory")
+		}
+	}()
+
+	if err := p.fetcher.Fetch(archiveURL, checksum, tempDir); err != nil {
+		return nil, fmt.Errorf("failed to get archive URL %s: %w", archiveURL, err)
+	}
+
+	fullPath, err := securejoin.SecureJoin(tempDir, path)
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(fullPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from archive file %q: %w", path, err)
+	}
+
+	return b, nil
+}
+
 // GenerateFromDirectories extracts the archive and processes the directories.
 func (p *RepositoryParser) GenerateFromDirectories(ctx context.Context, archiveURL, checksum string, dirs []templatesv1.RepositoryGeneratorDirectoryItem) ([]map[string]any, error) {
 	tempDir, err := os.MkdirTemp("", "parsing")
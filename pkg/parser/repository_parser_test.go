@@ -93,6 +93,28 @@ func TestGenerateFromFiles_missing_url(t *testing.T) {
 	}
 }
 
+func TestReadFile(t *testing.T) {
+	parser := NewRepositoryParser(logr.Discard(), fetch.NewArchiveFetcher(2, tar.UnlimitedUntarSize, tar.UnlimitedUntarSize, ""))
+	srv := test.StartFakeArchiveServer(t, "testdata")
+
+	b, err := parser.ReadFile(context.TODO(), srv.URL+"/files.tar.gz", strings.TrimSpace(mustReadFile(t, "testdata/files.tar.gz.sum")), "files/dev.yaml")
+	test.AssertNoError(t, err)
+
+	if diff := cmp.Diff("environment: dev\ninstances: 2\n", string(b)); diff != "" {
+		t.Fatalf("failed to read file:\n%s", diff)
+	}
+}
+
+func TestReadFile_missing_file(t *testing.T) {
+	parser := NewRepositoryParser(logr.Discard(), fetch.NewArchiveFetcher(2, tar.UnlimitedUntarSize, tar.UnlimitedUntarSize, ""))
+	srv := test.StartFakeArchiveServer(t, "testdata")
+
+	_, err := parser.ReadFile(context.TODO(), srv.URL+"/files.tar.gz", strings.TrimSpace(mustReadFile(t, "testdata/files.tar.gz.sum")), "files/test.yaml")
+	if !strings.Contains(err.Error(), "failed to read from archive file \"files/test.yaml\"") {
+		t.Fatalf("got error %v", err)
+	}
+}
+
 func TestGenerateFromDirectories(t *testing.T) {
 	fetchTests := []struct {
 		description string
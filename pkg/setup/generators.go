@@ -18,24 +18,31 @@ import (
 	templatesv1 "github.com/weaveworks/gitopssets-controller/api/v1alpha1"
 	"github.com/weaveworks/gitopssets-controller/pkg/generators"
 	"github.com/weaveworks/gitopssets-controller/pkg/generators/apiclient"
+	"github.com/weaveworks/gitopssets-controller/pkg/generators/bucket"
 	"github.com/weaveworks/gitopssets-controller/pkg/generators/cluster"
 	"github.com/weaveworks/gitopssets-controller/pkg/generators/config"
+	"github.com/weaveworks/gitopssets-controller/pkg/generators/gitopsset"
+	"github.com/weaveworks/gitopssets-controller/pkg/generators/gitrefs"
 	"github.com/weaveworks/gitopssets-controller/pkg/generators/gitrepository"
 	"github.com/weaveworks/gitopssets-controller/pkg/generators/imagepolicy"
+	"github.com/weaveworks/gitopssets-controller/pkg/generators/kubernetesresources"
 	"github.com/weaveworks/gitopssets-controller/pkg/generators/list"
 	"github.com/weaveworks/gitopssets-controller/pkg/generators/matrix"
+	"github.com/weaveworks/gitopssets-controller/pkg/generators/merge"
 	"github.com/weaveworks/gitopssets-controller/pkg/generators/ocirepository"
 	"github.com/weaveworks/gitopssets-controller/pkg/generators/pullrequests"
+	"github.com/weaveworks/gitopssets-controller/pkg/generators/scmrepositories"
 	"github.com/weaveworks/gitopssets-controller/pkg/parser"
 	//+kubebuilder:scaffold:imports
 )
 
 // AllGenerators contains the name of all possible Generators.
-var AllGenerators = []string{"GitRepository", "OCIRepository", "Cluster", "PullRequests", "List", "APIClient", "ImagePolicy", "Matrix", "Config"}
+var AllGenerators = []string{"GitRepository", "OCIRepository", "Bucket", "Cluster", "PullRequests", "GitRefs", "SCMRepositories", "List", "APIClient", "ImagePolicy", "Matrix", "Merge", "Config", "KubernetesResources", "GitOpsSet"}
 
 // DefaultGenerators contains the name of the default set of enabled Generators,
-// this leaves out generators that require optional dependencies.
-var DefaultGenerators = []string{"GitRepository", "OCIRepository", "PullRequests", "List", "APIClient", "Matrix", "Config"}
+// this leaves out generators that require optional dependencies, including
+// the Bucket generator, which requires the Flux Bucket CRD.
+var DefaultGenerators = []string{"GitRepository", "OCIRepository", "PullRequests", "GitRefs", "SCMRepositories", "List", "APIClient", "Matrix", "Merge", "Config", "GitOpsSet"}
 
 // NewSchemeForGenerators creates and returns a runtime.Scheme configured with
 // the correct schemes for the enabled generators.
@@ -80,28 +87,51 @@ func ValidateEnabledGenerators(enabledGenerators []string) error {
 
 // GetGenenerators returns a set of generator factories for the set of enabled
 // generators.
-func GetGenerators(enabledGenerators []string, fetcher parser.ArchiveFetcher, clientFactory apiclient.HTTPClientFactory) map[string]generators.GeneratorFactory {
+//
+// If allowClusterScope is true, the KubernetesResources generator can query
+// cluster-scoped resources and resources in all namespaces.
+func GetGenerators(enabledGenerators []string, fetcher parser.ArchiveFetcher, clientFactory apiclient.HTTPClientFactory, allowClusterScope bool) map[string]generators.GeneratorFactory {
 	matrixGenerators := filterEnabledGenerators(enabledGenerators, map[string]generators.GeneratorFactory{
-		"List":          list.GeneratorFactory,
-		"GitRepository": gitrepository.GeneratorFactory(fetcher),
-		"OCIRepository": ocirepository.GeneratorFactory(fetcher),
-		"PullRequests":  pullrequests.GeneratorFactory,
-		"Cluster":       cluster.GeneratorFactory,
-		"ImagePolicy":   imagepolicy.GeneratorFactory,
-		"APIClient":     apiclient.GeneratorFactory(clientFactory),
-		"Config":        config.GeneratorFactory,
+		"List":                list.GeneratorFactory,
+		"GitRepository":       gitrepository.GeneratorFactory(fetcher),
+		"OCIRepository":       ocirepository.GeneratorFactory(fetcher),
+		"Bucket":              bucket.GeneratorFactory(fetcher),
+		"PullRequests":        pullrequests.GeneratorFactory,
+		"GitRefs":             gitrefs.GeneratorFactory,
+		"SCMRepositories":     scmrepositories.GeneratorFactory,
+		"Cluster":             cluster.GeneratorFactory,
+		"ImagePolicy":         imagepolicy.GeneratorFactory,
+		"APIClient":           apiclient.GeneratorFactory(clientFactory),
+		"Config":              config.GeneratorFactory,
+		"KubernetesResources": kubernetesresources.GeneratorFactory(allowClusterScope),
+		"GitOpsSet":           gitopsset.GeneratorFactory,
 	})
 
+	// The Matrix and Merge generators can be nested in each other, so they
+	// share the nested generators.
+	if isGeneratorEnabled(enabledGenerators, "Matrix") {
+		matrixGenerators["Matrix"] = matrix.GeneratorFactory(matrixGenerators)
+	}
+	if isGeneratorEnabled(enabledGenerators, "Merge") {
+		matrixGenerators["Merge"] = merge.GeneratorFactory(matrixGenerators)
+	}
+
 	return filterEnabledGenerators(enabledGenerators, map[string]generators.GeneratorFactory{
-		"List":          list.GeneratorFactory,
-		"GitRepository": gitrepository.GeneratorFactory(fetcher),
-		"OCIRepository": ocirepository.GeneratorFactory(fetcher),
-		"PullRequests":  pullrequests.GeneratorFactory,
-		"Cluster":       cluster.GeneratorFactory,
-		"APIClient":     apiclient.GeneratorFactory(clientFactory),
-		"ImagePolicy":   imagepolicy.GeneratorFactory,
-		"Matrix":        matrix.GeneratorFactory(matrixGenerators),
-		"Config":        config.GeneratorFactory,
+		"List":                list.GeneratorFactory,
+		"GitRepository":       gitrepository.GeneratorFactory(fetcher),
+		"OCIRepository":       ocirepository.GeneratorFactory(fetcher),
+		"Bucket":              bucket.GeneratorFactory(fetcher),
+		"PullRequests":        pullrequests.GeneratorFactory,
+		"GitRefs":             gitrefs.GeneratorFactory,
+		"SCMRepositories":     scmrepositories.GeneratorFactory,
+		"Cluster":             cluster.GeneratorFactory,
+		"APIClient":           apiclient.GeneratorFactory(clientFactory),
+		"ImagePolicy":         imagepolicy.GeneratorFactory,
+		"Matrix":              matrix.GeneratorFactory(matrixGenerators),
+		"Merge":               merge.GeneratorFactory(matrixGenerators),
+		"Config":              config.GeneratorFactory,
+		"KubernetesResources": kubernetesresources.GeneratorFactory(allowClusterScope),
+		"GitOpsSet":           gitopsset.GeneratorFactory,
 	})
 }
 
@@ -119,6 +119,11 @@ func TestGetGenerators(t *testing.T) {
 			[]string{"cluster", "List"},
 			[]string{"List"},
 		},
+		{
+			"default generators",
+			DefaultGenerators,
+			[]string{"APIClient", "Config", "GitOpsSet", "GitRefs", "GitRepository", "List", "Matrix", "Merge", "OCIRepository", "PullRequests", "SCMRepositories"},
+		},
 		{
 			"unknown enabled generators are ignored",
 			[]string{"Cluster", "List", "foo"},
@@ -128,7 +133,7 @@ func TestGetGenerators(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := GetGenerators(tt.enabledGenerators, nil, nil)
+			got := GetGenerators(tt.enabledGenerators, nil, nil, false)
 			keys := make([]string, 0, len(got))
 			for k := range got {
 				keys = append(keys, k)
@@ -160,12 +165,12 @@ func TestValidateEnabledGenerators(t *testing.T) {
 		{
 			"unknown enabled generators raise error",
 			[]string{"Cluster", "List", "foo"},
-			`invalid generator "foo". valid values: \["GitRepository" "OCIRepository" "Cluster" "PullRequests" "List" "APIClient" "ImagePolicy" "Matrix" "Config"\]`,
+			`invalid generator "foo". valid values: \["GitRepository" "OCIRepository" "Bucket" "Cluster" "PullRequests" "GitRefs" "SCMRepositories" "List" "APIClient" "ImagePolicy" "Matrix" "Merge" "Config" "KubernetesResources" "GitOpsSet"\]`,
 		},
 		{
 			"case insensitive generators",
 			[]string{"cluster", "List"},
-			`invalid generator "cluster". valid values: \["GitRepository" "OCIRepository" "Cluster" "PullRequests" "List" "APIClient" "ImagePolicy" "Matrix" "Config"\]`,
+			`invalid generator "cluster". valid values: \["GitRepository" "OCIRepository" "Bucket" "Cluster" "PullRequests" "GitRefs" "SCMRepositories" "List" "APIClient" "ImagePolicy" "Matrix" "Merge" "Config" "KubernetesResources" "GitOpsSet"\]`,
 		},
 	}
 
@@ -0,0 +1,41 @@
+package templatefuncs
+
+import (
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+	"github.com/gitops-tools/pkg/sanitize"
+	syaml "sigs.k8s.io/yaml"
+)
+
+// FuncMap returns the functions that are available when rendering templates.
+func FuncMap() template.FuncMap {
+	f := sprig.TxtFuncMap()
+	unwanted := []string{
+		"env", "expandenv", "getHostByName", "genPrivateKey", "derivePassword", "sha256sum",
+		"base", "dir", "ext", "clean", "isAbs", "osBase", "osDir", "osExt", "osClean", "osIsAbs"}
+
+	for _, v := range unwanted {
+		delete(f, v)
+	}
+
+	f["sanitize"] = sanitize.SanitizeDNSName
+	f["getordefault"] = func(element map[string]any, key string, def interface{}) interface{} {
+		if v, ok := element[key]; ok {
+			return v
+		}
+
+		return def
+	}
+	f["toYaml"] = func(v interface{}) string {
+		data, err := syaml.Marshal(v)
+		if err != nil {
+			// Swallow errors inside of a template.
+			return ""
+		}
+		return strings.TrimSuffix(string(data), "\n")
+	}
+
+	return f
+}